@@ -0,0 +1,268 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/credsfile"
+	"github.com/DNSControl/dnscontrol/v4/pkg/prettyzone"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+	"github.com/DNSControl/dnscontrol/v4/pkg/providers"
+	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
+	"github.com/urfave/cli/v3"
+)
+
+var _ = cmd(catUtils, func() *cli.Command {
+	var args BackupArgs
+	return &cli.Command{
+		Name:  "backup",
+		Usage: "saves a snapshot of zones at a provider (stand-alone)",
+		Action: func(ctx context.Context, c *cli.Command) error {
+			if c.NArg() < 2 {
+				return cli.Exit("Arguments should be: credskey zone(s) (Ex: my_cloudflare example.com)", 1)
+			}
+			args.CredName = c.Args().Get(0)
+			args.ZoneNames = c.Args().Slice()[1:]
+			return exit(Backup(args))
+		},
+		Flags:     args.flags(),
+		UsageText: "dnscontrol backup [command options] credkey zone [...]",
+		Description: `Save a snapshot of zones at a provider.  This is a stand-alone utility.
+
+For each zone, two files are written to the backup directory: a BIND-style
+zonefile (.zone) and the same records as IR JSON (.json).  The JSON file can
+be restored with "dnscontrol restore".
+
+ARGUMENTS:
+   credkey:  The name used in creds.json
+   zone:     One or more zones (domains) to save; or "all".
+
+EXAMPLES:
+   dnscontrol backup my_route53 example.com
+   dnscontrol backup --backup-dir=/var/backups/dns my_cloudflare all
+
+Documentation: https://docs.dnscontrol.org/commands/backup-restore`,
+	}
+}())
+
+// BackupArgs contains all data/flags needed to run backup, independently of CLI.
+type BackupArgs struct {
+	GetCredentialsArgs          // Args related to creds.json
+	CredName           string   // key in creds.json
+	ZoneNames          []string // The zones to save
+	BackupDir          string   // Directory to write the snapshots to
+}
+
+func (args *BackupArgs) flags() []cli.Flag {
+	flags := args.GetCredentialsArgs.flags()
+	flags = append(flags, &cli.StringFlag{
+		Name:        "backup-dir",
+		Destination: &args.BackupDir,
+		Value:       "backups",
+		Usage:       `Directory to write the snapshots to`,
+	})
+	return flags
+}
+
+// Backup implements the backup subcommand.
+func Backup(args BackupArgs) error {
+	providerConfigs, err := credsfile.LoadProviderConfigs(args.CredsFile)
+	if err != nil {
+		return fmt.Errorf("failed Backup LoadProviderConfigs(%q): %w", args.CredsFile, err)
+	}
+	provider, err := providers.CreateDNSProvider("", providerConfigs[args.CredName], nil)
+	if err != nil {
+		return fmt.Errorf("failed Backup CDP: %w", err)
+	}
+	zones, err := expandZoneNames(provider, args.CredName, args.ZoneNames)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, zone := range zones {
+		recs, err := getZoneRecords(provider, zone)
+		if err != nil {
+			return fmt.Errorf("failed Backup gzr(%q): %w", zone, err)
+		}
+		files, err := writeSnapshot(args.BackupDir, now, args.CredName, zone, recs)
+		if err != nil {
+			return err
+		}
+		printer.Printf("Saved %q at %q: %s\n", zone, args.CredName, files[1])
+	}
+	return nil
+}
+
+var _ = cmd(catUtils, func() *cli.Command {
+	var args RestoreArgs
+	return &cli.Command{
+		Name:  "restore",
+		Usage: "pushes a snapshot made by backup (or push --backup-dir) back to the provider",
+		Action: func(ctx context.Context, c *cli.Command) error {
+			if c.NArg() != 1 {
+				return cli.Exit("Arguments should be: snapshot (Ex: backups/20240102T150405Z_example.com_my_cloudflare.json)", 1)
+			}
+			args.Snapshot = c.Args().Get(0)
+			return exit(Restore(args))
+		},
+		Flags:     args.flags(),
+		UsageText: "dnscontrol restore [command options] snapshot.json",
+		Description: `Restore a zone to the records in a snapshot.
+
+The snapshot is processed like "dnscontrol push --ir=snapshot.json": the
+changes are computed and printed, then executed (unless --preview is given).
+
+ARGUMENTS:
+   snapshot:  A .json file written by "dnscontrol backup" or "dnscontrol push --backup-dir".
+
+EXAMPLES:
+   dnscontrol restore --preview backups/20240102T150405Z_example.com_my_cloudflare.json
+   dnscontrol restore backups/20240102T150405Z_example.com_my_cloudflare.json
+
+Documentation: https://docs.dnscontrol.org/commands/backup-restore`,
+	}
+}())
+
+// RestoreArgs contains all data/flags needed to run restore, independently of CLI.
+type RestoreArgs struct {
+	GetCredentialsArgs        // Args related to creds.json
+	Snapshot           string // The .json file to restore
+	Preview            bool   // Only print the changes
+	Interactive        bool   // Confirm each correction
+}
+
+func (args *RestoreArgs) flags() []cli.Flag {
+	flags := args.GetCredentialsArgs.flags()
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "preview",
+		Destination: &args.Preview,
+		Usage:       `Print the changes that would be made, without making them`,
+	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "i",
+		Destination: &args.Interactive,
+		Usage:       "Interactive. Confirm or Exclude each correction before they run",
+	})
+	return flags
+}
+
+// Restore implements the restore subcommand.
+func Restore(args RestoreArgs) error {
+	cfg, err := readSnapshot(args.Snapshot)
+	if err != nil {
+		return err
+	}
+	pargs := PPreviewArgs{
+		GetDNSConfigArgs:   GetDNSConfigArgs{JSONFile: args.Snapshot},
+		GetCredentialsArgs: args.GetCredentialsArgs,
+		FilterArgs:         FilterArgs{Providers: cfg.DNSProviders[0].Name},
+		ConcurMode:         "concurrent",
+		ConcurMax:          100,
+		NoPopulate:         true,
+		Output:             "text",
+	}
+	if args.Preview {
+		return PPreview(pargs)
+	}
+	return PPush(PPushArgs{PPreviewArgs: pargs, Interactive: args.Interactive})
+}
+
+// expandZoneNames returns the list of zones to process. "all" is expanded
+// to every zone at the provider.
+func expandZoneNames(provider providers.DNSServiceProvider, credName string, zoneNames []string) ([]string, error) {
+	if len(zoneNames) == 1 && zoneNames[0] == "all" {
+		lister, ok := provider.(providers.ZoneLister)
+		if !ok {
+			return nil, fmt.Errorf("provider %s cannot list zones to use the 'all' feature", credName)
+		}
+		zones, err := lister.ListZones()
+		if err != nil {
+			return nil, fmt.Errorf("failed ListZones: %w", err)
+		}
+		return zones, nil
+	}
+	return zoneNames, nil
+}
+
+// snapshotBase returns the filename (without extension) of a snapshot.
+func snapshotBase(dir string, t time.Time, providerName, zoneName string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s_%s_%s", t.UTC().Format("20060102T150405Z"), zoneName, providerName))
+}
+
+// writeSnapshot writes recs as a BIND-style zonefile and as IR JSON. The
+// names of the files written are returned.
+func writeSnapshot(dir string, t time.Time, providerName, zoneName string, recs models.Records) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	base := snapshotBase(dir, t, providerName, zoneName)
+	files := []string{base + ".zone", base + ".json"}
+
+	dc := &models.DomainConfig{Name: zoneName}
+	dc.PostProcess()
+
+	// BIND format:
+	f, err := os.Create(files[0])
+	if err != nil {
+		return nil, err
+	}
+	z := prettyzone.PrettySort(recs, dc.Name, 0, nil)
+	err = writeZone(f, z.Records, dc.Name, 0)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("writing %q: %w", files[0], err)
+	}
+
+	// IR JSON (same format as "print-ir"). The provider's type is taken
+	// from creds.json when the snapshot is restored.
+	dc.RegistrarName = "none"
+	dc.DNSProviderNames = map[string]int{providerName: 0}
+	dc.Records = recs
+	cfg := &models.DNSConfig{
+		Registrars:   []*models.RegistrarConfig{{Name: "none", Type: "NONE"}},
+		DNSProviders: []*models.DNSProviderConfig{{Name: providerName, Type: "-"}},
+		Domains:      []*models.DomainConfig{dc},
+	}
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(files[1], b, 0o644); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// readSnapshot reads a snapshot written by writeSnapshot.
+func readSnapshot(filename string) (*models.DNSConfig, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &models.DNSConfig{}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("snapshot %q: %w", filename, err)
+	}
+	if len(cfg.Domains) != 1 || len(cfg.DNSProviders) != 1 {
+		return nil, fmt.Errorf("snapshot %q: expected exactly one domain and one DNS provider", filename)
+	}
+	return cfg, nil
+}
+
+// backupZone saves the records of zone at provider as they were before the
+// push (r.Existing).
+func backupZone(dir string, t time.Time, zone *models.DomainConfig, provider *models.DNSProviderInstance, r *zonerecs.Result) ([]string, error) {
+	if r == nil {
+		return nil, errors.New("no records were gathered")
+	}
+	return writeSnapshot(dir, t, provider.Name, zone.UniqueName, r.Existing)
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+func Test_writeSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	when := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	recs := models.Records{makeTestRec("www", "A", "1.2.3.4"), makeTestRec("@", "MX", "10 mx.example.com.")}
+
+	files, err := writeSnapshot(dir, when, "my_provider", "example.com", recs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(dir, "20240102T150405Z_example.com_my_provider")
+	if files[0] != base+".zone" || files[1] != base+".json" {
+		t.Errorf("unexpected filenames: %v", files)
+	}
+
+	z, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(z), "$ORIGIN example.com.") || !strings.Contains(string(z), "1.2.3.4") {
+		t.Errorf("unexpected zonefile:\n%s", z)
+	}
+
+	cfg, err := readSnapshot(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc := cfg.Domains[0]
+	if dc.Name != "example.com" || dc.DNSProviderNames["my_provider"] != 0 || cfg.DNSProviders[0].Name != "my_provider" {
+		t.Errorf("unexpected snapshot: %+v", dc)
+	}
+	if len(dc.Records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(dc.Records))
+	}
+	for i, rec := range dc.Records {
+		if rec.ToComparableNoTTL() != recs[i].ToComparableNoTTL() {
+			t.Errorf("record %d: got %q, want %q", i, rec.ToComparableNoTTL(), recs[i].ToComparableNoTTL())
+		}
+	}
+}
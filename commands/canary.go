@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+	"github.com/DNSControl/dnscontrol/v4/pkg/verify"
+	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
+)
+
+// canaryFor returns the provider that the changes to a zone are pushed to
+// first during a canary rollout: the provider named name if the zone uses
+// it, otherwise the first one. It returns nil if fewer than two of the
+// zone's providers are being processed.
+func canaryFor(zone *models.DomainConfig, providersToProcess []*models.DNSProviderInstance, name string) *models.DNSProviderInstance {
+	if len(providersToProcess) < 2 {
+		return nil
+	}
+	if i := slices.IndexFunc(providersToProcess, func(p *models.DNSProviderInstance) bool { return p.Name == name }); i != -1 {
+		return providersToProcess[i]
+	}
+	return providersToProcess[0]
+}
+
+// canaryOrder returns the providers of zone with the canary (if any) first.
+func canaryOrder(providers []*models.DNSProviderInstance, canary *models.DNSProviderInstance) []*models.DNSProviderInstance {
+	if canary == nil {
+		return providers
+	}
+	r := []*models.DNSProviderInstance{canary}
+	for _, p := range providers {
+		if p != canary {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+// verifyCanary waits until the nameservers of the canary provider serve
+// the changes in r. It gives up after timeout.
+func verifyCanary(zone *models.DomainConfig, canary *models.DNSProviderInstance, r *zonerecs.Result, timeout time.Duration, out printer.CLI) error {
+	if r == nil {
+		return errors.New("no changes were gathered")
+	}
+	names, err := providerNameservers(zone, canary)
+	if err != nil {
+		return err
+	}
+
+	changes, err := r.Changes()
+	if err != nil {
+		return err
+	}
+	out.Printf("CANARY: Verifying the changes to %q at %q (%s)\n", zone.GetUniqueName(), canary.Name, strings.Join(names, ", "))
+	var b strings.Builder
+	for _, st := range verify.Poll(names, zone.Name, changes, timeout, verifyInterval) {
+		for _, m := range st.Mismatches {
+			fmt.Fprintf(&b, "\n  %s", m)
+		}
+	}
+	if b.Len() != 0 {
+		return fmt.Errorf("the nameservers of %q do not serve the changes to %q after %s:%s", canary.Name, zone.GetUniqueName(), timeout, b.String())
+	}
+	out.Printf("CANARY: %q serves the changes to %q\n", canary.Name, zone.GetUniqueName())
+	return nil
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
+	"github.com/miekg/dns"
+)
+
+// nsProvider is a memProvider whose records are served by a local
+// nameserver.
+type nsProvider struct {
+	memProvider
+	addr string
+}
+
+func (p *nsProvider) GetNameservers(string) ([]*models.Nameserver, error) {
+	return []*models.Nameserver{{Name: p.addr}}, nil
+}
+
+func newNSProvider(t *testing.T, recs models.Records) *nsProvider {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &nsProvider{memProvider: memProvider{recs: recs}, addr: pc.LocalAddr().String()}
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.Authoritative = true
+		q := req.Question[0]
+		for _, rec := range p.recs {
+			rr := rec.ToRR()
+			if strings.EqualFold(rr.Header().Name, q.Name) && rr.Header().Rrtype == q.Qtype {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+		w.WriteMsg(m)
+	})}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+	return p
+}
+
+func Test_canaryFor(t *testing.T) {
+	a := &models.DNSProviderInstance{ProviderBase: models.ProviderBase{Name: "a"}}
+	b := &models.DNSProviderInstance{ProviderBase: models.ProviderBase{Name: "b"}}
+	zone := &models.DomainConfig{Name: "example.com"}
+
+	if got := canaryFor(zone, []*models.DNSProviderInstance{a}, ""); got != nil {
+		t.Errorf("canaryFor(one provider) = %v, want nil", got.Name)
+	}
+	if got := canaryFor(zone, []*models.DNSProviderInstance{a, b}, ""); got != a {
+		t.Errorf("canaryFor() = %v, want a", got.Name)
+	}
+	if got := canaryFor(zone, []*models.DNSProviderInstance{a, b}, "b"); got != b {
+		t.Errorf("canaryFor(b) = %v, want b", got.Name)
+	}
+	if got := canaryOrder([]*models.DNSProviderInstance{a, b}, b); got[0] != b || got[1] != a {
+		t.Errorf("canaryOrder() = %v, %v", got[0].Name, got[1].Name)
+	}
+}
+
+func Test_verifyCanary(t *testing.T) {
+	defer func(d time.Duration) { verifyInterval = d }(verifyInterval)
+	verifyInterval = 10 * time.Millisecond
+
+	p := newNSProvider(t, models.Records{makeTestRec("www", "A", "1.2.3.4")})
+	provider := &models.DNSProviderInstance{ProviderBase: models.ProviderBase{Name: "canary"}, Driver: p, NumberOfNameservers: -1}
+	zone := &models.DomainConfig{Name: "example.com", Records: models.Records{
+		makeTestRec("www", "A", "1.2.3.5"),
+		makeTestRec("api", "CNAME", "www.example.com."),
+	}}
+	r, err := zonerecs.CorrectZoneRecordsResult(p, zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &printer.ConsolePrinter{Writer: &strings.Builder{}}
+
+	// Before the push:
+	err = verifyCanary(zone, provider, r, 50*time.Millisecond, out)
+	if err == nil || !strings.Contains(err.Error(), "www.example.com A: want [1.2.3.5], got [1.2.3.4]") {
+		t.Errorf("verifyCanary() before the push = %v", err)
+	}
+
+	for _, c := range r.Corrections {
+		if err := c.F(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := verifyCanary(zone, provider, r, time.Second, out); err != nil {
+		t.Errorf("verifyCanary() after the push = %v", err)
+	}
+}
+
+func Test_verifyPushes(t *testing.T) {
+	defer func(d time.Duration) { verifyInterval = d }(verifyInterval)
+	verifyInterval = 10 * time.Millisecond
+
+	p := newNSProvider(t, models.Records{makeTestRec("www", "A", "1.2.3.4")})
+	provider := &models.DNSProviderInstance{ProviderBase: models.ProviderBase{Name: "bind"}, Driver: p, NumberOfNameservers: -1}
+	zone := &models.DomainConfig{Name: "example.com", UniqueName: "example.com", Records: models.Records{
+		makeTestRec("www", "A", "1.2.3.5"),
+		makeTestRec("api", "A", "1.2.3.6"),
+	}}
+	r, err := zonerecs.CorrectZoneRecordsResult(p, zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pushes := []pushed{{zone: zone, provider: provider, result: r}}
+
+	// Before the push:
+	var buf strings.Builder
+	if !verifyPushes(pushes, 50*time.Millisecond, &printer.ConsolePrinter{Writer: &buf}) {
+		t.Errorf("verifyPushes() before the push = false, want true")
+	}
+	for _, want := range []string{
+		`VERIFY: "example.com" at "bind": 0 of 2 changed records served by ` + p.addr,
+		"  www.example.com A: NOT SERVED after 50ms",
+		"    " + p.addr + ": www.example.com A: want [1.2.3.5], got [1.2.3.4]",
+		"  api.example.com A: NOT SERVED after 50ms",
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("verifyPushes() output does not contain %q:\n%s", want, buf.String())
+		}
+	}
+
+	for _, c := range r.Corrections {
+		if err := c.F(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf.Reset()
+	if verifyPushes(pushes, time.Second, &printer.ConsolePrinter{Writer: &buf}) {
+		t.Errorf("verifyPushes() after the push = true, want false:\n%s", buf.String())
+	}
+	if want := "  api.example.com A: served after 0s\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("verifyPushes() output does not contain %q:\n%s", want, buf.String())
+	}
+}
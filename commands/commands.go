@@ -65,6 +65,18 @@ func Run(v string) int {
 			Usage:       "Enable JS fetch(), dangerous on untrusted code!",
 			Destination: &js.EnableFetch,
 		},
+		&cli.StringFlag{
+			Name:        "js-engine",
+			Usage:       "JavaScript engine that runs dnsconfig.js: otto (ES5) or goja (ES2020+, ES modules, TypeScript)",
+			Value:       js.EngineOtto,
+			Destination: &js.Engine,
+			Action: func(ctx context.Context, c *cli.Command, v string) error {
+				if v != js.EngineGoja && v != js.EngineOtto {
+					return cli.Exit(fmt.Sprintf("invalid --js-engine %q (valid: %s, %s)", v, js.EngineGoja, js.EngineOtto), 1)
+				}
+				return nil
+			},
+		},
 		&cli.BoolFlag{
 			Name:   "diff2",
 			Usage:  "Obsolete flag. Will be removed in v5 or later",
@@ -79,6 +91,11 @@ func Run(v string) int {
 			Usage:       "Disables update reordering",
 			Destination: &diff2.DisableOrdering,
 		},
+		&cli.BoolFlag{
+			Name:        "no-provenance",
+			Usage:       "Do not show where in dnsconfig.js each changed record is defined",
+			Destination: &diff2.NoProvenance,
+		},
 		&cli.BoolFlag{
 			Name:        "no-colors",
 			Usage:       "Disable colors",
@@ -289,6 +306,21 @@ func (args *PrintJSONArgs) flags() []cli.Flag {
 	}
 }
 
+// PolicyArgs are used by the commands that check dnsconfig.js against a policy file.
+type PolicyArgs struct {
+	PolicyFile string
+}
+
+func (args *PolicyArgs) flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "policy",
+			Destination: &args.PolicyFile,
+			Usage:       "Check the configuration against the rules in this policy file (.json, .yaml or .js)",
+		},
+	}
+}
+
 // GetCredentialsArgs encapsulates the flags/args for sub-commands that use the creds.json file.
 type GetCredentialsArgs struct {
 	CredsFile string
@@ -0,0 +1,278 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/credsfile"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+	"github.com/DNSControl/dnscontrol/v4/pkg/providers"
+	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
+	"github.com/urfave/cli/v3"
+)
+
+// compareZonesExitDiffer is the exit code of the compare-zones command when
+// the zones differ. 0 means they are the same and 1 means an error occurred.
+const compareZonesExitDiffer = 2
+
+var errZonesDiffer = errors.New("the zones differ")
+
+var _ = cmd(catUtils, func() *cli.Command {
+	var args CompareZonesArgs
+	return &cli.Command{
+		Name:  "compare-zones",
+		Usage: "compares the records of zones at two providers (stand-alone)",
+		Action: func(ctx context.Context, c *cli.Command) error {
+			if c.NArg() < 3 {
+				return cli.Exit("Arguments should be: credkeyA credkeyB zone(s) (Ex: my_route53 my_cloudflare example.com)", 1)
+			}
+			args.CredNameA = c.Args().Get(0)
+			args.CredNameB = c.Args().Get(1)
+			args.ZoneNames = c.Args().Slice()[2:]
+			err := CompareZones(args)
+			if errors.Is(err, errZonesDiffer) {
+				return cli.Exit("", compareZonesExitDiffer)
+			}
+			return exit(err)
+		},
+		Flags:     args.flags(),
+		UsageText: "dnscontrol compare-zones [command options] credkeyA credkeyB zone [...]",
+		Description: `Compare the records that two providers serve for the same zones.  This is
+a stand-alone utility; dnsconfig.js is not used.
+
+The records at the first provider are adjusted the way the second provider
+would adjust them if they were pushed to it (TTL limits, TXT splitting,
+etc.) before they are compared.  The SOA record and the NS records at the
+apex are not compared (see --apex-ns).
+
+ARGUMENTS:
+   credkeyA:  The name of the first provider in creds.json
+   credkeyB:  The name of the second provider in creds.json
+   zone:      One or more zones (domains) to compare; or "all" (the zones at credkeyA).
+
+EXIT CODES:
+   0: The zones are the same.
+   1: An error occurred.
+   2: The zones differ.
+
+EXAMPLES:
+   dnscontrol compare-zones my_route53 my_cloudflare example.com
+   dnscontrol compare-zones --format=json my_route53 my_cloudflare all
+
+Documentation: https://docs.dnscontrol.org/commands/compare-zones`,
+	}
+}())
+
+// CompareZonesArgs contains all data/flags needed to run compare-zones, independently of CLI.
+type CompareZonesArgs struct {
+	GetCredentialsArgs          // Args related to creds.json
+	CredNameA          string   // key in creds.json of the first provider
+	CredNameB          string   // key in creds.json of the second provider
+	ZoneNames          []string // The zones to compare
+	Format             string   // Output format: text, json
+	ApexNS             bool     // Compare the NS records at the apex too
+}
+
+func (args *CompareZonesArgs) flags() []cli.Flag {
+	flags := args.GetCredentialsArgs.flags()
+	flags = append(flags, &cli.StringFlag{
+		Name:        "format",
+		Destination: &args.Format,
+		Value:       "text",
+		Usage:       `Output format: text, json`,
+		Action: func(ctx context.Context, c *cli.Command, s string) error {
+			if !slices.Contains([]string{"text", "json"}, s) {
+				fmt.Printf("%q is not a valid option for --format.  Values are: text, json\n", s)
+				os.Exit(1)
+			}
+			return nil
+		},
+	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "apex-ns",
+		Destination: &args.ApexNS,
+		Usage:       `Compare the NS records at the apex too (they usually differ between providers)`,
+	})
+	return flags
+}
+
+// ZoneComparison is the result of comparing one zone at two providers.
+type ZoneComparison struct {
+	Zone      string          `json:"zone"`
+	ProviderA string          `json:"provider_a"`
+	ProviderB string          `json:"provider_b"`
+	Same      bool            `json:"same"`
+	Error     string          `json:"error,omitempty"`
+	Diffs     []RecordSetDiff `json:"diffs,omitempty"`
+}
+
+// RecordSetDiff is a RecordKey (label and type) whose records differ
+// between the two providers. A or B is empty if the provider has no
+// records at that key.
+type RecordSetDiff struct {
+	Name string   `json:"name"`
+	Type string   `json:"type"`
+	A    []string `json:"a,omitempty"` // The records at the first provider (as adjusted for the second).
+	B    []string `json:"b,omitempty"` // The records at the second provider.
+}
+
+// CompareZones implements the compare-zones subcommand. It returns
+// errZonesDiffer if any zone differs.
+func CompareZones(args CompareZonesArgs) error {
+	providerConfigs, err := credsfile.LoadProviderConfigs(args.CredsFile)
+	if err != nil {
+		return fmt.Errorf("failed CompareZones LoadProviderConfigs(%q): %w", args.CredsFile, err)
+	}
+	providerA, err := providers.CreateDNSProvider("", providerConfigs[args.CredNameA], nil)
+	if err != nil {
+		return fmt.Errorf("failed CompareZones CDP(%q): %w", args.CredNameA, err)
+	}
+	providerB, err := providers.CreateDNSProvider("", providerConfigs[args.CredNameB], nil)
+	if err != nil {
+		return fmt.Errorf("failed CompareZones CDP(%q): %w", args.CredNameB, err)
+	}
+	zones, err := expandZoneNames(providerA, args.CredNameA, args.ZoneNames)
+	if err != nil {
+		return err
+	}
+
+	var results []*ZoneComparison
+	for _, zone := range zones {
+		zc := &ZoneComparison{Zone: zone, ProviderA: args.CredNameA, ProviderB: args.CredNameB}
+		results = append(results, zc)
+		zc.Diffs, err = compareZone(providerA, providerB, zone, args.ApexNS)
+		if err != nil {
+			zc.Error = err.Error()
+			continue
+		}
+		zc.Same = len(zc.Diffs) == 0
+	}
+
+	if args.Format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		writeZoneComparisons(os.Stdout, results)
+	}
+
+	switch {
+	case slices.ContainsFunc(results, func(zc *ZoneComparison) bool { return zc.Error != "" }):
+		return errors.New("compare-zones: completed with errors")
+	case slices.ContainsFunc(results, func(zc *ZoneComparison) bool { return !zc.Same }):
+		return errZonesDiffer
+	}
+	return nil
+}
+
+// compareZone returns the RecordKeys whose records differ between zone at
+// providerA and zone at providerB.
+//
+// The records at providerA are used as the desired records of providerB, as
+// if they were pushed to it: providerB adjusts them (TTL limits, TXT
+// splitting, etc.) while generating its corrections, which are discarded.
+// diff2 then compares them with the records at providerB.
+func compareZone(providerA, providerB models.DNSProvider, zone string, apexNS bool) ([]RecordSetDiff, error) {
+	recsA, err := getZoneRecords(providerA, zone)
+	if err != nil {
+		return nil, err
+	}
+	dc := zoneDomainConfig(zone)
+	dc.Records = comparableRecords(recsA, dc.Name, apexNS)
+	r, err := zonerecs.CorrectZoneRecordsResult(providerB, dc)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := r.Changes()
+	if err != nil {
+		return nil, err
+	}
+	// The records at providerB weren't filtered; filter the changes instead.
+	var diffs []RecordSetDiff
+	for _, c := range changes {
+		if c.Type == diff2.REPORT || !comparableKey(c.Key, dc.Name, apexNS) {
+			continue
+		}
+		diffs = append(diffs, RecordSetDiff{
+			Name: c.Key.NameFQDN,
+			Type: c.Key.Type,
+			A:    recordStrings(c.New),
+			B:    recordStrings(c.Old),
+		})
+	}
+	return diffs, nil
+}
+
+// comparableRecords returns the records of recs that are compared.
+func comparableRecords(recs models.Records, zone string, apexNS bool) models.Records {
+	var r models.Records
+	for _, rec := range recs {
+		if comparableKey(rec.Key(), zone, apexNS) {
+			r = append(r, rec)
+		}
+	}
+	return r
+}
+
+// comparableKey returns true if the records at key are compared. The SOA
+// record and (unless apexNS) the NS records at the apex are specific to
+// each provider.
+func comparableKey(key models.RecordKey, zone string, apexNS bool) bool {
+	switch {
+	case key.Type == "SOA":
+		return false
+	case key.Type == "NS" && strings.EqualFold(key.NameFQDN, zone):
+		return apexNS
+	}
+	return true
+}
+
+// recordStrings returns the data and TTL of each of recs.
+func recordStrings(recs models.Records) []string {
+	var r []string
+	for _, rec := range recs {
+		r = append(r, fmt.Sprintf("%s ttl=%d", rec.ToComparableNoTTL(), rec.TTL))
+	}
+	return r
+}
+
+// writeZoneComparisons writes the results in a human-readable format.
+func writeZoneComparisons(w io.Writer, results []*ZoneComparison) {
+	for _, zc := range results {
+		switch {
+		case zc.Error != "":
+			fmt.Fprintf(w, "%s: ERROR: %s\n", zc.Zone, zc.Error)
+			continue
+		case zc.Same:
+			fmt.Fprintf(w, "%s: %q and %q are the same\n", zc.Zone, zc.ProviderA, zc.ProviderB)
+			continue
+		}
+		fmt.Fprintf(w, "%s: %q and %q differ in %d record set(s)\n", zc.Zone, zc.ProviderA, zc.ProviderB, len(zc.Diffs))
+		for _, d := range zc.Diffs {
+			switch {
+			case len(d.B) == 0:
+				fmt.Fprintf(w, "  %s %s: only at %q\n", d.Name, d.Type, zc.ProviderA)
+			case len(d.A) == 0:
+				fmt.Fprintf(w, "  %s %s: only at %q\n", d.Name, d.Type, zc.ProviderB)
+			default:
+				fmt.Fprintf(w, "  %s %s:\n", d.Name, d.Type)
+			}
+			for _, s := range d.A {
+				fmt.Fprintf(w, "    %s: %s\n", zc.ProviderA, s)
+			}
+			for _, s := range d.B {
+				fmt.Fprintf(w, "    %s: %s\n", zc.ProviderB, s)
+			}
+		}
+	}
+}
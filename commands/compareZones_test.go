@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+// minTTLProvider is a memProvider that raises the TTLs below minTTL, like
+// providers that only support certain TTLs.
+type minTTLProvider struct {
+	memProvider
+	minTTL uint32
+}
+
+func (p *minTTLProvider) GetZoneRecordsCorrections(dc *models.DomainConfig, existing models.Records) ([]*models.Correction, int, error) {
+	for _, rec := range dc.Records {
+		rec.TTL = max(rec.TTL, p.minTTL)
+	}
+	return p.memProvider.GetZoneRecordsCorrections(dc, existing)
+}
+
+func withTTL(rec *models.RecordConfig, ttl uint32) *models.RecordConfig {
+	rec.TTL = ttl
+	return rec
+}
+
+func Test_compareZone(t *testing.T) {
+	a := &memProvider{recs: models.Records{
+		makeTestRec("@", "NS", "ns1.a.example."),
+		makeTestRec("www", "A", "1.2.3.4"),
+		withTTL(makeTestRec("mail", "A", "1.2.3.7"), 60),
+		makeTestRec("api", "CNAME", "www.example.com."),
+		makeTestRec("txt", "TXT", "v=spf1 -all"),
+	}}
+	b := &minTTLProvider{minTTL: 300, memProvider: memProvider{recs: models.Records{
+		makeTestRec("@", "NS", "ns1.b.example."),
+		makeTestRec("www", "A", "1.2.3.5"),
+		makeTestRec("mail", "A", "1.2.3.7"),
+		makeTestRec("api", "CNAME", "www.example.com."),
+		makeTestRec("old", "A", "1.2.3.6"),
+	}}}
+
+	diffs, err := compareZone(a, b, "example.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []*ZoneComparison{{Zone: "example.com", ProviderA: "a", ProviderB: "b", Diffs: diffs}}
+	var buf strings.Builder
+	writeZoneComparisons(&buf, results)
+	want := `example.com: "a" and "b" differ in 3 record set(s)
+  old.example.com A: only at "b"
+    b: 1.2.3.6 ttl=300
+  txt.example.com TXT: only at "a"
+    a: "v=spf1 -all" ttl=300
+  www.example.com A:
+    a: 1.2.3.4 ttl=300
+    b: 1.2.3.5 ttl=300
+`
+	if buf.String() != want {
+		t.Errorf("compareZone():\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	diffs, err = compareZone(a, b, "example.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 4 || diffs[0].Name != "example.com" || diffs[0].Type != "NS" {
+		t.Errorf("compareZone(apexNS) = %v, want the NS records at the apex first", diffs)
+	}
+}
@@ -0,0 +1,336 @@
+package commands
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/credsfile"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+	"github.com/DNSControl/dnscontrol/v4/pkg/nameservers"
+	"github.com/DNSControl/dnscontrol/v4/pkg/normalize"
+	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
+	"github.com/urfave/cli/v3"
+)
+
+// driftExitDrift is the exit code of the drift command when drift was found.
+// 0 means no drift (records protected by IGNORE*(), NO_PURGE, etc. are not
+// drift) and 1 means an error occurred.
+const driftExitDrift = 2
+
+var errDrift = errors.New("drift detected")
+
+var _ = cmd(catMain, func() *cli.Command {
+	var args DriftArgs
+	return &cli.Command{
+		Name:  "drift",
+		Usage: "report records at the providers that differ from dnsconfig.js",
+		Action: func(ctx context.Context, c *cli.Command) error {
+			err := Drift(args)
+			if errors.Is(err, errDrift) {
+				return cli.Exit("", driftExitDrift)
+			}
+			return exit(err)
+		},
+		Flags: args.flags(),
+		Description: `Compare the records at the providers with dnsconfig.js without making changes.
+
+Each difference is classified as "would create", "would modify" or "would
+delete (unmanaged)".  Records that IGNORE*(), NO_PURGE, IGNORE_EXTERNAL_DNS or
+OWNERSHIP protect are reported as "ignored" and are not drift.
+
+EXIT CODES:
+   0: No drift.
+   1: An error occurred.
+   2: Drift was found.
+
+Documentation: https://docs.dnscontrol.org/commands/drift`,
+	}
+}())
+
+// DriftArgs contains all data/flags needed to run drift, independently of CLI.
+type DriftArgs struct {
+	GetDNSConfigArgs
+	GetCredentialsArgs
+	FilterArgs
+	Format string // Output format: text, json
+	Full   bool   // List the ignored records too (text)
+}
+
+func (args *DriftArgs) flags() []cli.Flag {
+	flags := args.GetDNSConfigArgs.flags()
+	flags = append(flags, args.GetCredentialsArgs.flags()...)
+	flags = append(flags, args.FilterArgs.flags()...)
+	flags = append(flags, &cli.StringFlag{
+		Name:        "format",
+		Destination: &args.Format,
+		Value:       "text",
+		Usage:       `Output format: text, json`,
+		Action: func(ctx context.Context, c *cli.Command, s string) error {
+			if !slices.Contains([]string{"text", "json"}, s) {
+				fmt.Printf("%q is not a valid option for --format.  Values are: text, json\n", s)
+				os.Exit(1)
+			}
+			return nil
+		},
+	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "full",
+		Destination: &args.Full,
+		Usage:       `List the ignored records too (text format)`,
+	})
+	return flags
+}
+
+// The classes of DriftItem.
+const (
+	DriftCreate  = "create"  // In dnsconfig.js but not at the provider.
+	DriftModify  = "modify"  // At the provider, but different.
+	DriftDelete  = "delete"  // At the provider but not in dnsconfig.js (unmanaged).
+	DriftIgnored = "ignored" // At the provider but protected by IGNORE*(), NO_PURGE, etc. Not drift.
+)
+
+// DriftReport is the output of the drift command.
+type DriftReport struct {
+	Drift bool         `json:"drift"`
+	Zones []*DriftZone `json:"zones"`
+}
+
+// DriftZone is the drift of one zone at one provider.
+type DriftZone struct {
+	Domain   string      `json:"domain"`
+	Provider string      `json:"provider"`
+	Drift    bool        `json:"drift"`
+	Error    string      `json:"error,omitempty"`
+	Items    []DriftItem `json:"items,omitempty"`
+}
+
+// DriftItem is one record that differs.
+type DriftItem struct {
+	Class   string       `json:"class"` // DriftCreate, DriftModify, DriftDelete, DriftIgnored
+	Name    string       `json:"name"`
+	Type    string       `json:"type"`
+	Live    *DriftRecord `json:"live,omitempty"`    // The record at the provider.
+	Desired *DriftRecord `json:"desired,omitempty"` // The record in dnsconfig.js.
+	Reason  string       `json:"reason,omitempty"`  // DriftIgnored: IGNORE, NO_PURGE, IGNORE_EXTERNAL_DNS, OWNERSHIP
+	FilePos string       `json:"filepos,omitempty"` // Where the desired record is defined.
+}
+
+// DriftRecord is the data of a record in a DriftItem.
+type DriftRecord struct {
+	TTL  uint32 `json:"ttl"`
+	Data string `json:"data"`
+}
+
+// Drift implements the drift subcommand. It returns errDrift if drift was found.
+func Drift(args DriftArgs) error {
+	cfg, err := GetDNSConfig(args.GetDNSConfigArgs)
+	if err != nil {
+		return err
+	}
+	providerConfigs, err := credsfile.LoadProviderConfigs(args.CredsFile)
+	if err != nil {
+		return err
+	}
+	if _, err := PInitializeProviders(cfg, providerConfigs, false); err != nil {
+		return err
+	}
+	if errs := normalize.ValidateAndNormalizeConfig(cfg); PrintValidationErrors(errs) {
+		return errors.New("exiting due to validation errors")
+	}
+
+	report := &DriftReport{}
+	for _, zone := range whichZonesToProcess(cfg.Domains, args.Domains) {
+		report.Zones = append(report.Zones, driftZones(zone, args.Providers)...)
+	}
+
+	var anyErrors bool
+	for _, dz := range report.Zones {
+		report.Drift = report.Drift || dz.Drift
+		anyErrors = anyErrors || dz.Error != ""
+	}
+
+	if args.Format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		writeDriftText(os.Stdout, report, args.Full)
+	}
+
+	switch {
+	case anyErrors:
+		return errors.New("drift: completed with errors")
+	case report.Drift:
+		return errDrift
+	}
+	return nil
+}
+
+// driftZones returns the drift of zone at each of the (selected) providers.
+func driftZones(zone *models.DomainConfig, providerFilter string) []*DriftZone {
+	providersToProcess := whichProvidersToProcess(zone.DNSProviderInstances, providerFilter)
+	var result []*DriftZone
+
+	// The NS records at the apex are added to the desired records by
+	// preview/push. Do the same so that they are not reported as drift.
+	nsList, nsErr := nameservers.DetermineNameserversForProviders(zone, zone.DNSProviderInstances, true)
+	if nsErr == nil {
+		zone.Nameservers = nsList
+		nameservers.AddNSRecords(zone)
+	}
+
+	for _, provider := range providersToProcess {
+		dz := &DriftZone{Domain: zone.GetUniqueName(), Provider: provider.Name}
+		result = append(result, dz)
+		if nsErr != nil {
+			dz.Error = nsErr.Error()
+			continue
+		}
+
+		// Let the provider adjust the desired records (TTLs, etc.) the
+		// same way it does during preview.
+		r, err := zonerecs.CorrectZoneRecordsResult(provider.Driver, zone)
+		if err != nil {
+			dz.Error = err.Error()
+			continue
+		}
+		items, err := driftItems(r)
+		if err != nil {
+			dz.Error = err.Error()
+			continue
+		}
+		dz.Items = items
+		dz.Drift = slices.ContainsFunc(items, func(i DriftItem) bool { return i.Class != DriftIgnored })
+	}
+	return result
+}
+
+// driftItems classifies the differences between the existing and the
+// desired records of r, as the provider sees them.
+func driftItems(r *zonerecs.Result) ([]DriftItem, error) {
+	changes, err := r.RecordChanges()
+	if err != nil {
+		return nil, err
+	}
+	var items []DriftItem
+	for _, c := range changes {
+		item := DriftItem{Name: c.Key.NameFQDN, Type: c.Key.Type}
+		switch c.Type {
+		case diff2.CREATE:
+			item.Class = DriftCreate
+			item.Desired = driftRecord(c.New[0])
+			item.FilePos = c.New[0].FilePos
+		case diff2.CHANGE:
+			item.Class = DriftModify
+			item.Live = driftRecord(c.Old[0])
+			item.Desired = driftRecord(c.New[0])
+			item.FilePos = c.New[0].FilePos
+		case diff2.DELETE:
+			item.Class = DriftDelete
+			item.Live = driftRecord(c.Old[0])
+		default:
+			continue
+		}
+		items = append(items, item)
+	}
+
+	ho, err := diff2.FindHandsOff(r.Existing, r.Desired)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[*models.RecordConfig]bool{}
+	for _, group := range []struct {
+		reason string
+		recs   models.Records
+	}{
+		{"IGNORE", ho.Ignored},
+		{"IGNORE_EXTERNAL_DNS", ho.ExternalDNS},
+		{"NO_PURGE", ho.NoPurge},
+		{"OWNERSHIP", ho.NotOwned},
+	} {
+		for _, rec := range group.recs {
+			if seen[rec] {
+				continue
+			}
+			seen[rec] = true
+			items = append(items, DriftItem{
+				Class:  DriftIgnored,
+				Name:   rec.NameFQDN,
+				Type:   rec.Type,
+				Live:   driftRecord(rec),
+				Reason: group.reason,
+			})
+		}
+	}
+
+	slices.SortStableFunc(items, func(a, b DriftItem) int {
+		return cmp.Or(
+			strings.Compare(a.Name, b.Name),
+			strings.Compare(a.Type, b.Type),
+			strings.Compare(a.Class, b.Class),
+		)
+	})
+	return items, nil
+}
+
+func driftRecord(rec *models.RecordConfig) *DriftRecord {
+	return &DriftRecord{TTL: rec.TTL, Data: rec.ToComparableNoTTL()}
+}
+
+// writeDriftText writes the report in a human-readable format.
+func writeDriftText(w io.Writer, report *DriftReport, full bool) {
+	for _, dz := range report.Zones {
+		if dz.Error != "" {
+			fmt.Fprintf(w, "%s (%s): ERROR: %s\n", dz.Domain, dz.Provider, dz.Error)
+			continue
+		}
+		var drift, ignored []DriftItem
+		for _, item := range dz.Items {
+			if item.Class == DriftIgnored {
+				ignored = append(ignored, item)
+			} else {
+				drift = append(drift, item)
+			}
+		}
+		if len(drift) == 0 {
+			fmt.Fprintf(w, "%s (%s): no drift", dz.Domain, dz.Provider)
+		} else {
+			fmt.Fprintf(w, "%s (%s): %d record(s) drifted", dz.Domain, dz.Provider, len(drift))
+		}
+		if len(ignored) != 0 {
+			fmt.Fprintf(w, ", %d ignored", len(ignored))
+		}
+		fmt.Fprintln(w)
+
+		for _, item := range drift {
+			switch item.Class {
+			case DriftCreate:
+				fmt.Fprintf(w, "  would create %s %s %s", item.Name, item.Type, item.Desired.Data)
+			case DriftModify:
+				fmt.Fprintf(w, "  would modify %s %s (%s ttl=%d) -> (%s ttl=%d)", item.Name, item.Type, item.Live.Data, item.Live.TTL, item.Desired.Data, item.Desired.TTL)
+			case DriftDelete:
+				fmt.Fprintf(w, "  would delete (unmanaged) %s %s %s", item.Name, item.Type, item.Live.Data)
+			}
+			if item.FilePos != "" {
+				fmt.Fprintf(w, " %s", item.FilePos)
+			}
+			fmt.Fprintln(w)
+		}
+		if full {
+			for _, item := range ignored {
+				fmt.Fprintf(w, "  ignored (%s) %s %s %s\n", item.Reason, item.Name, item.Type, item.Live.Data)
+			}
+		}
+	}
+}
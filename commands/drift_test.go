@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
+)
+
+func Test_driftItems(t *testing.T) {
+	// The provider compares the "proxy" metadata, but not "comment".
+	withMeta := func(rec *models.RecordConfig, key, value string) *models.RecordConfig {
+		rec.Metadata = map[string]string{key: value}
+		return rec
+	}
+	p := &memProvider{
+		recs: models.Records{
+			makeTestRec("www", "A", "1.1.1.1"),
+			makeTestRec("old", "A", "2.2.2.2"),
+			makeTestRec("ign", "A", "3.3.3.3"),
+			withMeta(makeTestRec("proxied", "A", "5.5.5.5"), "proxy", "on"),
+			withMeta(makeTestRec("commented", "A", "6.6.6.6"), "comment", "a"),
+		},
+		compFunc: func(rc *models.RecordConfig) string { return rc.Metadata["proxy"] },
+	}
+	dc := &models.DomainConfig{
+		Name: "example.com",
+		Records: models.Records{
+			makeTestRec("www", "A", "1.1.1.9"),
+			makeTestRec("new", "A", "4.4.4.4"),
+			withMeta(makeTestRec("proxied", "A", "5.5.5.5"), "proxy", "off"),
+			withMeta(makeTestRec("commented", "A", "6.6.6.6"), "comment", "b"),
+		},
+		Unmanaged: []*models.UnmanagedConfig{{LabelPattern: "ign"}},
+	}
+	dc.Records[1].FilePos = "dnsconfig.js:3:5"
+
+	r, err := zonerecs.CorrectZoneRecordsResult(p, dc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := driftItems(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, i := range items {
+		got = append(got, i.Class+" "+i.Name+" "+i.Reason+" "+i.FilePos)
+	}
+	want := []string{
+		"ignored ign.example.com IGNORE ",
+		"create new.example.com  dnsconfig.js:3:5",
+		"delete old.example.com  ",
+		"modify proxied.example.com  ",
+		"modify www.example.com  ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	out := &bytes.Buffer{}
+	writeDriftText(out, &DriftReport{Zones: []*DriftZone{{Domain: "example.com", Provider: "p", Drift: true, Items: items}}}, false)
+	if !strings.Contains(out.String(), "4 record(s) drifted, 1 ignored") || !strings.Contains(out.String(), "would delete (unmanaged) old.example.com A 2.2.2.2") {
+		t.Errorf("unexpected text output:\n%s", out)
+	}
+}
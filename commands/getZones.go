@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -175,16 +176,9 @@ func GetZone(args GetZoneArgs) error {
 	}
 
 	// decide which zones we need to convert
-	zones := args.ZoneNames
-	if len(args.ZoneNames) == 1 && args.ZoneNames[0] == "all" {
-		lister, ok := provider.(providers.ZoneLister)
-		if !ok {
-			return fmt.Errorf("provider type %s:%s cannot list zones to use the 'all' feature", args.CredName, args.ProviderName)
-		}
-		zones, err = lister.ListZones()
-		if err != nil {
-			return fmt.Errorf("failed GetZone LZ: %w", err)
-		}
+	zones, err := expandZoneNames(provider, args.CredName, args.ZoneNames)
+	if err != nil {
+		return fmt.Errorf("failed GetZone: %w", err)
 	}
 
 	// first open output stream and print initial header (if applicable)
@@ -207,20 +201,10 @@ func GetZone(args GetZoneArgs) error {
 	// fetch all of the records
 	zoneRecs := make([]models.Records, len(zones))
 	for i, zone := range zones {
-		ff := domaintags.MakeDomainNameVarieties(zone)
-		recs, err := provider.GetZoneRecords(
-			&models.DomainConfig{
-				Name: ff.NameASCII,
-				Metadata: map[string]string{
-					models.DomainUniqueName:  ff.UniqueName,
-					models.DomainNameRaw:     ff.NameRaw,
-					models.DomainNameUnicode: ff.NameUnicode,
-				},
-			})
+		recs, err := getZoneRecords(provider, zone)
 		if err != nil {
 			return fmt.Errorf("failed GetZone gzr: %w", err)
 		}
-		rtypecontrol.FixLegacyRecords(&recs) // Call this after GetZoneRecords() to fix providers that haven't been updated for RecordConfigV2.
 		zoneRecs[i] = recs
 	}
 
@@ -248,11 +232,9 @@ func GetZone(args GetZoneArgs) error {
 		z := prettyzone.PrettySort(recs, zoneName, 0, nil)
 		switch args.OutputFormat {
 		case "zone":
-			fmt.Fprintf(w, "$ORIGIN %s.\n", zoneName)
-			if err := prettyzone.WriteZoneFileRC(w, z.Records, zoneName, uint32(args.DefaultTTL), nil); err != nil {
+			if err := writeZone(w, z.Records, zoneName, uint32(args.DefaultTTL)); err != nil {
 				return err
 			}
-			fmt.Fprintln(w)
 
 		case "js", "djs":
 			sep := ",\n\t" // Commas at EOL
@@ -380,6 +362,40 @@ func GetZone(args GetZoneArgs) error {
 	return nil
 }
 
+// getZoneRecords downloads the records of zone from provider.
+func getZoneRecords(provider models.DNSProvider, zone string) (models.Records, error) {
+	recs, err := provider.GetZoneRecords(zoneDomainConfig(zone))
+	if err != nil {
+		return nil, err
+	}
+	rtypecontrol.FixLegacyRecords(&recs) // Call this after GetZoneRecords() to fix providers that haven't been updated for RecordConfigV2.
+	return recs, nil
+}
+
+// zoneDomainConfig returns a DomainConfig (without records) for a zone
+// named on the command line.
+func zoneDomainConfig(zone string) *models.DomainConfig {
+	ff := domaintags.MakeDomainNameVarieties(zone)
+	return &models.DomainConfig{
+		Name: ff.NameASCII,
+		Metadata: map[string]string{
+			models.DomainUniqueName:  ff.UniqueName,
+			models.DomainNameRaw:     ff.NameRaw,
+			models.DomainNameUnicode: ff.NameUnicode,
+		},
+	}
+}
+
+// writeZone writes recs as a BIND-style zonefile.
+func writeZone(w io.Writer, recs models.Records, zoneName string, defaultTTL uint32) error {
+	fmt.Fprintf(w, "$ORIGIN %s.\n", zoneName)
+	if err := prettyzone.WriteZoneFileRC(w, recs, zoneName, defaultTTL, nil); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
 // jsonQuoted returns a properly escaped JSON string (without quotes).
 func jsonQuoted(i string) string {
 	// https://stackoverflow.com/questions/51691901
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/guardrails"
+)
+
+// checkGuardrails returns an error if the changes gathered by preview/push
+// exceed the per-zone limits (MAX_CHANGES, MAX_DELETES, --max-changes,
+// --max-deletes) or the global limits (--max-total-changes,
+// --max-total-deletes).
+func checkGuardrails(zones []*models.DomainConfig, args PPreviewArgs, zres *zoneResults) error {
+	limits := guardrails.Limits{MaxChanges: args.MaxChanges, MaxDeletes: args.MaxDeletes}
+	global := guardrails.Limits{MaxChanges: args.MaxTotalChanges, MaxDeletes: args.MaxTotalDeletes}
+	weights := guardrails.Weights{ApexNSSOA: args.WeightApex, RecordSet: args.WeightRecordSet}
+
+	var errs []error
+	var total guardrails.Counts
+	for _, zone := range zones {
+		zl := limits.ForZone(zone)
+		for _, provider := range whichProvidersToProcess(zone.DNSProviderInstances, args.Providers) {
+			r := zres.get(zone, provider.Name)
+			if r == nil {
+				continue
+			}
+			changes, err := r.Changes()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			c := guardrails.Count(changes, weights)
+			total.Add(c)
+			if err := zl.Check(fmt.Sprintf("zone %q at %q", zone.GetUniqueName(), provider.Name), c); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err := global.Check("all zones", total); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
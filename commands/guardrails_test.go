@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/guardrails"
+	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
+)
+
+func Test_checkGuardrails(t *testing.T) {
+	p := &memProvider{recs: models.Records{
+		makeTestRec("a", "A", "1.1.1.1"),
+		makeTestRec("b", "A", "1.1.1.2"),
+		makeTestRec("c", "A", "1.1.1.3"),
+	}}
+	provider := &models.DNSProviderInstance{Driver: p}
+	provider.Name = "mem"
+	provider.IsDefault = true
+	zone := &models.DomainConfig{
+		Name:                 "example.com",
+		UniqueName:           "example.com",
+		Records:              models.Records{makeTestRec("a", "A", "1.1.1.1")},
+		DNSProviderInstances: []*models.DNSProviderInstance{provider},
+	}
+	r, err := zonerecs.CorrectZoneRecordsResult(p, zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zres := newZoneResults()
+	zres.store(zone, provider.Name, r)
+
+	tests := []struct {
+		name    string
+		args    PPreviewArgs
+		maxDel  int // MAX_DELETES()
+		wantErr string
+	}{
+		{"no limits", PPreviewArgs{}, 0, ""},
+		{"within", PPreviewArgs{MaxDeletes: 2}, 0, ""},
+		{"zone", PPreviewArgs{MaxDeletes: 1}, 0, `zone "example.com" at "mem": 2 deletes exceeds the limit of 1`},
+		{"dsl overrides", PPreviewArgs{MaxDeletes: 1}, 5, ""},
+		{"total", PPreviewArgs{MaxTotalChanges: 1}, 0, "all zones: 2 changes exceeds the limit of 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args.WeightApex = guardrails.DefaultWeights.ApexNSSOA
+			tt.args.WeightRecordSet = guardrails.DefaultWeights.RecordSet
+			zone.MaxDeletes = tt.maxDel
+			err := checkGuardrails([]*models.DomainConfig{zone}, tt.args, zres)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+)
+
+// changeEvents converts a diff2.ChangeList to the structured form used by
+// printer.ChangePrinter. REPORTs are not changes and are skipped.
+func changeEvents(changes diff2.ChangeList) []printer.ChangeEvent {
+	var events []printer.ChangeEvent
+	for _, c := range changes {
+		if c.Type == diff2.REPORT {
+			continue
+		}
+		e := printer.ChangeEvent{
+			Verb:    c.Type.String(),
+			Name:    c.Key.NameFQDN,
+			Type:    c.Key.Type,
+			Old:     recordEvents(c.Old),
+			New:     recordEvents(c.New),
+			OnlyTTL: c.HintOnlyTTL,
+		}
+		for _, m := range c.Msgs {
+			e.Msgs = append(e.Msgs, printer.StripColors(m))
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func recordEvents(recs models.Records) []printer.RecordEvent {
+	var r []printer.RecordEvent
+	for _, rec := range recs {
+		r = append(r, printer.RecordEvent{
+			Type:       rec.Type,
+			TTL:        rec.TTL,
+			Data:       rec.ToComparableNoTTL(),
+			FilePos:    rec.FilePos,
+			Provenance: rec.Provenance,
+		})
+	}
+	return r
+}
+
+// reportSources returns where the records that changes would create or
+// modify are defined in dnsconfig.js.
+func reportSources(changes diff2.ChangeList) []ReportSource {
+	var r []ReportSource
+	for _, c := range changes {
+		if c.Type != diff2.CREATE && c.Type != diff2.CHANGE {
+			continue
+		}
+		for _, rec := range c.New {
+			if rec.FilePos == "" {
+				continue
+			}
+			r = append(r, ReportSource{
+				Verb:       c.Type.String(),
+				Name:       rec.NameFQDN,
+				Type:       rec.Type,
+				FilePos:    rec.FilePos,
+				Provenance: rec.Provenance,
+			})
+		}
+	}
+	return r
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+)
+
+func Test_reportSources(t *testing.T) {
+	mk := func(label, ip, filePos string) *models.RecordConfig {
+		rc := &models.RecordConfig{TTL: 300, FilePos: filePos}
+		rc.SetLabel(label, "example.com")
+		if err := rc.PopulateFromString("A", ip, "example.com"); err != nil {
+			t.Fatal(err)
+		}
+		return rc
+	}
+	created := mk("www", "1.2.3.4", "[dnsconfig.js:3:5]")
+	created.Provenance = []string{`D("example.com") dnsconfig.js:2:1`}
+	existing := mk("api", "1.2.3.5", "")
+	desired := mk("api", "1.2.3.6", "[dnsconfig.js:4:5]")
+	deleted := mk("old", "1.2.3.7", "")
+
+	changes := diff2.ChangeList{
+		{Type: diff2.CREATE, Key: created.Key(), New: models.Records{created}},
+		{Type: diff2.CHANGE, Key: desired.Key(), Old: models.Records{existing}, New: models.Records{desired}},
+		{Type: diff2.DELETE, Key: deleted.Key(), Old: models.Records{deleted}},
+	}
+	want := []ReportSource{
+		{Verb: "CREATE", Name: "www.example.com", Type: "A", FilePos: "[dnsconfig.js:3:5]", Provenance: created.Provenance},
+		{Verb: "CHANGE", Name: "api.example.com", Type: "A", FilePos: "[dnsconfig.js:4:5]"},
+	}
+	if got := reportSources(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("reportSources() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/plan"
+	"github.com/DNSControl/dnscontrol/v4/pkg/staged"
+	"github.com/DNSControl/dnscontrol/v4/pkg/statecache"
+	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
+)
+
+// zoneResults stores the zonerecs.Result of each zone/provider pair that was
+// gathered by preview/push. It is safe for concurrent use.
+type zoneResults struct {
+	sync.Mutex
+	m        map[string]*zonerecs.Result
+	observer gatherObserver    // Optional.
+	staged   *staged.State     // Optional: the state of STAGED_CHANGE().
+	cache    *statecache.Cache // Optional: the state cache (preview --state-cache).
+}
+
+func newZoneResults() *zoneResults {
+	return &zoneResults{m: map[string]*zonerecs.Result{}}
+}
+
+func zoneResultKey(zone *models.DomainConfig, providerName string) string {
+	return zone.UniqueName + "\t" + providerName
+}
+
+func (zr *zoneResults) store(zone *models.DomainConfig, providerName string, r *zonerecs.Result) {
+	zr.Lock()
+	defer zr.Unlock()
+	zr.m[zoneResultKey(zone, providerName)] = r
+}
+
+// gathered reports to the observer (if any) how gathering zone at
+// providerName went.
+func (zr *zoneResults) gathered(zone *models.DomainConfig, providerName string, d time.Duration, err error) {
+	if zr.observer != nil {
+		zr.observer.ZoneGathered(zone.GetUniqueName(), providerName, d, err)
+	}
+}
+
+// stagedAdjuster returns the zonerecs.Adjuster that applies STAGED_CHANGE()
+// to zone at providerName, or nil if no zone uses it.
+func (zr *zoneResults) stagedAdjuster(zone *models.DomainConfig, providerName string) zonerecs.Adjuster {
+	if zr.staged == nil {
+		return nil
+	}
+	return func(existing models.Records, dc *models.DomainConfig) ([]string, error) {
+		return zr.staged.Apply(zone.UniqueName, providerName, existing, dc, time.Now())
+	}
+}
+
+// usesStagedChanges returns true if any record of cfg has STAGED_CHANGE().
+func usesStagedChanges(cfg *models.DNSConfig) bool {
+	for _, dc := range cfg.Domains {
+		for _, rec := range dc.Records {
+			if _, ok := rec.Metadata[staged.MetaLowTTL]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// get returns the result for zone at providerName, or nil if the zone was not
+// gathered (filtered out or an error occurred).
+func (zr *zoneResults) get(zone *models.DomainConfig, providerName string) *zonerecs.Result {
+	zr.Lock()
+	defer zr.Unlock()
+	return zr.m[zoneResultKey(zone, providerName)]
+}
+
+// buildPlan generates a plan from the data gathered by preview.
+func buildPlan(zones []*models.DomainConfig, providerFilter string, zres *zoneResults) (*plan.Plan, error) {
+	p := plan.New()
+	for _, zone := range zones {
+		providersToProcess := whichProvidersToProcess(zone.DNSProviderInstances, providerFilter)
+		for _, provider := range providersToProcess {
+			if r := zres.get(zone, provider.Name); r != nil {
+				changes, err := r.Changes()
+				if err != nil {
+					return nil, err
+				}
+				p.Add(plan.ForProvider(zone.UniqueName, provider.Name, r.Existing, changes))
+			}
+		}
+		if skipProvider(zone.RegistrarInstance.Name, providersToProcess) {
+			if details := correctionDetails(zone.GetCorrections(zone.RegistrarInstance.Name)); len(details) != 0 {
+				p.Add(plan.ForRegistrar(zone.UniqueName, zone.RegistrarInstance.Name, details))
+			}
+		}
+	}
+	return p, nil
+}
+
+// checkPlan verifies that the data gathered by push matches the plan exactly.
+// That is, the live zones are unchanged since the plan was made and the
+// changes that will be made are the changes that were planned.
+func checkPlan(p *plan.Plan, zones []*models.DomainConfig, providerFilter string, zres *zoneResults) error {
+	var errs []error
+	visited := map[*plan.ZonePlan]bool{}
+	for _, zone := range zones {
+		providersToProcess := whichProvidersToProcess(zone.DNSProviderInstances, providerFilter)
+		for _, provider := range providersToProcess {
+			r := zres.get(zone, provider.Name)
+			if r == nil {
+				errs = append(errs, fmt.Errorf("zone %q at %q could not be verified against the plan", zone.UniqueName, provider.Name))
+				continue
+			}
+			changes, err := r.Changes()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			zp := p.Find(zone.UniqueName, provider.Name)
+			if zp == nil {
+				errs = append(errs, fmt.Errorf("zone %q at %q is not in the plan", zone.UniqueName, provider.Name))
+				continue
+			}
+			visited[zp] = true
+			if err := zp.CheckExisting(r.Existing); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err := zp.CheckChanges(changes); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if skipProvider(zone.RegistrarInstance.Name, providersToProcess) {
+			details := correctionDetails(zone.GetCorrections(zone.RegistrarInstance.Name))
+			zp := p.FindRegistrar(zone.UniqueName, zone.RegistrarInstance.Name)
+			if zp == nil {
+				zp = plan.ForRegistrar(zone.UniqueName, zone.RegistrarInstance.Name, nil)
+			}
+			visited[zp] = true
+			if err := zp.CheckCorrections(details); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for _, zp := range p.Zones {
+		if !visited[zp] {
+			errs = append(errs, fmt.Errorf("zone %q at %q is in the plan but was not processed (check --domains and --providers)", zp.Zone, zp.Provider+zp.Registrar))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"runtime"
@@ -20,13 +21,18 @@ import (
 	"github.com/DNSControl/dnscontrol/v4/models"
 	"github.com/DNSControl/dnscontrol/v4/pkg/bindserial"
 	"github.com/DNSControl/dnscontrol/v4/pkg/credsfile"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
 	"github.com/DNSControl/dnscontrol/v4/pkg/domaintags"
+	"github.com/DNSControl/dnscontrol/v4/pkg/guardrails"
 	"github.com/DNSControl/dnscontrol/v4/pkg/nameservers"
 	"github.com/DNSControl/dnscontrol/v4/pkg/normalize"
 	"github.com/DNSControl/dnscontrol/v4/pkg/notifications"
+	"github.com/DNSControl/dnscontrol/v4/pkg/plan"
 	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
 	"github.com/DNSControl/dnscontrol/v4/pkg/providers"
 	"github.com/DNSControl/dnscontrol/v4/pkg/rfc4183"
+	"github.com/DNSControl/dnscontrol/v4/pkg/staged"
+	"github.com/DNSControl/dnscontrol/v4/pkg/statecache"
 	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
 	"github.com/dustin/go-humanize"
 	"github.com/nozzle/throttler"
@@ -48,9 +54,14 @@ var _ = cmd(catMain, func() *cli.Command {
 		Name:  "preview",
 		Usage: "read live configuration and identify changes to be made, without applying them",
 		Action: func(ctx context.Context, c *cli.Command) error {
+			args.redirectForJSON()
 			return exit(PPreview(args))
 		},
-		Flags: args.flags(),
+		Flags: append(args.flags(), &cli.StringFlag{
+			Name:        "at",
+			Destination: &args.At,
+			Usage:       `Evaluate VALID_FROM() and VALID_UNTIL() at this time instead of now (Ex: "2025-06-20T02:00:00Z", "+24h")`,
+		}, args.stateCacheFlag()),
 	}
 }())
 
@@ -59,6 +70,7 @@ type PPreviewArgs struct {
 	GetDNSConfigArgs
 	GetCredentialsArgs
 	FilterArgs
+	PolicyArgs
 	Notify            bool
 	WarnChanges       bool
 	ConcurMode        string
@@ -67,6 +79,26 @@ type PPreviewArgs struct {
 	PopulateOnPreview bool
 	Report            string
 	Full              bool
+	Output            string        // Output format: text, json
+	PlanOut           string        // Write the plan to this file (preview)
+	PlanFile          string        // Only apply the changes in this plan (push)
+	RollbackOnError   bool          // Restore a zone's records if a correction fails (push)
+	BackupDir         string        // Save the records of each zone before changing it (push)
+	Force             bool          // Push even if the limits are exceeded (push)
+	MaxChanges        int           // Limit on the number of changes per zone (0 = no limit)
+	MaxDeletes        int           // Limit on the number of deletes per zone (0 = no limit)
+	MaxTotalChanges   int           // Limit on the number of changes in total (0 = no limit)
+	MaxTotalDeletes   int           // Limit on the number of deletes in total (0 = no limit)
+	WeightApex        int           // How much deleting an apex NS/SOA record counts
+	WeightRecordSet   int           // How much deleting all records of a type at a label counts (per record)
+	Canary            bool          // Push to one provider and verify before pushing to the others (push)
+	CanaryProvider    string        // The provider to push to first (push)
+	CanaryTimeout     time.Duration // How long to wait for the canary to serve the changes (push)
+	Verify            bool          // Check that the nameservers serve the changes after pushing (push)
+	VerifyTimeout     time.Duration // How long to wait for the nameservers to serve the changes (push)
+	At                string        // Evaluate VALID_FROM/VALID_UNTIL at this time instead of now (preview)
+	StagedState       string        // The state file of STAGED_CHANGE()
+	StateCache        string        // The file of the records of the zones (preview)
 }
 
 // ReportItem is a record of corrections for a particular domain/provider/registrar.
@@ -81,6 +113,7 @@ func (args *PPreviewArgs) flags() []cli.Flag {
 	flags := args.GetDNSConfigArgs.flags()
 	flags = append(flags, args.GetCredentialsArgs.flags()...)
 	flags = append(flags, args.FilterArgs.flags()...)
+	flags = append(flags, args.PolicyArgs.flags()...)
 	flags = append(flags, &cli.BoolFlag{
 		Name:        "notify",
 		Destination: &args.Notify,
@@ -91,32 +124,7 @@ func (args *PPreviewArgs) flags() []cli.Flag {
 		Destination: &args.WarnChanges,
 		Usage:       `set to true for non-zero return code if there are changes`,
 	})
-	flags = append(flags, &cli.StringFlag{
-		Name:        "cmode",
-		Destination: &args.ConcurMode,
-		Value:       "concurrent",
-		Usage:       `Which providers to run concurrently: concurrent, none, all`,
-		Action: func(ctx context.Context, c *cli.Command, s string) error {
-			if !slices.Contains([]string{"concurrent", "none", "all"}, s) {
-				fmt.Printf("%q is not a valid option for --cmode.  Values are: concurrent, none, all\n", s)
-				os.Exit(1)
-			}
-			return nil
-		},
-	})
-	flags = append(flags, &cli.IntFlag{
-		Name:        "cmax",
-		Destination: &args.ConcurMax,
-		Value:       100,
-		Usage:       `Maximum number of concurrent connections`,
-		Action: func(ctx context.Context, c *cli.Command, v int) error {
-			if v < 1 {
-				fmt.Printf("%d is not a valid value for --cmax.  Values must be 1 or greater\n", v)
-				os.Exit(1)
-			}
-			return nil
-		},
-	})
+	flags = append(flags, concurFlags(&args.ConcurMode, &args.ConcurMax)...)
 	flags = append(flags, &cli.BoolFlag{
 		Name:        "no-populate",
 		Destination: &args.NoPopulate,
@@ -152,15 +160,90 @@ func (args *PPreviewArgs) flags() []cli.Flag {
 		Destination: &args.Report,
 		Usage:       `Generate a machine-parseable report of corrections.`,
 	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "output",
+		Destination: &args.Output,
+		Value:       "text",
+		Usage:       `Output format: text, json (one JSON object per event)`,
+		Action: func(ctx context.Context, c *cli.Command, s string) error {
+			if !slices.Contains([]string{"text", "json"}, s) {
+				fmt.Printf("%q is not a valid option for --output.  Values are: text, json\n", s)
+				os.Exit(1)
+			}
+			return nil
+		},
+	})
+	flags = append(flags, &cli.IntFlag{
+		Name:        "max-changes",
+		Destination: &args.MaxChanges,
+		Usage:       `Refuse to push more than this many changes to a zone (0 = no limit). MAX_CHANGES() overrides it`,
+	})
+	flags = append(flags, &cli.IntFlag{
+		Name:        "max-deletes",
+		Destination: &args.MaxDeletes,
+		Usage:       `Refuse to push more than this many deletes to a zone (0 = no limit). MAX_DELETES() overrides it`,
+	})
+	flags = append(flags, &cli.IntFlag{
+		Name:        "max-total-changes",
+		Destination: &args.MaxTotalChanges,
+		Usage:       `Refuse to push more than this many changes in total (0 = no limit)`,
+	})
+	flags = append(flags, &cli.IntFlag{
+		Name:        "max-total-deletes",
+		Destination: &args.MaxTotalDeletes,
+		Usage:       `Refuse to push more than this many deletes in total (0 = no limit)`,
+	})
+	flags = append(flags, &cli.IntFlag{
+		Name:        "weight-apex",
+		Destination: &args.WeightApex,
+		Value:       guardrails.DefaultWeights.ApexNSSOA,
+		Usage:       `For the limits, deleting an NS or SOA record at the apex counts this many times`,
+	})
+	flags = append(flags, &cli.IntFlag{
+		Name:        "weight-recordset",
+		Destination: &args.WeightRecordSet,
+		Value:       guardrails.DefaultWeights.RecordSet,
+		Usage:       `For the limits, deleting all records of a type at a label counts this many times per record`,
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "staged-state",
+		Destination: &args.StagedState,
+		Value:       "dnscontrol-staged.json",
+		Usage:       `The file that keeps track of the records that STAGED_CHANGE() is changing`,
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "plan-out",
+		Destination: &args.PlanOut,
+		Usage:       `Write a machine-readable plan of the changes to this file (for use with push --plan)`,
+	})
 	return flags
 }
 
+// stateCacheFlag is the --state-cache flag of preview and serve.
+func (args *PPreviewArgs) stateCacheFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:        "state-cache",
+		Destination: &args.StateCache,
+		Usage:       `Keep the records of the zones in this file, and only download the zones that changed since (at providers that can tell)`,
+	}
+}
+
+// forceFlag is the --force flag of push and serve.
+func (args *PPreviewArgs) forceFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:        "force",
+		Destination: &args.Force,
+		Usage:       "Push even if the changes exceed the limits (MAX_CHANGES, --max-changes, etc.)",
+	}
+}
+
 var _ = cmd(catMain, func() *cli.Command {
 	var args PPushArgs
 	return &cli.Command{
 		Name:  "push",
 		Usage: "identify changes to be made, and perform them",
 		Action: func(ctx context.Context, c *cli.Command) error {
+			args.redirectForJSON()
 			return exit(PPush(args))
 		},
 		Flags: args.flags(),
@@ -180,17 +263,119 @@ func (args *PPushArgs) flags() []cli.Flag {
 		Destination: &args.Interactive,
 		Usage:       "Interactive. Confirm or Exclude each correction before they run",
 	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "plan",
+		Destination: &args.PlanFile,
+		Usage:       "Apply the changes in this plan (from preview --plan-out). Refuse to run if the zones no longer match it",
+	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "rollback-on-error",
+		Destination: &args.RollbackOnError,
+		Usage:       "If a correction fails, restore the zone's records at that provider to what they were before the push",
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "backup-dir",
+		Destination: &args.BackupDir,
+		Usage:       "Before changing a zone, save its records to this directory (see the restore command)",
+	})
+	flags = append(flags, args.forceFlag())
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "canary",
+		Destination: &args.Canary,
+		Usage:       "For zones with several providers, push to one provider, verify its nameservers serve the changes, then push to the others",
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "canary-provider",
+		Destination: &args.CanaryProvider,
+		Usage:       "The provider to push to first with --canary (default: the first provider of each zone)",
+	})
+	flags = append(flags, &cli.DurationFlag{
+		Name:        "canary-timeout",
+		Destination: &args.CanaryTimeout,
+		Value:       2 * time.Minute,
+		Usage:       "How long to wait for the canary's nameservers to serve the changes",
+	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "verify",
+		Destination: &args.Verify,
+		Usage:       "After pushing, check that the nameservers of each provider serve the changes",
+	})
+	flags = append(flags, &cli.DurationFlag{
+		Name:        "verify-timeout",
+		Destination: &args.VerifyTimeout,
+		Value:       2 * time.Minute,
+		Usage:       "How long to wait for the nameservers to serve the changes with --verify",
+	})
 	return flags
 }
 
+// concurFlags returns the --cmode and --cmax flags.
+func concurFlags(mode *string, maxConcur *int) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "cmode",
+			Destination: mode,
+			Value:       "concurrent",
+			Usage:       `Which providers to run concurrently: concurrent, none, all`,
+			Action: func(ctx context.Context, c *cli.Command, s string) error {
+				if !slices.Contains([]string{"concurrent", "none", "all"}, s) {
+					fmt.Printf("%q is not a valid option for --cmode.  Values are: concurrent, none, all\n", s)
+					os.Exit(1)
+				}
+				return nil
+			},
+		},
+		&cli.IntFlag{
+			Name:        "cmax",
+			Destination: maxConcur,
+			Value:       100,
+			Usage:       `Maximum number of concurrent connections`,
+			Action: func(ctx context.Context, c *cli.Command, v int) error {
+				if v < 1 {
+					fmt.Printf("%d is not a valid value for --cmax.  Values must be 1 or greater\n", v)
+					os.Exit(1)
+				}
+				return nil
+			},
+		},
+	}
+}
+
 // PPreview implements the preview subcommand.
 func PPreview(args PPreviewArgs) error {
-	return prun(args, false, false, printer.DefaultPrinter, args.Report)
+	return prun(args, false, false, args.cli(os.Stdout), args.Report)
 }
 
 // PPush implements the push subcommand.
 func PPush(args PPushArgs) error {
-	return prun(args.PPreviewArgs, true, args.Interactive, printer.DefaultPrinter, args.Report)
+	if args.Interactive && args.Output == "json" {
+		return errors.New("-i can not be used with --output=json")
+	}
+	if args.Interactive && args.Canary {
+		return errors.New("-i can not be used with --canary")
+	}
+	if args.Interactive && args.Verify {
+		return errors.New("-i can not be used with --verify")
+	}
+	return prun(args.PPreviewArgs, true, args.Interactive, args.cli(os.Stdout), args.Report)
+}
+
+// cli returns the printer.CLI selected by --output. The JSON stream is
+// written to w.
+func (args *PPreviewArgs) cli(w io.Writer) printer.CLI {
+	if args.Output == "json" {
+		return printer.NewJSONPrinter(w, printer.DefaultPrinter.Verbose)
+	}
+	return printer.DefaultPrinter
+}
+
+// redirectForJSON keeps stdout clean for the JSON stream of --output=json:
+// anything printed elsewhere (providers, etc.) goes to stderr. It is called
+// once, by the preview and push commands.
+func (args *PPreviewArgs) redirectForJSON() {
+	if args.Output == "json" {
+		printer.DefaultPrinter.Writer = os.Stderr
+	}
 }
 
 var pobsoleteDiff2FlagUsed = false
@@ -217,6 +402,17 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 		return err
 	}
 
+	var planIn *plan.Plan
+	if args.PlanFile != "" {
+		out.PrintfIf(fullMode, "Reading plan: %q\n", args.PlanFile)
+		planIn, err = plan.Read(args.PlanFile)
+		if err != nil {
+			return err
+		}
+		// Creating zones is not part of a plan.
+		args.NoPopulate = true
+	}
+
 	var notify = args.Notify
 
 	// We want to notify if args.Notify OR notify_on_*
@@ -245,13 +441,42 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 		return err
 	}
 
+	if args.At != "" {
+		at, err := parseAt(args.At, time.Now())
+		if err != nil {
+			return err
+		}
+		out.Printf("Evaluating VALID_FROM() and VALID_UNTIL() at %s\n", at.UTC().Format(time.RFC3339))
+		normalize.Now = func() time.Time { return at }
+	}
+
 	out.PrintfIf(fullMode, "Normalizing and validating 'desired'..\n")
 	errs := normalize.ValidateAndNormalizeConfig(cfg)
+	errs = append(errs, checkDangling(cfg)...)
+	perrs, err := checkPolicy(args.PolicyArgs, cfg)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, perrs...)
 	if PrintValidationErrors(errs) {
 		return errors.New("exiting due to validation errors")
 	}
 
 	zcache := NewCmdZoneCache()
+	zres := newZoneResults()
+	if o, ok := out.(gatherObserver); ok {
+		zres.observer = o
+	}
+	if args.StateCache != "" {
+		if zres.cache, err = statecache.Load(args.StateCache); err != nil {
+			return fmt.Errorf("could not read the state cache: %w", err)
+		}
+	}
+	if usesStagedChanges(cfg) {
+		if zres.staged, err = staged.Load(args.StagedState); err != nil {
+			return fmt.Errorf("could not read the STAGED_CHANGE() state: %w", err)
+		}
+	}
 
 	// Loop over all (or some) zones:
 	zonesToProcess := whichZonesToProcess(cfg.Domains, args.Domains)
@@ -327,7 +552,7 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 					totalCorrections += len(corrections)
 					out.EndProvider2(provider.Name, len(corrections))
 					reportItems = append(reportItems, genReportItem(zone.Name, corrections, provider.Name, ""))
-					anyErrors = cmp.Or(anyErrors, pprintOrRunCorrections(zone.Name, provider.Name, corrections, out, push || args.PopulateOnPreview, interactive, notifier, report))
+					anyErrors = cmp.Or(anyErrors, pprintOrRunCorrections(zone.Name, provider.Name, corrections, out, push || args.PopulateOnPreview, interactive, notifier, report, false))
 				}
 			}
 		}
@@ -340,7 +565,7 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 		out.PrintfIf(fullMode, "Concurrently gathering: %q\n", zone.UniqueName)
 		go func(zone *models.DomainConfig, args PPreviewArgs, zcache *CmdZoneCache) {
 			start := time.Now()
-			err := oneZone(zone, args)
+			err := oneZone(zone, args, zres)
 			if err != nil {
 				concurrentErrors.Store(true)
 			}
@@ -358,7 +583,7 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 	out.Printf("SERIALLY gathering records of %d zone(s)\n", len(zonesSerial))
 	for _, zone := range zonesSerial {
 		out.Printf("Serially Gathering: %q\n", zone.UniqueName)
-		if err := oneZone(zone, args); err != nil {
+		if err := oneZone(zone, args, zres); err != nil {
 			anyErrors = true
 		}
 	}
@@ -378,23 +603,114 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 
 	anyErrors = cmp.Or(anyErrors, concurrentErrors.Load())
 
+	if zres.cache != nil {
+		hits, misses := zres.cache.Stats()
+		out.Printf("State cache: %d zone(s) unchanged, %d downloaded\n", hits, misses)
+		if err := zres.cache.Save(args.StateCache); err != nil {
+			out.Warnf("Could not save the state cache to %q: %s\n", args.StateCache, err)
+		}
+	}
+
+	if planIn != nil {
+		if anyErrors {
+			return errors.New("exiting due to errors while verifying the plan")
+		}
+		if err := checkPlan(planIn, zonesToProcess, args.Providers, zres); err != nil {
+			out.Printf("%s\n", err)
+			return errors.New("live zones no longer match the plan; run preview again")
+		}
+	}
+
+	if err := checkGuardrails(zonesToProcess, args, zres); err != nil {
+		switch {
+		case !push:
+			out.Warnf("push will refuse to run these changes (unless --force is used):\n%s\n", err)
+		case args.Force:
+			out.Warnf("%s\n(Continuing because of --force)\n", err)
+		default:
+			out.Printf("%s\n", err)
+			return errors.New("the changes exceed the limits; use --force to push them anyway")
+		}
+	}
+
 	// Now we know what to do, print or do the tasks.
 	out.PrintfIf(fullMode, "PHASE 3: CORRECTIONS\n")
-	for _, zone := range zonesToProcess {
+	var rollbacks []*rollbackResult
+	var pushes []pushed
+	backupTime := time.Now()
+zones:
+	for _, zone := range orderZones(zonesToProcess, args.Providers, zres, out) {
 		out.StartDomain(zone)
 
 		// Process DNS provider changes:
 		providersToProcess := whichProvidersToProcess(zone.DNSProviderInstances, args.Providers)
-		for _, provider := range zone.DNSProviderInstances {
+		var canary *models.DNSProviderInstance
+		if push && args.Canary {
+			canary = canaryFor(zone, providersToProcess, args.CanaryProvider)
+		}
+		for _, provider := range canaryOrder(zone.DNSProviderInstances, canary) {
 			skip := skipProvider(provider.Name, providersToProcess)
 			out.StartDNSProvider(provider.Name, skip)
 			if !skip {
+				// The changes are only computed if they are output.
+				cp, printChanges := out.(printer.ChangePrinter)
+				var changes diff2.ChangeList
+				if r := zres.get(zone, provider.Name); r != nil && (printChanges || report != "") {
+					var err error
+					if changes, err = r.Changes(); err != nil {
+						out.Errorf("Error describing the changes to %q at %q: %s\n", zone.UniqueName, provider.Name, err)
+						anyErrors = true
+					}
+				}
+				if printChanges {
+					for _, c := range changeEvents(changes) {
+						cp.PrintChange(c)
+					}
+				}
 				corrections := zone.GetCorrections(provider.Name)
 				numActions := zone.GetChangeCount(provider.Name)
 				totalCorrections += numActions
 				out.EndProvider2(provider.Name, numActions)
-				reportItems = append(reportItems, genReportItem(zone.Name, corrections, provider.Name, ""))
-				anyErrors = cmp.Or(anyErrors, pprintOrRunCorrections(zone.Name, provider.Name, corrections, out, push, interactive, notifier, report))
+				item := genReportItem(zone.Name, corrections, provider.Name, "")
+				item.Sources = reportSources(changes)
+				reportItems = append(reportItems, item)
+				if push && args.BackupDir != "" && hasActions(corrections) {
+					files, err := backupZone(args.BackupDir, backupTime, zone, provider, zres.get(zone, provider.Name))
+					if err != nil {
+						out.Errorf("Backup of %q at %q failed; not changing it: %s\n", zone.UniqueName, provider.Name, err)
+						anyErrors = true
+						if provider == canary {
+							out.Errorf("CANARY: Aborting; the remaining providers and zones are not changed.\n")
+							break zones
+						}
+						continue
+					}
+					out.Printf("Backup of %q at %q saved to %s\n", zone.UniqueName, provider.Name, files[1])
+				}
+				failed := pprintOrRunCorrections(zone.Name, provider.Name, corrections, out, push, interactive, notifier, report, args.RollbackOnError)
+				anyErrors = cmp.Or(anyErrors, failed)
+				if failed && push && args.RollbackOnError {
+					rollbacks = append(rollbacks, rollbackZone(zone, provider, zres.get(zone, provider.Name), out))
+				}
+				if push && !failed && zres.staged != nil {
+					zres.staged.Commit(zone.UniqueName, provider.Name)
+				}
+				if push && args.Verify && !failed && hasActions(corrections) {
+					pushes = append(pushes, pushed{zone: zone, provider: provider, result: zres.get(zone, provider.Name)})
+				}
+				if provider == canary {
+					var err error
+					if failed {
+						err = fmt.Errorf("pushing the changes to %q at the canary %q failed", zone.GetUniqueName(), provider.Name)
+					} else if hasActions(corrections) {
+						err = verifyCanary(zone, provider, zres.get(zone, provider.Name), args.CanaryTimeout, out)
+					}
+					if err != nil {
+						out.Errorf("CANARY: %s\nCANARY: Aborting; the remaining providers and zones are not changed.\n", err)
+						anyErrors = true
+						break zones
+					}
+				}
 			}
 		}
 
@@ -407,7 +723,7 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 			out.EndProvider2(zone.RegistrarName, numActions)
 			totalCorrections += numActions
 			reportItems = append(reportItems, genReportItem(zone.Name, corrections, "", zone.RegistrarName))
-			anyErrors = cmp.Or(anyErrors, pprintOrRunCorrections(zone.Name, zone.RegistrarInstance.Name, corrections, out, push, interactive, notifier, report))
+			anyErrors = cmp.Or(anyErrors, pprintOrRunCorrections(zone.Name, zone.RegistrarInstance.Name, corrections, out, push, interactive, notifier, report, false))
 		}
 	}
 
@@ -417,12 +733,31 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 	rfc4183.PrintWarning()
 	out.PrintfIf(fullMode, "Inaccurate statistics: %s\n", stats(cfg))
 	notifier.Done()
+	printRollbacks(out, rollbacks)
+	if push && zres.staged != nil {
+		if err := zres.staged.Save(args.StagedState); err != nil {
+			out.Errorf("Could not save the STAGED_CHANGE() state to %q: %s\n", args.StagedState, err)
+			anyErrors = true
+		}
+	}
+	if len(pushes) != 0 {
+		anyErrors = cmp.Or(anyErrors, verifyPushes(pushes, args.VerifyTimeout, out))
+	}
 	out.Printf("Done. %d corrections.\n", totalCorrections)
 
 	err = writeReport(report, reportItems)
 	if err != nil {
 		return errors.New("could not write report")
 	}
+	if args.PlanOut != "" {
+		if anyErrors {
+			out.Warnf("Plan not written to %q because of errors\n", args.PlanOut)
+		} else if p, err := buildPlan(zonesToProcess, args.Providers, zres); err != nil {
+			return fmt.Errorf("could not build plan: %w", err)
+		} else if err := p.Write(args.PlanOut); err != nil {
+			return fmt.Errorf("could not write plan: %w", err)
+		}
+	}
 	if anyErrors {
 		return errors.New("completed with errors")
 	}
@@ -567,7 +902,7 @@ func oneZonePopulate(zone *models.DomainConfig, zc *CmdZoneCache) error {
 	return errors.Join(errs...)
 }
 
-func oneZone(zone *models.DomainConfig, args PPreviewArgs) error {
+func oneZone(zone *models.DomainConfig, args PPreviewArgs, zres *zoneResults) error {
 	var errs []error
 	// Fix the parent zone's delegation: (if able/needed)
 	delegationCorrections, dcCount, err := generateDelegationCorrections(zone, zone.DNSProviderInstances, zone.RegistrarInstance)
@@ -579,7 +914,12 @@ func oneZone(zone *models.DomainConfig, args PPreviewArgs) error {
 	providersToProcess := whichProvidersToProcess(zone.DNSProviderInstances, args.Providers)
 	for _, provider := range providersToProcess {
 		// Update the zone's records at the provider:
-		zoneCor, rep, actualChangeCount, err := generateZoneCorrections(zone, provider)
+		start := time.Now()
+		zoneCor, rep, actualChangeCount, result, err := generateZoneCorrections(zone, provider, zres)
+		zres.gathered(zone, provider.Name, time.Since(start), err)
+		if err == nil {
+			zres.store(zone, provider.Name, result)
+		}
 		zone.StoreCorrections(provider.Name, rep)
 		zone.StoreCorrections(provider.Name, zoneCor)
 		zone.IncrementChangeCount(provider.Name, actualChangeCount)
@@ -647,6 +987,31 @@ func parseCorrectionMsg(s string) []string {
 }
 
 func genReportItem(zoneName string, corrections []*models.Correction, providerName string, registrarName string) *ReportItem {
+	details := correctionDetails(corrections)
+
+	r := ReportItem{
+		Domain:            zoneName,
+		Corrections:       len(details),
+		CorrectionDetails: details,
+	}
+	if providerName != "" {
+		r.Provider = providerName
+	}
+	if registrarName != "" {
+		r.Registrar = registrarName
+	}
+	return &r
+}
+
+// hasActions returns true if any of the corrections would make a change.
+func hasActions(corrections []*models.Correction) bool {
+	return slices.ContainsFunc(corrections, func(c *models.Correction) bool {
+		return c.F != nil
+	})
+}
+
+// correctionDetails returns the list of actions described by corrections.
+func correctionDetails(corrections []*models.Correction) []string {
 	correctionDetails := make([]string, 0)
 	for _, cor := range corrections {
 		if cor.F != nil {
@@ -663,22 +1028,13 @@ func genReportItem(zoneName string, corrections []*models.Correction, providerNa
 			correctionDetails = append(correctionDetails, parseCorrectionMsg(cor.Msg)...)
 		}
 	}
-
-	r := ReportItem{
-		Domain:            zoneName,
-		Corrections:       len(correctionDetails),
-		CorrectionDetails: correctionDetails,
-	}
-	if providerName != "" {
-		r.Provider = providerName
-	}
-	if registrarName != "" {
-		r.Registrar = registrarName
-	}
-	return &r
+	return correctionDetails
 }
 
-func pprintOrRunCorrections(zoneName string, providerName string, corrections []*models.Correction, out printer.CLI, push bool, interactive bool, notifier notifications.Notifier, report string) bool {
+// pprintOrRunCorrections prints (and if push is true, runs) the corrections.
+// It returns true if any correction failed. If stopOnError is true, the
+// remaining corrections are not run after the first failure.
+func pprintOrRunCorrections(zoneName string, providerName string, corrections []*models.Correction, out printer.CLI, push bool, interactive bool, notifier notifications.Notifier, report string, stopOnError bool) bool {
 	if len(corrections) == 0 {
 		return false
 	}
@@ -714,6 +1070,10 @@ func pprintOrRunCorrections(zoneName string, providerName string, corrections []
 			if notifyErr != nil {
 				out.Warnf("Error sending notification: %s\n", notifyErr)
 			}
+			if err != nil && stopOnError {
+				out.Warnf("Not running the remaining corrections for %q at %q\n", zoneName, providerName)
+				break
+			}
 		}
 	}
 
@@ -774,12 +1134,12 @@ func generatePopulateCorrections(provider *models.DNSProviderInstance, zone *mod
 	}}, nil
 }
 
-func generateZoneCorrections(zone *models.DomainConfig, provider *models.DNSProviderInstance) ([]*models.Correction, []*models.Correction, int, error) {
-	reports, zoneCorrections, actualChangeCount, err := zonerecs.CorrectZoneRecords(provider.Driver, zone)
+func generateZoneCorrections(zone *models.DomainConfig, provider *models.DNSProviderInstance, zres *zoneResults) ([]*models.Correction, []*models.Correction, int, *zonerecs.Result, error) {
+	result, err := zonerecs.CorrectZoneRecordsResultAdjusted(zres.driver(provider), zone, zres.stagedAdjuster(zone, provider.Name))
 	if err != nil {
-		return []*models.Correction{{Msg: fmt.Sprintf("Domain %q provider %s Error: %s", zone.Name, provider.Name, err)}}, nil, 0, err
+		return []*models.Correction{{Msg: fmt.Sprintf("Domain %q provider %s Error: %s", zone.Name, provider.Name, err)}}, nil, 0, nil, err
 	}
-	return zoneCorrections, reports, actualChangeCount, nil
+	return result.Corrections, result.Reports, result.ActualChangeCount, result, nil
 }
 
 func generateDelegationCorrections(zone *models.DomainConfig, providers []*models.DNSProviderInstance, _ *models.RegistrarInstance) ([]*models.Correction, int, error) {
@@ -1057,3 +1417,20 @@ func prefineProviderType(credEntryName string, t string, credFields map[string]s
 		return ct, "", nil
 	}
 }
+
+// parseAt parses the value of preview --at: a time (as in VALID_FROM()) or
+// a duration after now (Ex: "+24h").
+func parseAt(s string, now time.Time) (time.Time, error) {
+	if d, ok := strings.CutPrefix(s, "+"); ok {
+		dur, err := time.ParseDuration(d)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("--at: %w", err)
+		}
+		return now.Add(dur), nil
+	}
+	t, err := normalize.ParseTime(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--at: %w", err)
+	}
+	return t, nil
+}
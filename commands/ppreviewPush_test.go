@@ -1,9 +1,13 @@
 package commands
 
 import (
+	"bytes"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
 	"github.com/DNSControl/dnscontrol/v4/pkg/rtypecontrol"
 )
 
@@ -181,3 +185,43 @@ func Test_whichZonesToProcess(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseAt(t *testing.T) {
+	now := time.Date(2025, 6, 20, 2, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"+24h", now.Add(24 * time.Hour), false},
+		{"2025-07-01", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2025-07-01T03:04:05Z", time.Date(2025, 7, 1, 3, 4, 5, 0, time.UTC), false},
+		{"+1 day", time.Time{}, true},
+		{"tomorrow", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAt(tt.in, now)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("parseAt(%q) = %v, %v; want %v (error: %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func Test_cli(t *testing.T) {
+	writer := printer.DefaultPrinter.Writer
+	args := PPreviewArgs{Output: "json"}
+	for range 2 {
+		var buf bytes.Buffer
+		out := args.cli(&buf)
+		out.Printf("hello\n")
+		if printer.DefaultPrinter.Writer != writer {
+			t.Fatal("cli() changed the writer of the DefaultPrinter")
+		}
+		if !strings.Contains(buf.String(), "hello") {
+			t.Errorf("the JSON stream was not written to the writer given: %q", buf.String())
+		}
+	}
+	if out := (&PPreviewArgs{Output: "text"}).cli(nil); out != printer.CLI(printer.DefaultPrinter) {
+		t.Errorf("cli() = %T, want the DefaultPrinter", out)
+	}
+}
@@ -17,6 +17,19 @@ type ReportItem struct {
 	CorrectionDetails []string `json:"correction_details,omitempty"`
 	Provider          string   `json:"provider,omitempty"`
 	Registrar         string   `json:"registrar,omitempty"`
+
+	// Sources lists where the records being created or modified are
+	// defined in dnsconfig.js.
+	Sources []ReportSource `json:"sources,omitempty"`
+}
+
+// ReportSource is where a record in a ReportItem is defined.
+type ReportSource struct {
+	Verb       string   `json:"verb"` // CREATE or CHANGE
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	FilePos    string   `json:"filepos"`
+	Provenance []string `json:"provenance,omitempty"` // The D()/D_EXTEND()/INCLUDE() calls, outermost first.
 }
 
 // InitializeProviders takes (fully processed) configuration and instantiates all providers and returns them.
@@ -10,8 +10,10 @@ import (
 	"strings"
 
 	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/dangling"
 	"github.com/DNSControl/dnscontrol/v4/pkg/js"
 	"github.com/DNSControl/dnscontrol/v4/pkg/normalize"
+	"github.com/DNSControl/dnscontrol/v4/pkg/policy"
 	"github.com/DNSControl/dnscontrol/v4/pkg/rfc4183"
 	"github.com/urfave/cli/v3"
 )
@@ -31,6 +33,11 @@ var _ = cmd(catDebug, func() *cli.Command {
 // CheckArgs encapsulates the flags/arguments for the check command.
 type CheckArgs struct {
 	GetDNSConfigArgs
+	PolicyArgs
+}
+
+func (args *CheckArgs) flags() []cli.Flag {
+	return append(args.GetDNSConfigArgs.flags(), args.PolicyArgs.flags()...)
 }
 
 var _ = cmd(catDebug, func() *cli.Command {
@@ -51,6 +58,7 @@ var _ = cmd(catDebug, func() *cli.Command {
 			pargs.JSONFile = args.JSONFile
 			pargs.DevMode = args.DevMode
 			pargs.Variable = args.Variable
+			pargs.PolicyFile = args.PolicyFile
 			// Force these settings:
 			pargs.Pretty = false
 			pargs.Output = os.DevNull
@@ -74,11 +82,13 @@ var _ = cmd(catDebug, func() *cli.Command {
 type PrintIRArgs struct {
 	GetDNSConfigArgs
 	PrintJSONArgs
+	PolicyArgs
 	Raw bool
 }
 
 func (args *PrintIRArgs) flags() []cli.Flag {
 	flags := append(args.GetDNSConfigArgs.flags(), args.PrintJSONArgs.flags()...)
+	flags = append(flags, args.PolicyArgs.flags()...)
 	flags = append(flags, &cli.BoolFlag{
 		Name:        "raw",
 		Usage:       "Skip validation and normalization. Just print js result.",
@@ -95,6 +105,12 @@ func PrintIR(args PrintIRArgs) error {
 	}
 	if !args.Raw {
 		errs := normalize.ValidateAndNormalizeConfig(cfg)
+		errs = append(errs, checkDangling(cfg)...)
+		perrs, err := checkPolicy(args.PolicyArgs, cfg)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, perrs...)
 		if PrintValidationErrors(errs) {
 			return errors.New("exiting due to validation errors")
 		}
@@ -119,20 +135,73 @@ func PrintValidationErrors(errs []error) (fatal bool) {
 	return
 }
 
+// checkDangling returns the targets in cfg that do not exist or can be taken
+// over, as validation warnings.
+func checkDangling(cfg *models.DNSConfig) []error {
+	findings, err := dangling.Check(cfg)
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, f := range findings {
+		errs = append(errs, normalize.NewWarning(f))
+	}
+	return errs
+}
+
+// checkPolicy returns the violations of the policy file (if any) by cfg, as
+// validation errors and warnings.
+func checkPolicy(args PolicyArgs, cfg *models.DNSConfig) ([]error, error) {
+	if args.PolicyFile == "" {
+		return nil, nil
+	}
+	p, err := policy.Load(args.PolicyFile)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, v := range p.Evaluate(cfg) {
+		if v.Warning {
+			errs = append(errs, normalize.NewWarning(v))
+		} else {
+			errs = append(errs, v)
+		}
+	}
+	return errs, nil
+}
+
 // ExecuteDSL executes the dnsconfig.js contents.
 func ExecuteDSL(args ExecuteDSLArgs) (*models.DNSConfig, error) {
 	if args.JSFile == "" {
 		return nil, errors.New("no config specified")
 	}
 
-	dnsConfig, err := js.ExecuteJavaScript(args.JSFile, args.DevMode, stringSliceToMap(args.Variable))
+	file := configFile(args.JSFile)
+	dnsConfig, err := js.ExecuteJavaScript(file, args.DevMode, stringSliceToMap(args.Variable))
 	if err != nil {
-		return nil, fmt.Errorf("executing %s: %w", args.JSFile, err)
+		return nil, fmt.Errorf("executing %s: %w", file, err)
 	}
 
 	return dnsConfig, nil
 }
 
+// configFile returns the configuration file to run: file, unless it is the
+// default (dnsconfig.js), which doesn't exist, and one of the other default
+// names (TypeScript or a declarative configuration) does.
+func configFile(file string) string {
+	if file != "dnsconfig.js" {
+		return file
+	}
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		for _, name := range []string{"dnsconfig.ts", "dnsconfig.yaml", "dnsconfig.yml", "dnsconfig.toml", "dnsconfig.json"} {
+			if _, err := os.Stat(name); err == nil {
+				return name
+			}
+		}
+	}
+	return file
+}
+
 // PrintJSON outputs/prettyprints the IR data.
 func PrintJSON(args PrintJSONArgs, config *models.DNSConfig) (err error) {
 	var dat []byte
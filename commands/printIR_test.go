@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"os"
+	"testing"
+)
+
+func TestConfigFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if got := configFile("dnsconfig.js"); got != "dnsconfig.js" {
+		t.Errorf("no config: got %q", got)
+	}
+	if err := os.WriteFile("dnsconfig.yaml", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := configFile("dnsconfig.js"); got != "dnsconfig.yaml" {
+		t.Errorf("dnsconfig.yaml only: got %q", got)
+	}
+	if err := os.WriteFile("dnsconfig.ts", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := configFile("dnsconfig.js"); got != "dnsconfig.ts" {
+		t.Errorf("dnsconfig.ts and dnsconfig.yaml: got %q", got)
+	}
+	if got := configFile("other.js"); got != "other.js" {
+		t.Errorf("--config other.js: got %q", got)
+	}
+	if err := os.WriteFile("dnsconfig.js", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := configFile("dnsconfig.js"); got != "dnsconfig.js" {
+		t.Errorf("both: got %q", got)
+	}
+}
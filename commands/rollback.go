@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
+)
+
+// rollbackResult is the outcome of rolling back one zone at one provider.
+type rollbackResult struct {
+	Zone        string
+	Provider    string
+	Corrections int   // Number of corrections that were run to restore the zone.
+	Err         error // nil if the zone was restored.
+}
+
+// rollbackZone restores the records of zone at provider to the snapshot that
+// was taken before the corrections were run (r.Existing). The inverse
+// changes are computed by asking the provider for the corrections that turn
+// the zone's current records into the snapshot.
+func rollbackZone(zone *models.DomainConfig, provider *models.DNSProviderInstance, r *zonerecs.Result, out printer.CLI) *rollbackResult {
+	rr := &rollbackResult{Zone: zone.GetUniqueName(), Provider: provider.Name}
+	if r == nil {
+		rr.Err = errors.New("no snapshot of the zone is available")
+		return rr
+	}
+
+	out.Printf("ROLLBACK: Restoring %q at %q to its state before the push\n", rr.Zone, rr.Provider)
+	dc, err := rollbackConfig(r)
+	if err != nil {
+		rr.Err = err
+		return rr
+	}
+	result, err := zonerecs.CorrectZoneRecordsResult(provider.Driver, dc)
+	if err != nil {
+		rr.Err = err
+		return rr
+	}
+
+	var errs []error
+	for i, c := range result.Corrections {
+		out.PrintCorrection(i, c)
+		err := c.F()
+		out.EndCorrection(err)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		rr.Corrections++
+	}
+	rr.Err = errors.Join(errs...)
+	return rr
+}
+
+// rollbackConfig returns a DomainConfig whose desired records are the
+// snapshot in r. Everything that would prevent the snapshot from being
+// restored exactly (NO_PURGE, IGNORE, ENSURE_ABSENT, OWNERSHIP, ...) is
+// turned off: the snapshot has the records of the other owners, and the
+// ownership markers, as they were.
+func rollbackConfig(r *zonerecs.Result) (*models.DomainConfig, error) {
+	dc, err := r.Desired.Copy()
+	if err != nil {
+		return nil, err
+	}
+	dc.Records = make(models.Records, 0, len(r.Existing))
+	for _, rec := range r.Existing {
+		c, err := rec.Copy()
+		if err != nil {
+			return nil, err
+		}
+		dc.Records = append(dc.Records, c)
+	}
+	dc.KeepUnknown = false
+	dc.Unmanaged = nil
+	dc.UnmanagedUnsafe = false
+	dc.IgnoreExternalDNS = false
+	dc.EnsureAbsent = nil
+	dc.OwnerID = ""
+	dc.OwnerAdopt = false
+	return dc, nil
+}
+
+// printRollbacks prints a summary of the rollbacks that were attempted.
+func printRollbacks(out printer.CLI, rollbacks []*rollbackResult) {
+	if len(rollbacks) == 0 {
+		return
+	}
+	out.Printf("ROLLBACK SUMMARY:\n")
+	for _, rr := range rollbacks {
+		if rr.Err != nil {
+			out.Errorf("ROLLBACK FAILED: %q at %q: %s\n", rr.Zone, rr.Provider, rr.Err)
+			continue
+		}
+		out.Printf("ROLLBACK SUCCEEDED: %q at %q (%d corrections)\n", rr.Zone, rr.Provider, rr.Corrections)
+	}
+}
@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
+)
+
+// memProvider is a DNS provider that keeps the zone in memory. Corrections
+// that touch the label failOn fail. compFunc is its ComparableFunc.
+type memProvider struct {
+	recs     models.Records
+	failOn   string
+	compFunc diff2.ComparableFunc
+}
+
+func (p *memProvider) GetNameservers(string) ([]*models.Nameserver, error) { return nil, nil }
+
+func (p *memProvider) GetZoneRecords(*models.DomainConfig) (models.Records, error) {
+	var r models.Records
+	for _, rec := range p.recs {
+		c, _ := rec.Copy()
+		r = append(r, c)
+	}
+	return r, nil
+}
+
+func (p *memProvider) GetZoneRecordsCorrections(dc *models.DomainConfig, existing models.Records) ([]*models.Correction, int, error) {
+	changes, count, err := diff2.ByRecord(existing, dc, p.compFunc)
+	if err != nil {
+		return nil, 0, err
+	}
+	var corrections []*models.Correction
+	for _, c := range changes {
+		if c.Type == diff2.REPORT {
+			continue
+		}
+		corrections = append(corrections, &models.Correction{
+			Msg: c.MsgsJoined,
+			F: func() error {
+				if c.Key.NameFQDN == p.failOn {
+					return fmt.Errorf("cannot update %s", p.failOn)
+				}
+				for _, rec := range c.Old {
+					p.recs = slices.DeleteFunc(p.recs, func(r *models.RecordConfig) bool {
+						return r.NameFQDN == rec.NameFQDN && r.ToComparableNoTTL() == rec.ToComparableNoTTL()
+					})
+				}
+				p.recs = append(p.recs, c.New...)
+				return nil
+			},
+		})
+	}
+	return corrections, count, nil
+}
+
+func (p *memProvider) zone() []string {
+	var r []string
+	for _, rec := range p.recs {
+		r = append(r, rec.NameFQDN+" "+rec.Type+" "+rec.ToComparableNoTTL())
+	}
+	slices.Sort(r)
+	return r
+}
+
+func makeTestRec(label, rtype, content string) *models.RecordConfig {
+	r := &models.RecordConfig{TTL: 300}
+	r.SetLabel(label, "example.com")
+	if err := r.PopulateFromString(rtype, content, "example.com"); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func Test_rollbackZone(t *testing.T) {
+	p := &memProvider{
+		recs:   models.Records{makeTestRec("www", "A", "1.1.1.1"), makeTestRec("old", "A", "2.2.2.2")},
+		failOn: "new.example.com",
+	}
+	before := p.zone()
+
+	zone := &models.DomainConfig{
+		Name:        "example.com",
+		UniqueName:  "example.com",
+		Records:     models.Records{makeTestRec("www", "A", "1.1.1.9"), makeTestRec("new", "A", "3.3.3.3")},
+		KeepUnknown: true,
+	}
+	provider := &models.DNSProviderInstance{Driver: p}
+	provider.Name = "mem"
+
+	r, err := zonerecs.CorrectZoneRecordsResult(p, zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failed bool
+	for _, c := range r.Corrections {
+		if err := c.F(); err != nil {
+			failed = true
+		}
+	}
+	if !failed {
+		t.Fatal("expected a correction to fail")
+	}
+	if slices.Equal(before, p.zone()) {
+		t.Fatal("expected the zone to be partially updated")
+	}
+
+	p.failOn = ""
+	out := &bytes.Buffer{}
+	rr := rollbackZone(zone, provider, r, &printer.ConsolePrinter{Writer: out})
+	if rr.Err != nil {
+		t.Fatalf("rollback failed: %s", rr.Err)
+	}
+	if rr.Corrections == 0 {
+		t.Error("expected rollback corrections")
+	}
+	if got := p.zone(); !slices.Equal(before, got) {
+		t.Errorf("zone not restored:\nwant %v\n got %v", before, got)
+	}
+}
+
+func Test_rollbackZone_noSnapshot(t *testing.T) {
+	zone := &models.DomainConfig{Name: "example.com", UniqueName: "example.com"}
+	provider := &models.DNSProviderInstance{Driver: &memProvider{}}
+	rr := rollbackZone(zone, provider, nil, &printer.ConsolePrinter{Writer: &bytes.Buffer{}})
+	if rr.Err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func Test_rollbackZone_ownership(t *testing.T) {
+	// A zone shared with OWNERSHIP(): team-b owns "api", and "legacy" is
+	// owned by no one.
+	p := &memProvider{
+		recs: models.Records{
+			makeTestRec("_dnscontrol-a-www", "TXT", "heritage=dnscontrol,dnscontrol/owner=team-a"),
+			makeTestRec("www", "A", "1.1.1.1"),
+			makeTestRec("_dnscontrol-a-api", "TXT", "heritage=dnscontrol,dnscontrol/owner=team-b"),
+			makeTestRec("api", "A", "2.2.2.2"),
+			makeTestRec("legacy", "A", "4.4.4.4"),
+		},
+		failOn: "new.example.com",
+	}
+	before := p.zone()
+
+	zone := &models.DomainConfig{
+		Name:       "example.com",
+		UniqueName: "example.com",
+		Records:    models.Records{makeTestRec("www", "A", "1.1.1.9"), makeTestRec("new", "A", "3.3.3.3")},
+		OwnerID:    "team-a",
+	}
+	provider := &models.DNSProviderInstance{Driver: p}
+	provider.Name = "mem"
+
+	r, err := zonerecs.CorrectZoneRecordsResult(p, zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range r.Corrections {
+		_ = c.F()
+	}
+	if slices.Equal(before, p.zone()) {
+		t.Fatal("expected the zone to be partially updated")
+	}
+
+	p.failOn = ""
+	rr := rollbackZone(zone, provider, r, &printer.ConsolePrinter{Writer: &bytes.Buffer{}})
+	if rr.Err != nil {
+		t.Fatalf("rollback failed: %s", rr.Err)
+	}
+	if got := p.zone(); !slices.Equal(before, got) {
+		t.Errorf("zone not restored:\nwant %v\n got %v", before, got)
+	}
+}
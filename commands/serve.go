@@ -0,0 +1,504 @@
+package commands
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/urfave/cli/v3"
+)
+
+var _ = cmd(catMain, func() *cli.Command {
+	var args ServeArgs
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "run preview (or push) periodically and serve metrics and a status page",
+		Action: func(ctx context.Context, c *cli.Command) error {
+			return exit(Serve(ctx, args))
+		},
+		Flags: args.flags(),
+		Description: `Run the preview pipeline every --interval and whenever dnsconfig.js or
+creds.json change.  With --push, the changes are made too (like "dnscontrol push").
+
+An HTTP server on --listen serves:
+   /             A status page listing the pending changes of each zone.
+   /status.json  The same information as JSON.
+   /metrics      Prometheus metrics.
+
+Documentation: https://docs.dnscontrol.org/commands/serve`,
+	}
+}())
+
+// ServeArgs contains all data/flags needed to run serve, independently of CLI.
+type ServeArgs struct {
+	PPreviewArgs
+	Listen   string        // Address of the HTTP server
+	Interval time.Duration // Time between runs
+	Push     bool          // Make the changes, not just preview them
+	Watch    bool          // Run when dnsconfig.js (or --ir) or creds.json change
+}
+
+// serveSkippedFlags are the flags of preview that don't apply to serve:
+// they are about the output and the exit code of a single run.
+var serveSkippedFlags = []string{"expect-no-changes", "output", "plan-out", "report"}
+
+func (args *ServeArgs) flags() []cli.Flag {
+	flags := slices.DeleteFunc(args.PPreviewArgs.flags(), func(f cli.Flag) bool {
+		return slices.Contains(serveSkippedFlags, f.Names()[0])
+	})
+	flags = append(flags, args.stateCacheFlag(), args.forceFlag())
+	flags = append(flags, &cli.StringFlag{
+		Name:        "listen",
+		Destination: &args.Listen,
+		Value:       "localhost:8080",
+		Usage:       `Address of the HTTP server (status page and /metrics)`,
+	})
+	flags = append(flags, &cli.DurationFlag{
+		Name:        "interval",
+		Destination: &args.Interval,
+		Value:       5 * time.Minute,
+		Usage:       `Time between runs`,
+		Action: func(ctx context.Context, c *cli.Command, d time.Duration) error {
+			if d <= 0 {
+				fmt.Printf("%s is not a valid value for --interval.  It must be greater than 0\n", d)
+				os.Exit(1)
+			}
+			return nil
+		},
+	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "push",
+		Destination: &args.Push,
+		Usage:       `Make the changes (like "dnscontrol push"). Without it, changes are only previewed`,
+	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "watch",
+		Destination: &args.Watch,
+		Value:       true,
+		Usage:       `Run as soon as dnsconfig.js or creds.json change (use --watch=false to disable)`,
+	})
+	return flags
+}
+
+// serveWatchInterval is how often the configuration files are checked for
+// changes.
+const serveWatchInterval = 2 * time.Second
+
+// Serve implements the serve subcommand.
+func Serve(ctx context.Context, args ServeArgs) error {
+	s := newServer(prometheus.NewRegistry())
+
+	ln, err := net.Listen("tcp", args.Listen)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			printer.Printf("ERROR: HTTP server: %s\n", err)
+		}
+	}()
+	defer srv.Close()
+	printer.Printf("Serving the status page and metrics on http://%s/\n", ln.Addr())
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(args.Interval)
+	defer ticker.Stop()
+	var watch <-chan time.Time
+	if args.Watch {
+		wt := time.NewTicker(serveWatchInterval)
+		defer wt.Stop()
+		watch = wt.C
+	}
+
+	mtimes := configModTimes(configFile(args.JSFile), args.GetDNSConfigArgs.JSONFile, args.CredsFile)
+	for {
+		s.reconcile(args.PPreviewArgs, args.Push)
+		ticker.Reset(args.Interval)
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				printer.Printf("Stopping.\n")
+				return nil
+			case <-ticker.C:
+				break wait
+			case <-watch:
+				if t := configModTimes(configFile(args.JSFile), args.GetDNSConfigArgs.JSONFile, args.CredsFile); !maps.Equal(t, mtimes) {
+					mtimes = t
+					printer.Printf("Configuration changed.\n")
+					break wait
+				}
+			}
+		}
+	}
+}
+
+// configModTimes returns the modification time of each file. Files that
+// can not be read are omitted.
+func configModTimes(files ...string) map[string]time.Time {
+	m := map[string]time.Time{}
+	for _, f := range files {
+		if f == "" {
+			continue
+		}
+		if fi, err := os.Stat(f); err == nil {
+			m[f] = fi.ModTime()
+		}
+	}
+	return m
+}
+
+// server holds the state of the serve command.
+type server struct {
+	metrics *serveMetrics
+	reg     *prometheus.Registry
+
+	mu   sync.Mutex
+	last *serveRun // The most recent run. nil until the first run completes.
+}
+
+func newServer(reg *prometheus.Registry) *server {
+	return &server{metrics: newServeMetrics(reg), reg: reg}
+}
+
+// serveRun is the outcome of one run of the preview/push pipeline.
+type serveRun struct {
+	Start    time.Time    `json:"start"`
+	Duration float64      `json:"duration"` // seconds
+	Push     bool         `json:"push"`
+	Error    string       `json:"error,omitempty"`
+	Zones    []*serveZone `json:"zones"`
+}
+
+// serveZone is the status of a zone at a provider (or registrar).
+type serveZone struct {
+	Zone     string   `json:"zone"`
+	Provider string   `json:"provider"`
+	Pending  []string `json:"pending,omitempty"` // Corrections that were not (successfully) made.
+	Applied  int      `json:"applied,omitempty"` // Corrections that were made.
+	Error    string   `json:"error,omitempty"`
+}
+
+// InSync returns true if the zone at the provider matches dnsconfig.js.
+func (z *serveZone) InSync() bool {
+	return z.Error == "" && len(z.Pending) == 0
+}
+
+// reconcile runs the preview/push pipeline once and records the outcome.
+func (s *server) reconcile(args PPreviewArgs, push bool) {
+	rec := newServeRecorder(printer.DefaultPrinter, s.metrics)
+	start := time.Now()
+	err := prun(args, push, false, rec, "")
+	run := &serveRun{
+		Start:    start,
+		Duration: time.Since(start).Seconds(),
+		Push:     push,
+		Zones:    rec.zoneList(),
+	}
+	if err != nil {
+		run.Error = err.Error()
+		printer.Printf("ERROR: %s\n", err)
+	}
+	s.metrics.observeRun(run)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = run
+}
+
+func (s *server) lastRun() *serveRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(s.reg, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/status.json", s.handleStatusJSON)
+	mux.HandleFunc("/{$}", s.handleStatus)
+	return mux
+}
+
+func (s *server) handleStatusJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(s.lastRun())
+}
+
+var statusTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>DNSControl status</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.ok { color: green; }
+.pending { color: darkorange; }
+.error { color: red; }
+</style>
+</head>
+<body>
+<h1>DNSControl status</h1>
+{{- with .}}
+<p>Last run: {{.Start.Format "2006-01-02 15:04:05 MST"}} ({{printf "%.1f" .Duration}}s, {{if .Push}}push{{else}}preview{{end}})</p>
+{{- if .Error}}
+<p class="error">{{.Error}}</p>
+{{- end}}
+<table>
+<tr><th>Zone</th><th>Provider</th><th>Status</th><th>Pending changes</th></tr>
+{{- range .Zones}}
+<tr>
+<td>{{.Zone}}</td>
+<td>{{.Provider}}</td>
+{{- if .Error}}
+<td class="error">error: {{.Error}}</td>
+{{- else if .Pending}}
+<td class="pending">{{len .Pending}} pending</td>
+{{- else}}
+<td class="ok">in sync</td>
+{{- end}}
+<td>{{range .Pending}}<pre>{{.}}</pre>{{end}}</td>
+</tr>
+{{- end}}
+</table>
+{{- else}}
+<p>The first run has not completed yet.</p>
+{{- end}}
+</body>
+</html>
+`))
+
+func (s *server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := statusTemplate.Execute(w, s.lastRun()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// serveMetrics are the Prometheus metrics exported by serve.
+type serveMetrics struct {
+	zoneInSync  *prometheus.GaugeVec
+	pending     *prometheus.GaugeVec
+	zonesInSync prometheus.Gauge
+	zones       prometheus.Gauge
+	corrections *prometheus.CounterVec
+	provErrors  *prometheus.CounterVec
+	apiLatency  *prometheus.HistogramVec
+	runs        *prometheus.CounterVec
+	lastRun     prometheus.Gauge
+	runDuration prometheus.Gauge
+}
+
+func newServeMetrics(reg prometheus.Registerer) *serveMetrics {
+	m := &serveMetrics{
+		zoneInSync: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "dnscontrol_zone_in_sync",
+			Help: "1 if the zone at the provider matches dnsconfig.js, 0 otherwise.",
+		}, []string{"zone", "provider"}),
+		pending: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "dnscontrol_zone_pending_corrections",
+			Help: "Number of corrections needed to bring the zone at the provider in sync.",
+		}, []string{"zone", "provider"}),
+		zonesInSync: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "dnscontrol_zones_in_sync",
+			Help: "Number of zone/provider pairs that match dnsconfig.js.",
+		}),
+		zones: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "dnscontrol_zones",
+			Help: "Number of zone/provider pairs checked by the last run.",
+		}),
+		corrections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "dnscontrol_corrections_total",
+			Help: "Number of corrections run, by result (applied, failed).",
+		}, []string{"zone", "provider", "result"}),
+		provErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "dnscontrol_provider_errors_total",
+			Help: "Number of errors returned by providers while gathering records or running corrections.",
+		}, []string{"provider"}),
+		apiLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "dnscontrol_provider_duration_seconds",
+			Help:    "Time spent in provider APIs, by operation (gather, correction).",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+		}, []string{"provider", "operation"}),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "dnscontrol_runs_total",
+			Help: "Number of runs of the preview/push pipeline, by result (success, error).",
+		}, []string{"result"}),
+		lastRun: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "dnscontrol_last_run_timestamp_seconds",
+			Help: "Time the last run of the preview/push pipeline completed.",
+		}),
+		runDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "dnscontrol_last_run_duration_seconds",
+			Help: "How long the last run of the preview/push pipeline took.",
+		}),
+	}
+	reg.MustRegister(m.zoneInSync, m.pending, m.zonesInSync, m.zones, m.corrections,
+		m.provErrors, m.apiLatency, m.runs, m.lastRun, m.runDuration)
+	return m
+}
+
+// observeRun updates the metrics that describe the state after a run.
+func (m *serveMetrics) observeRun(run *serveRun) {
+	m.zoneInSync.Reset()
+	m.pending.Reset()
+	inSync := 0
+	for _, z := range run.Zones {
+		v := 0.0
+		if z.InSync() {
+			v = 1
+			inSync++
+		}
+		m.zoneInSync.WithLabelValues(z.Zone, z.Provider).Set(v)
+		m.pending.WithLabelValues(z.Zone, z.Provider).Set(float64(len(z.Pending)))
+	}
+	m.zonesInSync.Set(float64(inSync))
+	m.zones.Set(float64(len(run.Zones)))
+
+	result := "success"
+	if run.Error != "" {
+		result = "error"
+	}
+	m.runs.WithLabelValues(result).Inc()
+	m.lastRun.SetToCurrentTime()
+	m.runDuration.Set(run.Duration)
+}
+
+// gatherObserver is implemented by printers that want to know how the
+// data-gathering phase of preview/push went for each zone at each provider.
+type gatherObserver interface {
+	ZoneGathered(zone, provider string, d time.Duration, err error)
+}
+
+// serveRecorder is a printer.CLI that records the outcome of a run of the
+// preview/push pipeline (for the status page and metrics) and passes
+// everything on to the printer it wraps.
+type serveRecorder struct {
+	printer.CLI
+	metrics *serveMetrics
+
+	mu        sync.Mutex
+	zones     map[string]*serveZone
+	domain    string
+	provider  string
+	corrStart time.Time
+}
+
+func newServeRecorder(out printer.CLI, m *serveMetrics) *serveRecorder {
+	return &serveRecorder{CLI: out, metrics: m, zones: map[string]*serveZone{}}
+}
+
+// zone returns the status of domain at provider. The caller must hold r.mu.
+func (r *serveRecorder) zone(domain, provider string) *serveZone {
+	key := domain + "\t" + provider
+	z, ok := r.zones[key]
+	if !ok {
+		z = &serveZone{Zone: domain, Provider: provider}
+		r.zones[key] = z
+	}
+	return z
+}
+
+// zoneList returns the status of every zone seen, sorted.
+func (r *serveRecorder) zoneList() []*serveZone {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l := slices.Collect(maps.Values(r.zones))
+	slices.SortFunc(l, func(a, b *serveZone) int {
+		return cmp.Or(strings.Compare(a.Zone, b.Zone), strings.Compare(a.Provider, b.Provider))
+	})
+	return l
+}
+
+// ZoneGathered implements gatherObserver.
+func (r *serveRecorder) ZoneGathered(zone, provider string, d time.Duration, err error) {
+	r.metrics.apiLatency.WithLabelValues(provider, "gather").Observe(d.Seconds())
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	z := r.zone(zone, provider)
+	if err != nil {
+		r.metrics.provErrors.WithLabelValues(provider).Inc()
+		z.Error = err.Error()
+	}
+}
+
+// StartDomain is called at the start of each domain.
+func (r *serveRecorder) StartDomain(dc *models.DomainConfig) {
+	r.mu.Lock()
+	r.domain = dc.GetUniqueName()
+	r.mu.Unlock()
+	r.CLI.StartDomain(dc)
+}
+
+// StartDNSProvider is called at the start of each DNS provider.
+func (r *serveRecorder) StartDNSProvider(name string, skip bool) {
+	r.mu.Lock()
+	r.provider = name
+	if !skip {
+		r.zone(r.domain, name)
+	}
+	r.mu.Unlock()
+	r.CLI.StartDNSProvider(name, skip)
+}
+
+// StartRegistrar is called at the start of each registrar.
+func (r *serveRecorder) StartRegistrar(name string, skip bool) {
+	r.mu.Lock()
+	r.provider = name
+	r.mu.Unlock()
+	r.CLI.StartRegistrar(name, skip)
+}
+
+// PrintCorrection is called for each correction. It is pending until
+// EndCorrection reports that it was made.
+func (r *serveRecorder) PrintCorrection(n int, c *models.Correction) {
+	r.mu.Lock()
+	z := r.zone(r.domain, r.provider)
+	z.Pending = append(z.Pending, printer.StripColors(c.Msg))
+	r.corrStart = time.Now()
+	r.mu.Unlock()
+	r.CLI.PrintCorrection(n, c)
+}
+
+// EndCorrection is called after a correction was run (push).
+func (r *serveRecorder) EndCorrection(err error) {
+	r.mu.Lock()
+	z := r.zone(r.domain, r.provider)
+	r.metrics.apiLatency.WithLabelValues(r.provider, "correction").Observe(time.Since(r.corrStart).Seconds())
+	if err != nil {
+		r.metrics.corrections.WithLabelValues(z.Zone, z.Provider, "failed").Inc()
+		r.metrics.provErrors.WithLabelValues(r.provider).Inc()
+		z.Error = err.Error()
+	} else {
+		r.metrics.corrections.WithLabelValues(z.Zone, z.Provider, "applied").Inc()
+		z.Pending = z.Pending[:len(z.Pending)-1]
+		z.Applied++
+	}
+	r.mu.Unlock()
+	r.CLI.EndCorrection(err)
+}
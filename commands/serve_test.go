@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_serveRecorder(t *testing.T) {
+	s := newServer(prometheus.NewRegistry())
+	rec := newServeRecorder(&printer.ConsolePrinter{Writer: &bytes.Buffer{}}, s.metrics)
+
+	// Gathering example.org failed.
+	rec.ZoneGathered("example.com", "p1", time.Millisecond, nil)
+	rec.ZoneGathered("example.org", "p1", time.Millisecond, errors.New("boom"))
+
+	// example.com: one correction applied, one failed.
+	rec.StartDomain(&models.DomainConfig{Name: "example.com", UniqueName: "example.com"})
+	rec.StartDNSProvider("p1", false)
+	rec.PrintCorrection(0, &models.Correction{Msg: "+ CREATE a.example.com"})
+	rec.EndCorrection(nil)
+	rec.PrintCorrection(1, &models.Correction{Msg: "+ CREATE b.example.com"})
+	rec.EndCorrection(errors.New("denied"))
+	rec.StartDNSProvider("p2", true)
+
+	// example.net: in sync.
+	rec.StartDomain(&models.DomainConfig{Name: "example.net", UniqueName: "example.net"})
+	rec.StartDNSProvider("p1", false)
+
+	run := &serveRun{Zones: rec.zoneList()}
+	s.metrics.observeRun(run)
+
+	var got []string
+	for _, z := range run.Zones {
+		got = append(got, z.Zone+" "+z.Provider)
+	}
+	if want := "example.com p1,example.net p1,example.org p1"; strings.Join(got, ",") != want {
+		t.Fatalf("zones = %v, want %s", got, want)
+	}
+	exCom, exNet, exOrg := run.Zones[0], run.Zones[1], run.Zones[2]
+	if exCom.Applied != 1 || len(exCom.Pending) != 1 || exCom.Pending[0] != "+ CREATE b.example.com" || exCom.Error != "denied" {
+		t.Errorf("example.com = %+v", exCom)
+	}
+	if !exNet.InSync() {
+		t.Errorf("example.net should be in sync: %+v", exNet)
+	}
+	if exOrg.Error != "boom" {
+		t.Errorf("example.org = %+v", exOrg)
+	}
+
+	m := s.metrics
+	for _, c := range []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"zones_in_sync", testutil.ToFloat64(m.zonesInSync), 1},
+		{"zones", testutil.ToFloat64(m.zones), 3},
+		{"zone_in_sync example.com", testutil.ToFloat64(m.zoneInSync.WithLabelValues("example.com", "p1")), 0},
+		{"applied", testutil.ToFloat64(m.corrections.WithLabelValues("example.com", "p1", "applied")), 1},
+		{"failed", testutil.ToFloat64(m.corrections.WithLabelValues("example.com", "p1", "failed")), 1},
+		{"provider errors", testutil.ToFloat64(m.provErrors.WithLabelValues("p1")), 2},
+		{"runs", testutil.ToFloat64(m.runs.WithLabelValues("success")), 1},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func Test_serverHandler(t *testing.T) {
+	s := newServer(prometheus.NewRegistry())
+	h := s.handler()
+
+	get := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	if _, body := get("/"); !strings.Contains(body, "has not completed") {
+		t.Errorf("status page before the first run: %s", body)
+	}
+
+	run := &serveRun{Zones: []*serveZone{{Zone: "example.com", Provider: "p1", Pending: []string{"+ CREATE <a>.example.com"}}}}
+	s.metrics.observeRun(run)
+	s.last = run
+
+	if _, body := get("/"); !strings.Contains(body, "1 pending") || !strings.Contains(body, "&lt;a&gt;.example.com") {
+		t.Errorf("status page: %s", body)
+	}
+	if _, body := get("/status.json"); !strings.Contains(body, `"zone": "example.com"`) {
+		t.Errorf("status.json: %s", body)
+	}
+	if _, body := get("/metrics"); !strings.Contains(body, `dnscontrol_zone_pending_corrections{provider="p1",zone="example.com"} 1`) {
+		t.Errorf("metrics: %s", body)
+	}
+	if code, _ := get("/nope"); code != http.StatusNotFound {
+		t.Errorf("GET /nope = %d, want 404", code)
+	}
+}
+
+func Test_ServeArgs_flags(t *testing.T) {
+	var args ServeArgs
+	names := map[string]bool{}
+	for _, f := range args.flags() {
+		name := f.Names()[0]
+		if names[name] {
+			t.Errorf("--%s is defined twice", name)
+		}
+		names[name] = true
+	}
+	for _, name := range []string{"max-changes", "max-deletes", "force", "policy", "staged-state", "state-cache", "listen", "push"} {
+		if !names[name] {
+			t.Errorf("no --%s", name)
+		}
+	}
+	for _, name := range serveSkippedFlags {
+		if names[name] {
+			t.Errorf("--%s doesn't apply to serve", name)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/providers"
+	"github.com/DNSControl/dnscontrol/v4/pkg/statecache"
+)
+
+// driver returns the driver of provider, which uses the state cache if
+// there is one and the provider can tell whether a zone changed.
+func (zr *zoneResults) driver(provider *models.DNSProviderInstance) models.DNSProvider {
+	versioner, ok := provider.Driver.(providers.ZoneVersioner)
+	if zr.cache == nil || !ok {
+		return provider.Driver
+	}
+	return &cachingDriver{DNSProvider: provider.Driver, versioner: versioner, cache: zr.cache, name: provider.Name}
+}
+
+// cachingDriver is a provider whose GetZoneRecords returns the records in
+// the state cache if the zone did not change since they were saved.
+type cachingDriver struct {
+	models.DNSProvider
+	versioner providers.ZoneVersioner
+	cache     *statecache.Cache
+	name      string
+}
+
+func (d *cachingDriver) GetZoneRecords(dc *models.DomainConfig) (models.Records, error) {
+	token, err := d.versioner.ZoneVersion(dc)
+	if err != nil || token == "" {
+		return d.DNSProvider.GetZoneRecords(dc)
+	}
+	if recs, ok := d.cache.Get(d.name, dc.UniqueName, dc.Name, token); ok {
+		return recs, nil
+	}
+	recs, err := d.DNSProvider.GetZoneRecords(dc)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.cache.Put(d.name, dc.UniqueName, token, recs); err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/statecache"
+)
+
+// versionedProvider is a memProvider that tells the version of the zone and
+// counts the calls to GetZoneRecords.
+type versionedProvider struct {
+	memProvider
+	version string
+	calls   int
+}
+
+func (p *versionedProvider) ZoneVersion(*models.DomainConfig) (string, error) {
+	return p.version, nil
+}
+
+func (p *versionedProvider) GetZoneRecords(dc *models.DomainConfig) (models.Records, error) {
+	p.calls++
+	return p.memProvider.GetZoneRecords(dc)
+}
+
+func Test_cachingDriver(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cache.json")
+	p := &versionedProvider{memProvider: memProvider{recs: models.Records{makeTestRec("www", "A", "1.1.1.1")}}, version: "1"}
+	provider := &models.DNSProviderInstance{Driver: p}
+	provider.Name = "mem"
+	dc := &models.DomainConfig{Name: "example.com", UniqueName: "example.com"}
+
+	get := func() string {
+		t.Helper()
+		cache, err := statecache.Load(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zres := newZoneResults()
+		zres.cache = cache
+		recs, err := zres.driver(provider).GetZoneRecords(dc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cache.Save(filename); err != nil {
+			t.Fatal(err)
+		}
+		return recs[0].NameFQDN + " " + recs[0].GetTargetField()
+	}
+
+	for i, tt := range []struct {
+		version   string
+		wantCalls int
+	}{
+		{"1", 1}, // Not cached yet.
+		{"1", 1}, // Cached.
+		{"2", 2}, // Changed.
+		{"", 3},  // Unknown version.
+		{"2", 3}, // Cached.
+	} {
+		p.version = tt.version
+		if got := get(); got != "www.example.com 1.1.1.1" {
+			t.Errorf("#%d: GetZoneRecords() = %q", i, got)
+		}
+		if p.calls != tt.wantCalls {
+			t.Errorf("#%d: %d calls to the provider, want %d", i, p.calls, tt.wantCalls)
+		}
+	}
+
+	// Without a cache, the provider is used directly:
+	if d := newZoneResults().driver(provider); d != p {
+		t.Errorf("driver() = %T, want the provider", d)
+	}
+}
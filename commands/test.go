@@ -0,0 +1,266 @@
+package commands
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/DNSControl/dnscontrol/v4/pkg/js"
+	"github.com/DNSControl/dnscontrol/v4/pkg/normalize"
+	"github.com/urfave/cli/v3"
+)
+
+var _ = cmd(catDebug, func() *cli.Command {
+	var args TestArgs
+	return &cli.Command{
+		Name:      "test",
+		Usage:     "Run the tests (*_test.js files) of dnsconfig.js",
+		ArgsUsage: "[files or directories...]",
+		Action: func(ctx context.Context, c *cli.Command) error {
+			args.Files = c.Args().Slice()
+			return exit(Test(args))
+		},
+		Flags: args.flags(),
+		Description: `Run the *_test.js (and *_test.ts) files against the configuration of
+dnsconfig.js, once validated and normalized (as print-ir outputs it). The
+tests use expectRecord(), expectNoRecord(), expectTTL(), ... to check it.
+
+The files or directories given are run. By default, the *_test.js files in
+the directory of dnsconfig.js (and its subdirectories) are run.
+
+Documentation: https://docs.dnscontrol.org/commands/test`,
+	}
+}())
+
+// TestArgs contains all data/flags needed to run test, independently of CLI.
+type TestArgs struct {
+	GetDNSConfigArgs
+	Format string   // Output format: tap, junit
+	Output string   // File to write the report to (default stdout)
+	Files  []string // The test files, or the directories to find them in
+}
+
+func (args *TestArgs) flags() []cli.Flag {
+	flags := args.GetDNSConfigArgs.flags()
+	flags = append(flags, &cli.StringFlag{
+		Name:        "format",
+		Destination: &args.Format,
+		Value:       "tap",
+		Usage:       `Output format: tap, junit`,
+		Action: func(ctx context.Context, c *cli.Command, s string) error {
+			if !slices.Contains([]string{"tap", "junit"}, s) {
+				fmt.Printf("%q is not a valid option for --format.  Values are: tap, junit\n", s)
+				os.Exit(1)
+			}
+			return nil
+		},
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "out",
+		Destination: &args.Output,
+		Usage:       "File to write the report to (default stdout)",
+	})
+	return flags
+}
+
+// TestFileResults are the results of the tests of a test file.
+type TestFileResults struct {
+	File    string
+	Results []*js.TestResult
+}
+
+// Test implements the test subcommand.
+func Test(args TestArgs) error {
+	cfg, err := GetDNSConfig(args.GetDNSConfigArgs)
+	if err != nil {
+		return err
+	}
+	if PrintValidationErrors(normalize.ValidateAndNormalizeConfig(cfg)) {
+		return errors.New("exiting due to validation errors")
+	}
+
+	files, err := findTestFiles(args.Files, filepath.Dir(configFile(args.JSFile)))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("no test files (*_test.js) found")
+	}
+	var results []TestFileResults
+	for _, file := range files {
+		r, err := js.RunTests(file, cfg, stringSliceToMap(args.Variable))
+		if err != nil {
+			return err
+		}
+		results = append(results, TestFileResults{File: file, Results: r})
+	}
+
+	w := io.Writer(os.Stdout)
+	if args.Output != "" {
+		f, err := os.Create(args.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if args.Format == "junit" {
+		err = writeJUnit(w, results)
+	} else {
+		err = writeTAP(w, results)
+	}
+	if err != nil {
+		return err
+	}
+
+	var total, failed int
+	for _, f := range results {
+		for _, r := range f.Results {
+			total++
+			if r.Failed() {
+				failed++
+			}
+		}
+	}
+	if failed != 0 {
+		return fmt.Errorf("%d of %d tests failed", failed, total)
+	}
+	return nil
+}
+
+// isTestFile returns true if the file is a test file of dnsconfig.js.
+func isTestFile(file string) bool {
+	return strings.HasSuffix(file, "_test.js") || strings.HasSuffix(file, "_test.ts")
+}
+
+// findTestFiles returns the test files of args: the files given, and the
+// test files in the directories given (dir if none is given). Dot
+// directories and node_modules are skipped.
+func findTestFiles(args []string, dir string) ([]string, error) {
+	if len(args) == 0 {
+		args = []string{dir}
+	}
+	var files []string
+	for _, arg := range args {
+		st, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != arg && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if isTestFile(d.Name()) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// writeTAP writes the results in the Test Anything Protocol (version 13).
+func writeTAP(w io.Writer, results []TestFileResults) error {
+	var total int
+	for _, f := range results {
+		total += len(f.Results)
+	}
+	fmt.Fprintf(w, "TAP version 13\n1..%d\n", total)
+	n := 0
+	for _, f := range results {
+		fmt.Fprintf(w, "# %s\n", f.File)
+		for _, r := range f.Results {
+			n++
+			status := "ok"
+			if r.Failed() {
+				status = "not ok"
+			}
+			// "#" starts a directive in TAP.
+			fmt.Fprintf(w, "%s %d - %s\n", status, n, strings.ReplaceAll(r.Name, "#", `\#`))
+			if !r.Failed() {
+				continue
+			}
+			fmt.Fprintf(w, "  ---\n  at: %q\n  failures:\n", r.Position)
+			for _, failure := range r.Failures {
+				fmt.Fprintf(w, "    - %q\n", failure)
+			}
+			fmt.Fprintf(w, "  ...\n")
+		}
+	}
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the results as JUnit XML, with a test suite per file.
+func writeJUnit(w io.Writer, results []TestFileResults) error {
+	var suites junitTestSuites
+	for _, f := range results {
+		suite := junitTestSuite{Name: f.File, Cases: []junitTestCase{}}
+		for _, r := range f.Results {
+			c := junitTestCase{Name: r.Name, Classname: f.File}
+			if r.Failed() {
+				c.Failure = &junitFailure{Message: r.Failures[0], Text: "at " + r.Position + "\n" + strings.Join(r.Failures, "\n")}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+		suite.Tests = len(suite.Cases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
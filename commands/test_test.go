@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/pkg/js"
+)
+
+var testResults = []TestFileResults{{
+	File: "tests/records_test.js",
+	Results: []*js.TestResult{
+		{Name: "example.com: www A 1.2.3.4", Position: "tests/records_test.js:1:1"},
+		{Name: "TTLs #1", Position: "tests/records_test.js:2:1", Failures: []string{
+			"example.com: @ A TTL 300: A 1.2.3.4 has TTL 600 (at tests/records_test.js:3:5)",
+			`a "b" c (at tests/records_test.js:4:5)`,
+		}},
+	},
+}}
+
+func Test_writeTAP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeTAP(&buf, testResults); err != nil {
+		t.Fatal(err)
+	}
+	want := `TAP version 13
+1..2
+# tests/records_test.js
+ok 1 - example.com: www A 1.2.3.4
+not ok 2 - TTLs \#1
+  ---
+  at: "tests/records_test.js:2:1"
+  failures:
+    - "example.com: @ A TTL 300: A 1.2.3.4 has TTL 600 (at tests/records_test.js:3:5)"
+    - "a \"b\" c (at tests/records_test.js:4:5)"
+  ...
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func Test_writeJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJUnit(&buf, testResults); err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="2" failures="1">
+  <testsuite name="tests/records_test.js" tests="2" failures="1">
+    <testcase name="example.com: www A 1.2.3.4" classname="tests/records_test.js"></testcase>
+    <testcase name="TTLs #1" classname="tests/records_test.js">
+      <failure message="example.com: @ A TTL 300: A 1.2.3.4 has TTL 600 (at tests/records_test.js:3:5)">at tests/records_test.js:2:1&#xA;example.com: @ A TTL 300: A 1.2.3.4 has TTL 600 (at tests/records_test.js:3:5)&#xA;a &#34;b&#34; c (at tests/records_test.js:4:5)</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func Test_findTestFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"a_test.js", "b.js", "sub/c_test.ts", "sub/d_test.js.bak",
+		".git/e_test.js", "node_modules/x/f_test.js", "other/g_test.js",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"a_test.js", "other/g_test.js", "sub/c_test.ts"}},
+		{[]string{"sub", "b.js"}, []string{"sub/c_test.ts", "b.js"}},
+	} {
+		var args []string
+		for _, a := range tt.args {
+			args = append(args, filepath.Join(dir, a))
+		}
+		files, err := findTestFiles(args, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, f := range files {
+			rel, _ := filepath.Rel(dir, f)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("findTestFiles(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+
+	if _, err := findTestFiles([]string{filepath.Join(dir, "missing_test.js")}, dir); err == nil {
+		t.Error("findTestFiles(missing file): no error")
+	}
+}
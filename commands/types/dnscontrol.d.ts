@@ -329,6 +329,35 @@ declare function AKAMAITLC(name: string, answer_type: "DUAL" | "A" | "AAAA", tar
  */
 declare function ALIAS(name: string, target: string, ...modifiers: RecordModifier[]): DomainModifier;
 
+/**
+ * `dnscontrol check`, `preview` and `push` warn about the targets of CNAME, ALIAS, MX and NS records that are likely to be broken or to be a subdomain takeover risk:
+ *
+ * * **dangling target**: The target is in one of the zones of `dnsconfig.js`, but nothing in that zone has that name. Wildcards, delegations (NS records), [`IGNORE()`](IGNORE.md), [`NO_PURGE`](NO_PURGE.md), [`IGNORE_EXTERNAL_DNS`](IGNORE_EXTERNAL_DNS.md) and [`OWNERSHIP()`](OWNERSHIP.md) are taken into account: in a zone with `NO_PURGE`, `IGNORE_EXTERNAL_DNS` or `OWNERSHIP()`, any name may exist.
+ * * **takeover risk**: The target (of a CNAME or ALIAS record) is at a cloud service, such as `*.cloudapp.net`, `*.s3.amazonaws.com` or `*.github.io`, where anyone can claim the name once the resource it points to has been deleted.
+ *
+ * `ALLOW_DANGLING` turns off these warnings for the records of the domain whose target matches one of the patterns. In the patterns, `*` matches any characters within a label and `**` matches any number of labels.
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider(DSP_MY_PROVIDER),
+ *   ALLOW_DANGLING("myapp.cloudapp.net", "*.github.io"),
+ *   CNAME("app", "myapp.cloudapp.net."),
+ *   CNAME("docs", "example.github.io."),
+ *   CNAME("shop", "example.myshopify.com."),
+ * );
+ * ```
+ *
+ * ```shell
+ * $ dnscontrol check
+ * 1 Validation errors:
+ * WARNING: takeover risk: CNAME shop.example.com -> example.myshopify.com. [dnsconfig.js:5:3]: the name can be claimed by someone else if the resource at the cloud service is deleted; use ALLOW_DANGLING("example.myshopify.com") if this is intentional
+ * ```
+ *
+ * These are warnings: they do not stop `preview` or `push`.
+ *
+ * @see https://docs.dnscontrol.org/language-reference/domain-modifiers/allow_dangling
+ */
+declare function ALLOW_DANGLING(...patterns: string[]): DomainModifier;
+
 /**
  * `AUTODNSSEC_OFF` tells the provider to disable AutoDNSSEC. It takes no
  * parameters.
@@ -1898,6 +1927,39 @@ declare function INCLUDE(domain: string): DomainModifier;
  */
 declare function IP(ip: string): number;
 
+/**
+ * `LOAD_YAML(pattern)` loads the [declarative configuration](../../advanced-features/declarative-config.md) files (YAML, TOML or JSON, according to their extension) that match `pattern`, and returns their names. The registrars, DNS providers, domains and records they list are added to the configuration as if they were written with `NewRegistrar()`, `NewDnsProvider()`, `D()`, `A()`, ...
+ *
+ * `pattern` is a file name, or a glob (wildcard, see [Go's filepath.Match](https://golang.org/pkg/path/filepath/#Match)). It is relative to the directory of the file that calls `LOAD_YAML()`, like `require()`. The files are loaded in alphabetical order. It is an error if no file matches.
+ *
+ * ```javascript
+ * var REG_NONE = NewRegistrar("none");
+ * var DSP_BIND = NewDnsProvider("bind");
+ *
+ * D("example.com", REG_NONE, DnsProvider(DSP_BIND),
+ *     A("@", "192.0.2.1"),
+ * );
+ *
+ * // Domains maintained by people who don't write JavaScript:
+ * LOAD_YAML("zones/*.yaml");
+ * ```
+ *
+ * ```yaml
+ * domains:
+ *   - name: example.org
+ *     registrar: none
+ *     providers: [bind]
+ *     records:
+ *       - { type: A, name: "@", target: 192.0.2.2 }
+ *       - { type: MX, name: "@", args: [10, mail.example.com.] }
+ * ```
+ *
+ * A declarative file can also add records to a domain of `dnsconfig.js`, with `extend: true` (like [`D_EXTEND()`](D_EXTEND.md)).
+ *
+ * @see https://docs.dnscontrol.org/language-reference/top-level-functions/load_yaml
+ */
+declare function LOAD_YAML(pattern: string): string[];
+
 /**
  * `LOC` add a [Location record](https://www.rfc-editor.org/rfc/rfc1876) to the domain.
  *
@@ -2327,6 +2389,42 @@ declare function LUA(name: string, rtype: string, contents: string | string[], .
  */
 declare function M365_BUILDER(opts: { label?: string; mx?: boolean; autodiscover?: boolean; dkim?: boolean; skypeForBusiness?: boolean; mdm?: boolean; domainGUID?: string; initialDomain?: string }): DomainModifier;
 
+/**
+ * `MAX_CHANGES` makes `dnscontrol push` refuse to update the domain if more than `n` records would be created, modified or deleted at a provider. Use `push --force` to make the changes anyway.
+ *
+ * It overrides `--max-changes` for this domain. Deleting an NS or SOA record at the apex counts more than other changes. See [Guardrails](../../commands/preview-push.md#guardrails) for details.
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider(DSP_MY_PROVIDER),
+ *   MAX_CHANGES(20),
+ *   A("www", "1.2.3.4"),
+ * );
+ * ```
+ *
+ * See also [`MAX_DELETES`](MAX_DELETES.md).
+ *
+ * @see https://docs.dnscontrol.org/language-reference/domain-modifiers/max_changes
+ */
+declare function MAX_CHANGES(n: number): DomainModifier;
+
+/**
+ * `MAX_DELETES` makes `dnscontrol push` refuse to update the domain if more than `n` records would be deleted at a provider. Use `push --force` to make the changes anyway.
+ *
+ * It overrides `--max-deletes` for this domain. Deleting an NS or SOA record at the apex counts as 10 deletes (`--weight-apex`). See [Guardrails](../../commands/preview-push.md#guardrails) for details.
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider(DSP_MY_PROVIDER),
+ *   MAX_DELETES(5),
+ *   A("www", "1.2.3.4"),
+ * );
+ * ```
+ *
+ * See also [`MAX_CHANGES`](MAX_CHANGES.md).
+ *
+ * @see https://docs.dnscontrol.org/language-reference/domain-modifiers/max_deletes
+ */
+declare function MAX_DELETES(n: number): DomainModifier;
+
 /**
  * `MIKROTIK_FORWARDER` manages a RouterOS DNS forwarder entry (`/ip/dns/forwarders`). The `name` parameter can be a domain name (e.g. `corp.example.com`) or an arbitrary alias (e.g. `my-upstream`). These named entries can then be referenced as the target of [`MIKROTIK_FWD`](MIKROTIK_FWD.md) records.
  *
@@ -2936,6 +3034,56 @@ declare function NewRegistrar(name: string, type?: string, meta?: object): strin
  */
 declare function OPENPGPKEY(name: string, target: string, ...modifiers: RecordModifier[]): DomainModifier;
 
+/**
+ * `OWNERSHIP` lets several `dnsconfig.js` files (for example, one per team, each in its own repository) manage the same zone. Each `D()` block of the zone gets a different owner ID. DNSControl then only modifies or deletes the records that carry its own owner ID, and leaves all the others alone.
+ *
+ * This is more robust than [`IGNORE()`](IGNORE.md) patterns, which have to be kept in sync with what the other teams manage.
+ *
+ * ```javascript
+ * D("example.com", REG_NONE, DnsProvider(DSP_MY_PROVIDER),
+ *   OWNERSHIP("team-a"),
+ *   A("www", "1.2.3.4"),
+ *   CNAME("api", "www.example.com."),
+ * );
+ * ```
+ *
+ * ```javascript
+ * D("example.com", REG_NONE, DnsProvider(DSP_MY_PROVIDER),
+ *   OWNERSHIP("team-b"),
+ *   A("shop", "1.2.3.5"),
+ *   MX("@", 10, "mail.example.com."),
+ * );
+ * ```
+ *
+ * ## How it works
+ *
+ * Ownership is tracked per label and record type (a record set). For each record set in the `D()`, DNSControl creates a TXT record, the marker, with the owner ID:
+ *
+ * ```text
+ * _dnscontrol-a-www.example.com.    TXT "heritage=dnscontrol,dnscontrol/owner=team-a"
+ * _dnscontrol-cname-api.example.com. TXT "heritage=dnscontrol,dnscontrol/owner=team-a"
+ * ```
+ *
+ * The marker's label is `_dnscontrol-`, the record type and the leftmost label of the record (`_wildcard` for `*`). The marker of a record at the apex is `_dnscontrol-TYPE` (for example, `_dnscontrol-mx`). As a DNS label is limited to 63 characters, the leftmost label of a record can be at most 63 characters minus the length of `_dnscontrol-TYPE-` (49 characters for an `A` record); DNSControl reports an error for longer labels.
+ *
+ * When the zone is pushed:
+ *
+ * * Records whose record set has this `D()`'s marker are modified or deleted as usual. The marker is deleted with the last record of the set.
+ * * Records whose record set has another owner's marker, or no marker at all, are not touched (`preview` reports them as "not being changed because they are not owned by ...").
+ * * If the `D()` has records in a record set that someone else owns, `preview` and `push` fail with an error. The same is true for a record set that already exists but has no owner. To take over such record sets (for example, the first time `OWNERSHIP` is used in a zone that DNSControl already manages), use `OWNERSHIP("team-a", "adopt")`.
+ *
+ * The SOA record and the `NS` records at the apex belong to the zone rather than to an owner: every `D()` of the zone has them, and they are not marked.
+ *
+ * The owner ID may contain letters, digits, `.`, `_` and `-`.
+ *
+ * The markers are ordinary TXT records, so anyone who can edit the zone can change them. They prevent accidents, not malice.
+ *
+ * See also [`IGNORE_EXTERNAL_DNS`](IGNORE_EXTERNAL_DNS.md), which uses similar markers to coexist with Kubernetes external-dns.
+ *
+ * @see https://docs.dnscontrol.org/language-reference/domain-modifiers/ownership
+ */
+declare function OWNERSHIP(ownerID: string, mode?: "adopt"): DomainModifier;
+
 /**
  * `PANIC` terminates the script and therefore DNSControl with an exit code of 1. This should be used if your script cannot gather enough information to generate records, for example when a HTTP request failed.
  *
@@ -3639,6 +3787,35 @@ declare function SRV(name: string, priority: number, weight: number, port: numbe
  */
 declare function SSHFP(name: string, algorithm: 0 | 1 | 2 | 3 | 4, type: 0 | 1 | 2, value: string, ...modifiers: RecordModifier[]): DomainModifier;
 
+/**
+ * `STAGED_CHANGE` changes the value of a record the safe way: lower the TTL, wait for the old TTL to expire, change the value, then raise the TTL again. Resolvers then stop serving the old value soon after the change, instead of up to the old TTL later.
+ *
+ * Each `push` does one step. When the value of the record in `dnsconfig.js` differs from the one at the provider:
+ *
+ *   1. The TTL of the current value is lowered to `low_ttl` (default: `60`).
+ *   2. Until the old TTL has passed, nothing is done.
+ *   3. The value is changed, still with the TTL `low_ttl`.
+ *   4. The TTL is set to the one in `dnsconfig.js`.
+ *
+ * Run `push` regularly (for example, from cron or a CI pipeline) to go through the steps. `preview` shows which step the next `push` would do.
+ *
+ * When step 1 was done is kept in a state file, `dnscontrol-staged.json` in the current directory (see `--staged-state` in [preview/push](../../commands/preview-push.md)). Keep it between runs: without it, the next `push` changes the value right away. The file is removed when no record is being changed.
+ *
+ * `STAGED_CHANGE` has no effect when the record is created, or when only its TTL changes. If the TTL at the provider is already at most `low_ttl`, the value is changed right away.
+ *
+ * `low_ttl` is a number of seconds or a duration, as with [`TTL`](TTL.md).
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider(DSP_MY_PROVIDER),
+ *   A("www", "5.6.7.8", TTL("1h"), STAGED_CHANGE()),
+ *   CNAME("api", "api-v2.example.net.", TTL("1d"), STAGED_CHANGE("5m")),
+ * );
+ * ```
+ *
+ * @see https://docs.dnscontrol.org/language-reference/record-modifiers/staged_change
+ */
+declare function STAGED_CHANGE(low_ttl?: Duration): RecordModifier;
+
 /**
  * `SVCB` adds a [Service Binding record](https://www.rfc-editor.org/rfc/rfc9460) to a domain. The name should be the relative label for the record. Use `@` for the domain apex.
  *
@@ -3820,6 +3997,81 @@ declare function URL(name: string, target: string, ...modifiers: RecordModifier[
  */
 declare function URL301(name: string, target: string, ...modifiers: RecordModifier[]): DomainModifier;
 
+/**
+ * `VALID_FROM` includes the record only from `time` on. Before that, DNSControl acts as if the record was not in `dnsconfig.js`: `push` does not create it (or deletes it).
+ *
+ * The time is evaluated each time `preview` or `push` runs, so the record is created by the first `push` after `time`. Run `push` regularly (for example, from cron or a CI pipeline) to schedule changes. Use `preview --at` to see what a run at another time would do (see [Scheduled changes](../../commands/preview-push.md#scheduled-changes)).
+ *
+ * `time` is a JavaScript `Date` or a string in one of these formats:
+ *
+ *   * `2025-06-20T02:00:00Z` or `2025-06-20T04:00:00+02:00` (RFC 3339)
+ *   * `2025-06-20T02:00` or `2025-06-20 02:00` (UTC)
+ *   * `2025-06-20` (midnight UTC)
+ *
+ * Use it with [`VALID_UNTIL`](VALID_UNTIL.md) to replace a record at a given time, or to include a record only during a period.
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider(DSP_MY_PROVIDER),
+ *   // Move www to the new server at 02:00 UTC on June 20th:
+ *   A("www", "1.2.3.4", VALID_UNTIL("2025-06-20T02:00:00Z")),
+ *   A("www", "5.6.7.8", VALID_FROM("2025-06-20T02:00:00Z")),
+ *   // Only during the sale:
+ *   CNAME("sale", "shop.example.net.", VALID_FROM("2025-11-28"), VALID_UNTIL("2025-12-02")),
+ * );
+ * ```
+ *
+ * @see https://docs.dnscontrol.org/language-reference/record-modifiers/valid_from
+ */
+declare function VALID_FROM(time: string | Date): RecordModifier;
+
+/**
+ * `VALID_UNTIL` includes the record only until `time`. From then on, DNSControl acts as if the record was not in `dnsconfig.js`: `push` deletes it. This is useful for temporary records, such as those used to verify the ownership of a domain.
+ *
+ * The time is evaluated each time `preview` or `push` runs, so the record is deleted by the first `push` after `time`. Run `push` regularly (for example, from cron or a CI pipeline) to schedule changes. Use `preview --at` to see what a run at another time would do (see [Scheduled changes](../../commands/preview-push.md#scheduled-changes)).
+ *
+ * `time` is in the same formats as with [`VALID_FROM`](VALID_FROM.md). It must be after the `VALID_FROM` time of the record, if any.
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider(DSP_MY_PROVIDER),
+ *   TXT("_acme-challenge", "dGhpcyBpcyBhIHRlc3Q", VALID_UNTIL("2025-07-01")),
+ *   A("beta", "1.2.3.4", VALID_UNTIL(new Date(Date.UTC(2025, 8, 30)))),
+ * );
+ * ```
+ *
+ * @see https://docs.dnscontrol.org/language-reference/record-modifiers/valid_until
+ */
+declare function VALID_UNTIL(time: string | Date): RecordModifier;
+
+/**
+ * `ZONEFILE` adds the records of a zone file (in the [RFC 1035](https://datatracker.ietf.org/doc/html/rfc1035#section-5) format used by BIND) to the domain. Use it for zones that other teams still maintain as zone files.
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider(DSP_MY_PROVIDER),
+ *   ZONEFILE("zones/example.com.zone"),
+ *   A("new", "192.0.2.9"), // Records can be added as usual.
+ * );
+ * ```
+ *
+ * ```text
+ * $TTL 3600
+ * @       IN SOA ns1.example.com. hostmaster.example.com. 2024010101 7200 3600 1209600 300
+ * @       IN NS  ns1.example.com.
+ * @       IN A   192.0.2.1
+ * www 300 IN CNAME @
+ * @       IN MX  10 mail
+ * $INCLUDE mail.zone
+ * ```
+ *
+ * * The file is parsed like the [BIND provider](../../provider/bind.md) parses zone files, so the same record types are supported.
+ * * `path` is relative to the directory of the file that calls `ZONEFILE()`. The paths of `$INCLUDE` are relative to the directory of the zone file.
+ * * The origin is the name of the domain (or of the subdomain, in a [`D_EXTEND()`](../top-level-functions/D_EXTEND.md)), until a `$ORIGIN`. `$TTL` sets the TTL of the records that have none. Without `$TTL`, a record without a TTL gets the TTL of the previous record (as in BIND) or, if there is none, the [`DefaultTTL()`](DefaultTTL.md) of the domain (if it is before `ZONEFILE()`, as for the other records).
+ * * SOA records, and NS records at the apex, are skipped: the DNS providers manage them. Use [`NAMESERVER()`](NAMESERVER.md) to set the nameservers.
+ * * The position of the records (in the output of `preview` and in errors) is their file and line in the zone file (`zones/example.com.zone:4`). A syntax error in the zone file is reported with the name of the file and the line.
+ *
+ * @see https://docs.dnscontrol.org/language-reference/domain-modifiers/zonefile
+ */
+declare function ZONEFILE(path: string): DomainModifier;
+
 /**
  * `getConfiguredDomains` getConfiguredDomains is a helper function that returns the domain names
  * configured at the time the function is called. Calling this function early or later in
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+	"github.com/DNSControl/dnscontrol/v4/pkg/nameservers"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+	"github.com/DNSControl/dnscontrol/v4/pkg/verify"
+	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
+)
+
+// verifyInterval is how often the nameservers are queried while waiting
+// for them to serve the changes (--canary and --verify).
+var verifyInterval = 5 * time.Second
+
+// providerNameservers returns the nameservers of provider for zone. Only
+// the provider's own, not those of the zone's other providers or
+// NAMESERVER().
+func providerNameservers(zone *models.DomainConfig, provider *models.DNSProviderInstance) ([]string, error) {
+	nss, err := nameservers.DetermineNameserversForProviders(&models.DomainConfig{Name: zone.Name}, []*models.DNSProviderInstance{provider}, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(nss) == 0 {
+		return nil, fmt.Errorf("%q has no nameservers for %q to verify the changes with", provider.Name, zone.Name)
+	}
+	return verify.Nameservers(nss), nil
+}
+
+// pushed is the changes pushed to a zone at a provider, to be verified
+// with --verify.
+type pushed struct {
+	zone     *models.DomainConfig
+	provider *models.DNSProviderInstance
+	result   *zonerecs.Result
+}
+
+// verification is the result of verifying a pushed.
+type verification struct {
+	pushed
+	nameservers []string
+	statuses    []*verify.Status
+	err         error
+}
+
+// verifyPushes waits (up to timeout) until the nameservers of each provider
+// serve the changes pushed to it, and prints which records each serves.
+// The providers are queried concurrently. It returns true if any records
+// are not served.
+func verifyPushes(pushes []pushed, timeout time.Duration, out printer.CLI) bool {
+	results := make([]*verification, len(pushes))
+	var wg sync.WaitGroup
+	for i, p := range pushes {
+		results[i] = &verification{pushed: p}
+		wg.Add(1)
+		go func(v *verification) {
+			defer wg.Done()
+			v.nameservers, v.err = providerNameservers(v.zone, v.provider)
+			if v.err != nil || v.result == nil {
+				return
+			}
+			var changes diff2.ChangeList
+			if changes, v.err = v.result.Changes(); v.err == nil {
+				v.statuses = verify.Poll(v.nameservers, v.zone.Name, changes, timeout, verifyInterval)
+			}
+		}(results[i])
+	}
+	wg.Wait()
+
+	var anyErrors bool
+	for _, v := range results {
+		if v.err != nil {
+			out.Errorf("VERIFY: %q at %q: %s\n", v.zone.GetUniqueName(), v.provider.Name, v.err)
+			anyErrors = true
+			continue
+		}
+		if len(v.statuses) == 0 {
+			continue
+		}
+		converged := 0
+		for _, st := range v.statuses {
+			if st.Converged {
+				converged++
+			}
+		}
+		out.Printf("VERIFY: %q at %q: %d of %d changed records served by %s\n", v.zone.GetUniqueName(), v.provider.Name, converged, len(v.statuses), strings.Join(v.nameservers, ", "))
+		for _, st := range v.statuses {
+			if st.Converged {
+				out.Printf("  %s %s: served after %s\n", st.Name, st.Type, st.After)
+				continue
+			}
+			anyErrors = true
+			out.Printf("  %s %s: NOT SERVED after %s\n", st.Name, st.Type, timeout)
+			for _, m := range st.Mismatches {
+				out.Printf("    %s\n", m)
+			}
+		}
+	}
+	return anyErrors
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+	"github.com/DNSControl/dnscontrol/v4/pkg/dnssort"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+)
+
+// orderZones returns the zones in the order their corrections should be
+// run: a zone that creates the target of a record in another zone (for
+// example, the A record that a CNAME in another zone points to) goes first,
+// and a zone that deletes the target of a record in another zone goes after
+// it. Zones that don't depend on each other keep their order.
+//
+// The corrections of a zone are opaque (some providers replace the whole
+// zone at once), so the changes are ordered within each zone (by diff2) and
+// the zones are ordered here.
+func orderZones(zones []*models.DomainConfig, providerFilter string, zres *zoneResults, out printer.CLI) []*models.DomainConfig {
+	if diff2.DisableOrdering || len(zones) < 2 {
+		return zones
+	}
+
+	groups := make([][]diff2.Change, len(zones))
+	for i, zone := range zones {
+		for _, provider := range whichProvidersToProcess(zone.DNSProviderInstances, providerFilter) {
+			r := zres.get(zone, provider.Name)
+			if r == nil {
+				continue
+			}
+			changes, err := r.Changes()
+			if err != nil {
+				out.Warnf("The zones are pushed in the order of dnsconfig.js: %s\n", err)
+				return zones
+			}
+			groups[i] = append(groups[i], changes...)
+		}
+	}
+
+	result := dnssort.SortGroupsUsingGraph(groups)
+	if len(result.Unresolved) > 0 {
+		var names []string
+		for _, i := range result.Unresolved {
+			names = append(names, zones[i].GetUniqueName())
+		}
+		out.Warnf("The changes to these zones depend on each other: %s. They are pushed in the order of dnsconfig.js; "+
+			"a second push may be needed if a provider checks the targets of records. "+
+			"See https://docs.dnscontrol.org/advanced-features/ordering\n", strings.Join(names, ", "))
+	}
+
+	ordered := make([]*models.DomainConfig, len(zones))
+	for i, g := range result.Order {
+		ordered[i] = zones[g]
+	}
+	return ordered
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+	"github.com/DNSControl/dnscontrol/v4/pkg/zonerecs"
+)
+
+func Test_orderZones(t *testing.T) {
+	rec := func(label, rtype, content, domain string) *models.RecordConfig {
+		r := &models.RecordConfig{TTL: 300}
+		r.SetLabel(label, domain)
+		if err := r.PopulateFromString(rtype, content, domain); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// zone returns a zone whose records at the provider are existing and
+	// whose desired records are desired.
+	zres := newZoneResults()
+	zone := func(name string, existing, desired models.Records) *models.DomainConfig {
+		p := &memProvider{recs: existing}
+		provider := &models.DNSProviderInstance{Driver: p}
+		provider.Name = "mem"
+		provider.IsDefault = true
+		z := &models.DomainConfig{
+			Name:                 name,
+			UniqueName:           name,
+			Records:              desired,
+			DNSProviderInstances: []*models.DNSProviderInstance{provider},
+		}
+		r, err := zonerecs.CorrectZoneRecordsResult(p, z)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zres.store(z, provider.Name, r)
+		return z
+	}
+	names := func(zones []*models.DomainConfig) string {
+		var r []string
+		for _, z := range zones {
+			r = append(r, z.Name)
+		}
+		return strings.Join(r, " ")
+	}
+
+	// a.com points to a new name in b.com: b.com first.
+	a := zone("a.com", nil, models.Records{rec("www", "CNAME", "new.b.com.", "a.com")})
+	b := zone("b.com", nil, models.Records{rec("new", "A", "1.2.3.4", "b.com")})
+	// c.com stops pointing to a name that d.com deletes: c.com first.
+	d := zone("d.com", models.Records{rec("old", "A", "1.2.3.4", "d.com")}, nil)
+	c := zone("c.com", models.Records{rec("www", "CNAME", "old.d.com.", "c.com")}, models.Records{rec("www", "A", "1.2.3.5", "c.com")})
+	// e.com and f.com point to each other's new names.
+	e := zone("e.com", nil, models.Records{rec("x", "CNAME", "y.f.com.", "e.com"), rec("y", "A", "1.2.3.4", "e.com")})
+	f := zone("f.com", nil, models.Records{rec("x", "CNAME", "y.e.com.", "f.com"), rec("y", "A", "1.2.3.4", "f.com")})
+
+	var buf bytes.Buffer
+	out := &printer.ConsolePrinter{Writer: &buf}
+	if got, want := names(orderZones([]*models.DomainConfig{a, b, d, c}, "", zres, out)), "b.com a.com c.com d.com"; got != want {
+		t.Errorf("orderZones() = %q, want %q", got, want)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected warning: %s", buf.String())
+	}
+
+	if got, want := names(orderZones([]*models.DomainConfig{e, a, f, b}, "", zres, out)), "b.com a.com e.com f.com"; got != want {
+		t.Errorf("orderZones() = %q, want %q", got, want)
+	}
+	if !strings.Contains(buf.String(), "depend on each other: e.com, f.com.") {
+		t.Errorf("missing warning, got: %s", buf.String())
+	}
+}
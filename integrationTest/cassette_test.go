@@ -0,0 +1,75 @@
+package main
+
+// Record and replay the HTTP interactions with the providers' APIs, so
+// that the tests can run without a live account. See pkg/cassette.
+
+import (
+	"flag"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/pkg/cassette"
+)
+
+var (
+	recordFlag  = flag.Bool("record", false, "Record the HTTP interactions with the provider into cassettes (see -cassettes)")
+	replayFlag  = flag.Bool("replay", false, "Replay the HTTP interactions recorded in cassettes instead of using the provider's API")
+	cassetteDir = flag.String("cassettes", "cassettes", "Directory of the cassettes of -record and -replay")
+)
+
+// cassetteFile returns the cassette of the test t for the profile.
+func cassetteFile(t *testing.T, profile string) string {
+	return filepath.Join(*cassetteDir, profile, t.Name()+".json")
+}
+
+// loadCassetteProfile returns the profile recorded in the cassette, in the
+// format of profiles.json.
+func loadCassetteProfile(t *testing.T, profile string) map[string]map[string]string {
+	c, err := cassette.Load(cassetteFile(t, profile))
+	if err != nil {
+		t.Fatalf("-replay: %s", err)
+	}
+	return map[string]map[string]string{profile: c.Profile}
+}
+
+// useCassette makes the HTTP requests of the test t go through a recorder
+// (-record) or a replayer (-replay) until the end of t.
+//
+// The requests are intercepted by replacing http.DefaultTransport, which
+// http.DefaultClient and most API clients use. A provider that creates its
+// own http.Transport can't be recorded.
+func useCassette(t *testing.T, profile string, cfg map[string]string) {
+	filename := cassetteFile(t, profile)
+	saved := http.DefaultTransport
+	switch {
+	case *recordFlag && *replayFlag:
+		t.Fatal("-record and -replay are mutually exclusive")
+	case *recordFlag:
+		rec := cassette.NewRecorder(saved, cfg)
+		http.DefaultTransport = rec
+		t.Cleanup(func() {
+			http.DefaultTransport = saved
+			if t.Failed() {
+				t.Logf("-record: %s not saved because the test failed", filename)
+				return
+			}
+			if err := rec.Cassette().Save(filename); err != nil {
+				t.Errorf("-record: %s", err)
+			}
+		})
+	case *replayFlag:
+		c, err := cassette.Load(filename)
+		if err != nil {
+			t.Fatalf("-replay: %s", err)
+		}
+		rep := cassette.NewReplayer(c)
+		http.DefaultTransport = rep
+		t.Cleanup(func() {
+			http.DefaultTransport = saved
+			if n := rep.Unused(); n != 0 {
+				t.Logf("-replay: %d interaction(s) of %s were not replayed", n, filename)
+			}
+		})
+	}
+}
@@ -43,19 +43,25 @@ func getProvider(t *testing.T) (providers.DNSServiceProvider, string, map[string
 		return nil, "", nil
 	}
 
-	// Load the profile values
-
-	jsons, err := credsfile.LoadProviderConfigs("profiles.json")
-	if err != nil {
-		t.Fatalf("Error loading provider configs: %s", err)
-	}
-
 	// Which profile are we using? Use the profile but default to the provider.
 	targetProfile := *profileFlag
 	if targetProfile == "" {
 		targetProfile = *providerFlag
 	}
 
+	// Load the profile values (from the cassette if replaying)
+
+	var jsons map[string]map[string]string
+	if *replayFlag {
+		jsons = loadCassetteProfile(t, targetProfile)
+	} else {
+		var err error
+		jsons, err = credsfile.LoadProviderConfigs("profiles.json")
+		if err != nil {
+			t.Fatalf("Error loading provider configs: %s", err)
+		}
+	}
+
 	var profileName, profileType string
 	var cfg map[string]string
 
@@ -112,6 +118,8 @@ func getProvider(t *testing.T) (providers.DNSServiceProvider, string, map[string
 		metadata = []byte(`{ ` + strings.Join(items, `, `) + ` }`)
 	}
 
+	useCassette(t, profileName, cfg)
+
 	provider, err := providers.CreateDNSProvider(profileType, cfg, metadata)
 	if err != nil {
 		t.Fatal(err)
@@ -50,6 +50,14 @@ type DomainConfig struct {
 	IgnoreExternalDNS bool   `json:"ignore_external_dns,omitempty"` // IGNORE_EXTERNAL_DNS
 	ExternalDNSPrefix string `json:"external_dns_prefix,omitempty"` // IGNORE_EXTERNAL_DNS prefix
 
+	OwnerID    string `json:"owner_id,omitempty"`    // OWNERSHIP
+	OwnerAdopt bool   `json:"owner_adopt,omitempty"` // OWNERSHIP "adopt"
+
+	MaxChanges int `json:"max_changes,omitempty"` // MAX_CHANGES
+	MaxDeletes int `json:"max_deletes,omitempty"` // MAX_DELETES
+
+	AllowDangling []string `json:"allow_dangling,omitempty"` // ALLOW_DANGLING
+
 	AutoDNSSEC string `json:"auto_dnssec,omitempty"` // "", "on", "off"
 	// DNSSEC        bool              `json:"dnssec,omitempty"`
 
@@ -5,9 +5,10 @@ package models
 // NOTE: Only newer rtypes are processed this way.  Eventually the
 // legacy types will be converted.
 type RawRecordConfig struct {
-	Type    string           `json:"type"`
-	Args    []any            `json:"args,omitempty"`
-	Metas   []map[string]any `json:"metas,omitempty"`
-	TTL     uint32           `json:"ttl,omitempty"`
-	FilePos string           `json:"filepos"` // Where in the file this record was defined.
+	Type       string           `json:"type"`
+	Args       []any            `json:"args,omitempty"`
+	Metas      []map[string]any `json:"metas,omitempty"`
+	TTL        uint32           `json:"ttl,omitempty"`
+	FilePos    string           `json:"filepos"`              // Where in the file this record was defined.
+	Provenance []string         `json:"provenance,omitempty"` // The D()/D_EXTEND()/INCLUDE() calls that added this record.
 }
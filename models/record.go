@@ -56,6 +56,11 @@ type RecordConfig struct {
 	// FilePos (desired) is "filename:line:char" of the record in dnsconfig.js (desired).
 	FilePos string `json:"filepos"`
 
+	// Provenance (desired) is the chain of D()/D_EXTEND()/INCLUDE() calls
+	// that added the record to the domain, outermost first. For example:
+	// ["D(\"example.com\") dnsconfig.js:3:1", "INCLUDE(\"common\") dnsconfig.js:4:5", "D(\"common\") common.js:1:1"]
+	Provenance []string `json:"provenance,omitempty"`
+
 	// Subdomain (if non-empty) contains the subdomain path for this record.
 	// When .Name* fields are updated to include the subdomain, this field is
 	// cleared.
@@ -143,16 +148,17 @@ func (rc *RecordConfig) UnmarshalJSON(b []byte) error {
 	recj := &struct {
 		Target string `json:"target"`
 
-		Type      string            `json:"type"` // All caps rtype name.
-		Name      string            `json:"name"` // The short name. See above.
-		SubDomain string            `json:"subdomain,omitempty"`
-		NameFQDN  string            `json:"-"` // Must end with ".$origin". See above.
-		target    string            // If a name, must end with "."
-		TTL       uint32            `json:"ttl,omitempty"`
-		Metadata  map[string]string `json:"meta,omitempty"`
-		FilePos   string            `json:"filepos"` // Where in the file this record was defined.
-		Original  any               `json:"-"`       // Store pointer to provider-specific record object. Used in diffing.
-		Args      []any             `json:"args,omitempty"`
+		Type       string            `json:"type"` // All caps rtype name.
+		Name       string            `json:"name"` // The short name. See above.
+		SubDomain  string            `json:"subdomain,omitempty"`
+		NameFQDN   string            `json:"-"` // Must end with ".$origin". See above.
+		target     string            // If a name, must end with "."
+		TTL        uint32            `json:"ttl,omitempty"`
+		Metadata   map[string]string `json:"meta,omitempty"`
+		FilePos    string            `json:"filepos"` // Where in the file this record was defined.
+		Provenance []string          `json:"provenance,omitempty"`
+		Original   any               `json:"-"` // Store pointer to provider-specific record object. Used in diffing.
+		Args       []any             `json:"args,omitempty"`
 
 		MxPreference       uint16            `json:"mxpreference,omitempty"`
 		SrvPriority        uint16            `json:"srvpriority,omitempty"`
@@ -252,6 +258,20 @@ func FixPosition(str string) string {
 	return fmt.Sprintf("[%s]", str)
 }
 
+// Origin returns where in dnsconfig.js the record was defined: its FilePos
+// followed, if the record did not come directly from the D() of its domain,
+// by the chain of D()/D_EXTEND()/INCLUDE() calls that added it. It returns
+// "" for records that did not come from dnsconfig.js.
+func (rc *RecordConfig) Origin() string {
+	if rc.FilePos == "" {
+		return ""
+	}
+	if len(rc.Provenance) == 0 || (len(rc.Provenance) == 1 && strings.HasPrefix(rc.Provenance[0], "D(")) {
+		return rc.FilePos
+	}
+	return rc.FilePos + " via " + strings.Join(rc.Provenance, " > ")
+}
+
 // Copy returns a deep copy of a RecordConfig.
 func (rc *RecordConfig) Copy() (*RecordConfig, error) {
 	newR := &RecordConfig{}
@@ -260,3 +260,30 @@ func TestFixPosition(t *testing.T) {
 		})
 	}
 }
+
+func TestOrigin(t *testing.T) {
+	tests := []struct {
+		name       string
+		filePos    string
+		provenance []string
+		want       string
+	}{
+		{"not from dnsconfig.js", "", []string{`D("example.com") dnsconfig.js:1:1`}, ""},
+		{"no provenance", "[dnsconfig.js:2:5]", nil, "[dnsconfig.js:2:5]"},
+		{"directly in D", "[dnsconfig.js:2:5]", []string{`D("example.com") dnsconfig.js:1:1`}, "[dnsconfig.js:2:5]"},
+		{
+			"via INCLUDE",
+			"[dnsconfig.js:2:5]",
+			[]string{`D("example.com") dnsconfig.js:5:1`, `INCLUDE("common.example") dnsconfig.js:6:5`},
+			`[dnsconfig.js:2:5] via D("example.com") dnsconfig.js:5:1 > INCLUDE("common.example") dnsconfig.js:6:5`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &RecordConfig{FilePos: tt.filePos, Provenance: tt.provenance}
+			if got := rc.Origin(); got != tt.want {
+				t.Errorf("Origin() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+// Package cassette records the HTTP requests that a provider makes, and the
+// responses of the API, so that they can be replayed later without the
+// API. This lets the integration tests run offline.
+//
+// A cassette is recorded by a run against a live account (see Recorder).
+// It is sanitized: the values of the secret fields of the profile (API
+// keys, passwords, ...) are replaced by placeholders everywhere, and the
+// headers that authenticate the requests are not recorded. The profile is
+// stored in the cassette with the placeholders, so that the provider makes
+// the same requests when it is replayed (see Replayer).
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Version is the version of the format of the cassette files.
+const Version = 1
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Version      int               `json:"version"`
+	Profile      map[string]string `json:"profile"` // The profile (creds) of the provider, sanitized.
+	Interactions []*Interaction    `json:"interactions"`
+}
+
+// Interaction is a request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. Its headers are not recorded: they are
+// not used to find the response of a request, and often have secrets.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   Body   `json:"body,omitzero"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    Body        `json:"body,omitzero"`
+}
+
+// Body is the body of a request or response. It is stored as a string if it
+// is valid UTF-8, else in base64.
+type Body []byte
+
+// MarshalJSON implements json.Marshaler.
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	d, err := base64.StdEncoding.DecodeString(m["base64"])
+	*b = d
+	return err
+}
+
+// Load reads the cassette filename.
+func Load(filename string) (*Cassette, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cassette{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if c.Version != Version {
+		return nil, fmt.Errorf("%s: unsupported version %d (want %d); record it again", filename, c.Version, Version)
+	}
+	return c, nil
+}
+
+// Save writes the cassette to filename, creating its directory if needed.
+func (c *Cassette) Save(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(b, '\n'), 0o644)
+}
+
+// Sanitizer replaces the secrets of a profile by placeholders.
+type Sanitizer struct {
+	replacer *strings.Replacer
+	profile  map[string]string
+}
+
+// secretWords are the words that, in the name of a field of a profile,
+// make its value a secret.
+var secretWords = []string{"key", "secret", "token", "password", "passwd", "auth", "credential", "cookie"}
+
+// minSecretLen is the length under which a value is not considered a
+// secret: replacing such a short string everywhere would mangle the
+// requests.
+const minSecretLen = 4
+
+// IsSecretField returns true if the field name of a profile has a secret
+// value.
+func IsSecretField(name string) bool {
+	name = strings.ToLower(name)
+	return slices.ContainsFunc(secretWords, func(w string) bool { return strings.Contains(name, w) })
+}
+
+// Placeholder returns the placeholder of the value of the field name.
+func Placeholder(name string) string {
+	return "REDACTED_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// NewSanitizer returns a Sanitizer of the secret fields of profile.
+func NewSanitizer(profile map[string]string) *Sanitizer {
+	s := &Sanitizer{profile: map[string]string{}}
+	var secrets [][2]string // The secrets and their placeholders.
+	for name, v := range profile {
+		if IsSecretField(name) && len(v) >= minSecretLen {
+			p := Placeholder(name)
+			s.profile[name] = p
+			secrets = append(secrets, [2]string{v, p})
+			// The secret may also appear URL-encoded.
+			if e := url.QueryEscape(v); e != v {
+				secrets = append(secrets, [2]string{e, p})
+			}
+			continue
+		}
+		s.profile[name] = v
+	}
+	// Longest secrets first, so that a secret that contains another is
+	// replaced as a whole.
+	slices.SortStableFunc(secrets, func(a, b [2]string) int { return len(b[0]) - len(a[0]) })
+	var oldnew []string
+	for _, p := range secrets {
+		oldnew = append(oldnew, p[0], p[1])
+	}
+	s.replacer = strings.NewReplacer(oldnew...)
+	return s
+}
+
+// Profile returns the profile with the secrets replaced by placeholders.
+func (s *Sanitizer) Profile() map[string]string {
+	return s.profile
+}
+
+// String returns v with the secrets replaced by placeholders.
+func (s *Sanitizer) String(v string) string {
+	return s.replacer.Replace(v)
+}
+
+// Bytes returns b with the secrets replaced by placeholders.
+func (s *Sanitizer) Bytes(b []byte) []byte {
+	return []byte(s.replacer.Replace(string(b)))
+}
+
+// Headers returns the headers of a response that are recorded.
+func (s *Sanitizer) Headers(h http.Header) http.Header {
+	r := http.Header{}
+	for k, vs := range h {
+		if secretHeader(k) {
+			continue
+		}
+		for _, v := range vs {
+			r.Add(k, s.String(v))
+		}
+	}
+	return r
+}
+
+// secretHeader returns true if the header k is not recorded.
+func secretHeader(k string) bool {
+	switch http.CanonicalHeaderKey(k) {
+	case "Set-Cookie", "Authorization", "Proxy-Authorization", "Cookie":
+		return true
+	}
+	return IsSecretField(k) || strings.Contains(strings.ToLower(k), "signature")
+}
+
+// readBody reads body, which may be nil.
+func readBody(body io.ReadCloser) ([]byte, error) {
+	if body == nil || body == http.NoBody {
+		return nil, nil
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// Recorder is an http.RoundTripper that sends the requests with another
+// RoundTripper and records them, sanitized.
+type Recorder struct {
+	base      http.RoundTripper
+	sanitizer *Sanitizer
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+// NewRecorder returns a Recorder that sends the requests with base (or
+// http.DefaultTransport if base is nil) and sanitizes the secrets of
+// profile.
+func NewRecorder(base http.RoundTripper, profile map[string]string) *Recorder {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	s := NewSanitizer(profile)
+	return &Recorder{
+		base:      base,
+		sanitizer: s,
+		cassette:  &Cassette{Version: Version, Profile: s.Profile(), Interactions: []*Interaction{}},
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	if reqBody != nil {
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		// Errors are not recorded; the replay fails if the request is made.
+		return nil, err
+	}
+	respBody, err := readBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	s := r.sanitizer
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    s.String(req.URL.String()),
+			Body:   s.Bytes(reqBody),
+		},
+		Response: Response{
+			Status:  resp.StatusCode,
+			Headers: s.Headers(resp.Header),
+			Body:    s.Bytes(respBody),
+		},
+	})
+	return resp, nil
+}
+
+// Cassette returns what was recorded.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette
+}
+
+// Replayer is an http.RoundTripper that answers the requests with the
+// responses of a cassette.
+//
+// A request gets the response of the first interaction not yet replayed
+// with the same method, URL and body; or else (if the body has something
+// that changes from run to run, such as a timestamp) with the same method
+// and URL.
+type Replayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer returns a Replayer of c.
+func NewReplayer(c *Cassette) *Replayer {
+	return &Replayer{cassette: c, used: make([]bool, len(c.Interactions))}
+}
+
+// ErrNotRecorded is returned for a request that is not in the cassette.
+var ErrNotRecorded = errors.New("request not recorded in the cassette")
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	u := req.URL.String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(func(in *Interaction) bool {
+		return in.Request.Method == req.Method && in.Request.URL == u && bytes.Equal(in.Request.Body, body)
+	})
+	if i < 0 {
+		i = r.find(func(in *Interaction) bool {
+			return in.Request.Method == req.Method && in.Request.URL == u
+		})
+	}
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, req.Method, u)
+	}
+	r.used[i] = true
+
+	rec := r.cassette.Interactions[i].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.Headers.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(rec.Body)),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
+
+// find returns the index of the first interaction not yet replayed that
+// matches, or -1.
+func (r *Replayer) find(match func(*Interaction) bool) int {
+	for i, in := range r.cassette.Interactions {
+		if !r.used[i] && match(in) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Unused returns the number of interactions that were not replayed.
+func (r *Replayer) Unused() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, u := range r.used {
+		if !u {
+			n++
+		}
+	}
+	return n
+}
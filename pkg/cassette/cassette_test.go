@@ -0,0 +1,185 @@
+package cassette
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizer(t *testing.T) {
+	s := NewSanitizer(map[string]string{
+		"TYPE":      "EXAMPLE",
+		"domain":    "example.com",
+		"api_key":   "sekrit-key",
+		"api_token": "sekrit-key/with+more",
+		"pin":       "123",
+		"username":  "alice",
+	})
+
+	wantProfile := map[string]string{
+		"TYPE":      "EXAMPLE",
+		"domain":    "example.com",
+		"api_key":   "REDACTED_API_KEY",
+		"api_token": "REDACTED_API_TOKEN",
+		"pin":       "123",
+		"username":  "alice",
+	}
+	for k, v := range wantProfile {
+		if got := s.Profile()[k]; got != v {
+			t.Errorf("Profile()[%q] = %q, want %q", k, got, v)
+		}
+	}
+
+	tests := []struct{ in, want string }{
+		{"/zones?key=sekrit-key", "/zones?key=REDACTED_API_KEY"},
+		// The longer secret is replaced as a whole:
+		{`{"t":"sekrit-key/with+more"}`, `{"t":"REDACTED_API_TOKEN"}`},
+		{"/auth?t=sekrit-key%2Fwith%2Bmore", "/auth?t=REDACTED_API_TOKEN"},
+		{"user alice at example.com", "user alice at example.com"},
+	}
+	for _, tt := range tests {
+		if got := s.String(tt.in); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	h := s.Headers(http.Header{
+		"Content-Type":    {"application/json"},
+		"Set-Cookie":      {"session=1"},
+		"X-Api-Key":       {"sekrit-key"},
+		"X-Request-Owner": {"owner sekrit-key"},
+	})
+	want := http.Header{
+		"Content-Type":    {"application/json"},
+		"X-Request-Owner": {"owner REDACTED_API_KEY"},
+	}
+	if fmt.Sprint(h) != fmt.Sprint(want) {
+		t.Errorf("Headers() = %v, want %v", h, want)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	// A fake API: a counter that POST increments, and that requires the key.
+	counter := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "sekrit-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPost {
+			b, _ := io.ReadAll(r.Body)
+			if string(b) != "+1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			counter++
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "%d (key %s)", counter, r.Header.Get("X-Api-Key"))
+	}))
+	defer srv.Close()
+
+	// run is the code under test: it reads, increments and reads again.
+	run := func(client *http.Client, key string) []string {
+		var got []string
+		do := func(method, body string) {
+			req, _ := http.NewRequest(method, srv.URL+"/counter", strings.NewReader(body))
+			req.Header.Set("X-Api-Key", key)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			b, _ := io.ReadAll(resp.Body)
+			got = append(got, fmt.Sprintf("%d %s", resp.StatusCode, b))
+		}
+		do(http.MethodGet, "")
+		do(http.MethodPost, "+1")
+		do(http.MethodGet, "")
+		return got
+	}
+
+	rec := NewRecorder(nil, map[string]string{"TYPE": "EXAMPLE", "api_key": "sekrit-key"})
+	live := run(&http.Client{Transport: rec}, "sekrit-key")
+	wantLive := []string{"200 0 (key sekrit-key)", "200 1 (key sekrit-key)", "200 1 (key sekrit-key)"}
+	if fmt.Sprint(live) != fmt.Sprint(wantLive) {
+		t.Fatalf("live run = %q, want %q", live, wantLive)
+	}
+
+	filename := filepath.Join(t.TempDir(), "sub", "cassette.json")
+	if err := rec.Cassette().Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Interactions) != 3 {
+		t.Fatalf("got %d interactions, want 3", len(c.Interactions))
+	}
+	key := c.Profile["api_key"]
+	if key != "REDACTED_API_KEY" {
+		t.Errorf("the profile of the cassette has api_key %q", key)
+	}
+
+	// The replay uses the sanitized profile, and the API is down.
+	srv.Close()
+	replayer := NewReplayer(c)
+	replayed := run(&http.Client{Transport: replayer}, key)
+	wantReplayed := []string{"200 0 (key REDACTED_API_KEY)", "200 1 (key REDACTED_API_KEY)", "200 1 (key REDACTED_API_KEY)"}
+	if fmt.Sprint(replayed) != fmt.Sprint(wantReplayed) {
+		t.Errorf("replay = %q, want %q", replayed, wantReplayed)
+	}
+	if n := replayer.Unused(); n != 0 {
+		t.Errorf("%d interactions not replayed", n)
+	}
+
+	// A request that wasn't recorded:
+	_, err = (&http.Client{Transport: replayer}).Get(srv.URL + "/counter")
+	if !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("got %v, want ErrNotRecorded", err)
+	}
+}
+
+func TestReplay_bodyMismatch(t *testing.T) {
+	// A request whose body differs (a timestamp, ...) gets the response of
+	// the same method and URL.
+	c := &Cassette{Version: Version, Interactions: []*Interaction{
+		{Request: Request{Method: "POST", URL: "http://api/x", Body: Body("a")}, Response: Response{Status: 201, Body: Body("first")}},
+		{Request: Request{Method: "POST", URL: "http://api/x", Body: Body("b")}, Response: Response{Status: 201, Body: Body("second")}},
+	}}
+	client := &http.Client{Transport: NewReplayer(c)}
+	for _, tt := range []struct{ body, want string }{{"b", "second"}, {"c", "first"}} {
+		resp, err := client.Post("http://api/x", "text/plain", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(b) != tt.want {
+			t.Errorf("POST %q: got %q, want %q", tt.body, b, tt.want)
+		}
+	}
+}
+
+func TestBody_binary(t *testing.T) {
+	c := &Cassette{Version: Version, Interactions: []*Interaction{
+		{Response: Response{Status: 200, Body: Body{0xff, 0x00, 'a'}}},
+	}}
+	filename := filepath.Join(t.TempDir(), "c.json")
+	if err := c.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := got.Interactions[0].Response.Body; string(b) != "\xff\x00a" {
+		t.Errorf("got %q", b)
+	}
+}
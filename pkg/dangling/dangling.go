@@ -0,0 +1,196 @@
+// Package dangling finds records whose target is likely to be broken or
+// vulnerable to a subdomain takeover: CNAME, ALIAS, MX and NS records that
+// point to names that do not exist in the zones of dnsconfig.js, and CNAME
+// and ALIAS records that point to cloud services whose names can be
+// claimed by someone else once the resource is deleted.
+package dangling
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/gobwas/glob"
+)
+
+// TakeoverPatterns are targets at services that are well-known subdomain
+// takeover vectors. "**" matches one or more labels.
+var TakeoverPatterns = []string{
+	"**.azure-api.net",
+	"**.azurecontainer.io",
+	"**.azureedge.net",
+	"**.azurewebsites.net",
+	"**.blob.core.windows.net",
+	"**.cloudapp.azure.com",
+	"**.cloudapp.net",
+	"**.trafficmanager.net",
+	"**.elasticbeanstalk.com",
+	"**.s3.amazonaws.com",
+	"**.s3.*.amazonaws.com",
+	"**.s3-website*.amazonaws.com",
+	"**.s3-website.*.amazonaws.com",
+	"**.bitbucket.io",
+	"**.ghost.io",
+	"**.github.io",
+	"**.herokuapp.com",
+	"**.herokudns.com",
+	"**.helpscoutdocs.com",
+	"**.myshopify.com",
+	"**.netlify.app",
+	"**.ngrok.io",
+	"**.pantheonsite.io",
+	"**.readthedocs.io",
+	"**.surge.sh",
+	"**.unbouncepages.com",
+	"**.wordpress.com",
+	"**.zendesk.com",
+}
+
+var takeoverGlobs = func() []glob.Glob {
+	globs, err := compileAll(TakeoverPatterns)
+	if err != nil {
+		panic(err)
+	}
+	return globs
+}()
+
+// checkedTypes are the types of the records whose targets are checked.
+var checkedTypes = map[string]bool{"CNAME": true, "ALIAS": true, "MX": true, "NS": true}
+
+// zone is the union of the DomainConfigs of a zone (there is more than one
+// if split horizon is used).
+type zone struct {
+	name      string
+	names     map[string]bool // The FQDNs that have records.
+	delegated map[string]bool // The FQDNs (other than the apex) that have NS records.
+	unknown   bool            // NO_PURGE, IGNORE_EXTERNAL_DNS or OWNERSHIP: there may be records we don't know about.
+	ignored   []glob.Glob     // IGNORE() label patterns.
+}
+
+// Check returns a description of each dangling target in cfg. Targets that
+// match the ALLOW_DANGLING() patterns of the record's domain are not
+// reported. The error is about invalid ALLOW_DANGLING() patterns.
+func Check(cfg *models.DNSConfig) ([]error, error) {
+	zones := map[string]*zone{}
+	for _, dc := range cfg.Domains {
+		z := zones[dc.Name]
+		if z == nil {
+			z = &zone{name: dc.Name, names: map[string]bool{}, delegated: map[string]bool{}}
+			zones[dc.Name] = z
+		}
+		// Other systems (external-dns) or configs (OWNERSHIP) may own
+		// names in the zone, like with NO_PURGE.
+		z.unknown = z.unknown || dc.KeepUnknown || dc.IgnoreExternalDNS || dc.OwnerID != ""
+		for _, u := range dc.Unmanaged {
+			if g, err := glob.Compile(cmp.Or(u.LabelPattern, "*")); err == nil {
+				z.ignored = append(z.ignored, g)
+			}
+		}
+		for _, rec := range dc.Records {
+			name := strings.ToLower(rec.GetLabelFQDN())
+			z.names[name] = true
+			if rec.Type == "NS" && name != dc.Name {
+				z.delegated[name] = true
+			}
+		}
+	}
+
+	var findings, errs []error
+	for _, dc := range cfg.Domains {
+		allowed, err := compileAll(dc.AllowDangling)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ALLOW_DANGLING in %s: %w", dc.Name, err))
+			continue
+		}
+		for _, rec := range dc.Records {
+			if !checkedTypes[rec.Type] {
+				continue
+			}
+			target := strings.ToLower(strings.TrimSuffix(rec.GetTargetField(), "."))
+			if target == "" || matchAny(allowed, target) {
+				continue
+			}
+			what := fmt.Sprintf("%s %s -> %s", rec.Type, rec.GetLabelFQDN(), rec.GetTargetField())
+			if rec.FilePos != "" {
+				what += " " + rec.FilePos
+			}
+
+			if z := findZone(zones, target); z != nil {
+				if !z.exists(target) {
+					findings = append(findings, fmt.Errorf("dangling target: %s: %q does not exist in %s; add it or use ALLOW_DANGLING(%q) if this is intentional", what, target, z.name, target))
+				}
+				continue
+			}
+			if rec.Type == "CNAME" || rec.Type == "ALIAS" {
+				if matchAny(takeoverGlobs, target) {
+					findings = append(findings, fmt.Errorf("takeover risk: %s: the name can be claimed by someone else if the resource at the cloud service is deleted; use ALLOW_DANGLING(%q) if this is intentional", what, target))
+				}
+			}
+		}
+	}
+	return findings, errors.Join(errs...)
+}
+
+// findZone returns the zone that name is in, or nil if it is not in one
+// of the zones.
+func findZone(zones map[string]*zone, name string) *zone {
+	for n := name; n != ""; n = parent(n) {
+		if z, ok := zones[n]; ok {
+			return z
+		}
+	}
+	return nil
+}
+
+// exists returns true if name (in z) has records, is covered by a wildcard
+// or might exist without us knowing (delegated, IGNORE()d, NO_PURGE,
+// IGNORE_EXTERNAL_DNS or OWNERSHIP).
+func (z *zone) exists(name string) bool {
+	if z.unknown || z.names[name] {
+		return true
+	}
+	label := strings.TrimSuffix(strings.TrimSuffix(name, z.name), ".")
+	if label == "" {
+		label = "@"
+	}
+	if matchAny(z.ignored, label) {
+		return true
+	}
+	for n := name; n != z.name && n != ""; n = parent(n) {
+		if z.delegated[n] || z.names["*."+parent(n)] {
+			return true
+		}
+	}
+	return false
+}
+
+// parent returns name without its first label.
+func parent(name string) string {
+	_, p, _ := strings.Cut(name, ".")
+	return p
+}
+
+// compileAll compiles glob patterns in which "*" matches within a label
+// and "**" matches across labels.
+func compileAll(patterns []string) ([]glob.Glob, error) {
+	var r []glob.Glob
+	for _, p := range patterns {
+		g, err := glob.Compile(strings.ToLower(strings.TrimSuffix(p, ".")), '.')
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", p, err)
+		}
+		r = append(r, g)
+	}
+	return r, nil
+}
+
+func matchAny(globs []glob.Glob, s string) bool {
+	for _, g := range globs {
+		if g.Match(s) {
+			return true
+		}
+	}
+	return false
+}
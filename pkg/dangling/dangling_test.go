@@ -0,0 +1,80 @@
+package dangling
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+func makeRec(zone, label, rtype, content string) *models.RecordConfig {
+	r := &models.RecordConfig{TTL: 300}
+	r.SetLabel(label, zone)
+	if err := r.PopulateFromString(rtype, content, zone); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func TestCheck(t *testing.T) {
+	exCom := &models.DomainConfig{
+		Name: "example.com",
+		Records: models.Records{
+			makeRec("example.com", "@", "NS", "ns1.example.com."),
+			makeRec("example.com", "ns1", "A", "1.2.3.4"),
+			makeRec("example.com", "@", "MX", "10 mail.example.com."),
+			makeRec("example.com", "www", "A", "1.2.3.5"),
+			makeRec("example.com", "api", "CNAME", "www.example.com."),
+			makeRec("example.com", "typo", "CNAME", "wwww.example.com."),
+			makeRec("example.com", "shop", "CNAME", "www.example.net."),
+			makeRec("example.com", "gone", "CNAME", "nope.example.net."),
+			makeRec("example.com", "wild", "CNAME", "x.dyn.example.com."),
+			makeRec("example.com", "*.dyn", "A", "1.2.3.6"),
+			makeRec("example.com", "sub", "NS", "ns.elsewhere.org."),
+			makeRec("example.com", "deleg", "CNAME", "host.sub.example.com."),
+			makeRec("example.com", "app", "CNAME", "myapp.cloudapp.net."),
+			makeRec("example.com", "site", "CNAME", "example.github.io."),
+			makeRec("example.com", "bucket", "CNAME", "my.bucket.s3.us-east-2.amazonaws.com."),
+			makeRec("example.com", "cdn", "CNAME", "cdn.example.org."),
+			makeRec("example.com", "k8s", "CNAME", "app.example.info."),
+			makeRec("example.com", "team", "CNAME", "app.example.biz."),
+		},
+		AllowDangling: []string{"*.github.io"},
+	}
+	exCom.Records[5].FilePos = "[dnsconfig.js:7:5]"
+	exNet := &models.DomainConfig{
+		Name: "example.net",
+		Records: models.Records{
+			makeRec("example.net", "www", "A", "1.2.3.7"),
+		},
+	}
+	exOrg := &models.DomainConfig{Name: "example.org", KeepUnknown: true}
+	exInfo := &models.DomainConfig{Name: "example.info", IgnoreExternalDNS: true}
+	exBiz := &models.DomainConfig{Name: "example.biz", OwnerID: "team-a"}
+
+	findings, err := Check(&models.DNSConfig{Domains: []*models.DomainConfig{exCom, exNet, exOrg, exInfo, exBiz}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.Error())
+	}
+	want := []string{
+		`dangling target: MX example.com -> mail.example.com.: "mail.example.com" does not exist in example.com; add it or use ALLOW_DANGLING("mail.example.com") if this is intentional`,
+		`dangling target: CNAME typo.example.com -> wwww.example.com. [dnsconfig.js:7:5]: "wwww.example.com" does not exist in example.com; add it or use ALLOW_DANGLING("wwww.example.com") if this is intentional`,
+		`dangling target: CNAME gone.example.com -> nope.example.net.: "nope.example.net" does not exist in example.net; add it or use ALLOW_DANGLING("nope.example.net") if this is intentional`,
+		`takeover risk: CNAME app.example.com -> myapp.cloudapp.net.: the name can be claimed by someone else if the resource at the cloud service is deleted; use ALLOW_DANGLING("myapp.cloudapp.net") if this is intentional`,
+		`takeover risk: CNAME bucket.example.com -> my.bucket.s3.us-east-2.amazonaws.com.: the name can be claimed by someone else if the resource at the cloud service is deleted; use ALLOW_DANGLING("my.bucket.s3.us-east-2.amazonaws.com") if this is intentional`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Check():\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckInvalidPattern(t *testing.T) {
+	dc := &models.DomainConfig{Name: "example.com", AllowDangling: []string{"[oops"}}
+	if _, err := Check(&models.DNSConfig{Domains: []*models.DomainConfig{dc}}); err == nil || !strings.Contains(err.Error(), `"[oops"`) {
+		t.Errorf("Check() error = %v, want an error about the pattern", err)
+	}
+}
@@ -11,6 +11,12 @@ import (
 )
 
 func analyzeByRecordSet(cc *CompareConfig) (ChangeList, int) {
+	instructions, actualChangeCount := analyzeByRecordSetUnordered(cc)
+	return orderByDependencies(instructions), actualChangeCount
+}
+
+// analyzeByRecordSetUnordered is analyzeByRecordSet without the dependency ordering.
+func analyzeByRecordSetUnordered(cc *CompareConfig) (ChangeList, int) {
 	var instructions ChangeList
 	var actualChangeCount int
 	// For each label...
@@ -37,8 +43,6 @@ func analyzeByRecordSet(cc *CompareConfig) (ChangeList, int) {
 		}
 	}
 
-	instructions = orderByDependencies(instructions)
-
 	return instructions, actualChangeCount
 }
 
@@ -181,7 +185,7 @@ func findTTLChanges(existing, desired []targetConfig) ([]targetConfig, []targetC
 		}
 
 		if ecomp == dcomp && er.TTL != dr.TTL {
-			m := color.YellowString("± MODIFY-TTL %s %s %s", dr.NameFQDN, dr.Type, humanDiff(existing[ei], desired[di]))
+			m := color.YellowString("± MODIFY-TTL %s %s %s%s", dr.NameFQDN, dr.Type, humanDiff(existing[ei], desired[di]), origin(dr))
 			v := mkChange(dr.NameFQDN, dr.Type, []string{m},
 				models.Records{er},
 				models.Records{dr},
@@ -243,10 +247,41 @@ func humanDiff(a, b targetConfig) string {
 		a.comparableNoTTL)
 }
 
+// origin returns " " followed by where in dnsconfig.js rc was defined, or ""
+// if that is unknown or NoProvenance is set.
+func origin(rc *models.RecordConfig) string {
+	if NoProvenance {
+		return ""
+	}
+	if o := rc.Origin(); o != "" {
+		return " " + o
+	}
+	return ""
+}
+
 var echRe = regexp.MustCompile(`ech="?([\w+/=]+)"?`)
 
 // diffTargets is the real workhorse of the diff2 system.  All the setup has been complete,
 // now we can find the differences between two zones.
+// SplitByRecord returns the changes of the record set change c (from
+// ByRecordSet or ByRecordSetUnordered) record by record, as ByRecord
+// would: each change has at most one old and one new record. The records
+// are compared with compFunc, which must be the one c was generated with.
+func SplitByRecord(c Change, compFunc ComparableFunc) ChangeList {
+	if c.Type == REPORT {
+		return nil
+	}
+	targets := func(recs models.Records) []targetConfig {
+		r := make([]targetConfig, 0, len(recs))
+		for _, rec := range recs {
+			compNoTTL, compFull := mkCompareBlobs(rec, compFunc)
+			r = append(r, targetConfig{comparableNoTTL: compNoTTL, comparableFull: compFull, rec: rec})
+		}
+		return r
+	}
+	return diffTargets(targets(c.Old), targets(c.New))
+}
+
 func diffTargets(existing, desired []targetConfig) ChangeList {
 	// fmt.Printf("DEBUG: diffTargets(\nexisting=%v\ndesired=%v\nDEBUG.\n", existing, desired)
 
@@ -301,7 +336,7 @@ func diffTargets(existing, desired []targetConfig) ChangeList {
 		er := existing[i].rec
 		dr := desired[i].rec
 
-		m := color.YellowString("± MODIFY %s %s %s", dr.NameFQDN, dr.Type, humanDiff(existing[i], desired[i]))
+		m := color.YellowString("± MODIFY %s %s %s%s", dr.NameFQDN, dr.Type, humanDiff(existing[i], desired[i]), origin(dr))
 
 		mkc := mkChange(dr.NameFQDN, dr.Type, []string{m}, models.Records{er}, models.Records{dr})
 		instructions = append(instructions, mkc)
@@ -317,7 +352,7 @@ func diffTargets(existing, desired []targetConfig) ChangeList {
 	// any left-over desired are creates
 	for i := mi; i < len(desired); i++ {
 		dr := desired[i].rec
-		m := color.GreenString("+ CREATE %s %s %s", dr.NameFQDN, dr.Type, desired[i].comparableFull)
+		m := color.GreenString("+ CREATE %s %s %s%s", dr.NameFQDN, dr.Type, desired[i].comparableFull, origin(dr))
 		instructions = append(instructions, mkAdd(dr.NameFQDN, dr.Type, []string{m}, models.Records{dr}))
 	}
 
@@ -717,6 +717,45 @@ func Test_diffTargets(t *testing.T) {
 	}
 }
 
+func Test_diffTargetsOrigin(t *testing.T) {
+	created := makeRec("laba", "A", "1.2.3.4")
+	created.FilePos = "[dnsconfig.js:3:5]"
+	created.Provenance = []string{`D("f.com") dnsconfig.js:6:1`, `INCLUDE("common") dnsconfig.js:7:5`}
+	want := `+ CREATE laba.f.com A 1.2.3.4 ttl=300 [dnsconfig.js:3:5] via D("f.com") dnsconfig.js:6:1 > INCLUDE("common") dnsconfig.js:7:5`
+
+	got := diffTargets(nil, mkTargetConfig(created))
+	if g := strings.TrimSpace(justMsgString(got)); g != want {
+		t.Errorf("diffTargets() = %q, want %q", g, want)
+	}
+
+	NoProvenance = true
+	defer func() { NoProvenance = false }()
+	got = diffTargets(nil, mkTargetConfig(created))
+	if g := strings.TrimSpace(justMsgString(got)); g != "+ CREATE laba.f.com A 1.2.3.4 ttl=300" {
+		t.Errorf("diffTargets() with NoProvenance = %q", g)
+	}
+}
+
+func TestSplitByRecord(t *testing.T) {
+	a1, a2 := makeRec("laba", "A", "1.2.3.4"), makeRec("laba", "A", "1.2.3.5")
+	a2ttl := makeRecTTL("laba", "A", "1.2.3.5", 600)
+	a3 := makeRec("laba", "A", "1.2.3.6")
+	c := mkChange("laba.f.com", "A", nil, models.Records{a1, a2}, models.Records{a2ttl, a3})
+
+	var got []string
+	for _, ch := range SplitByRecord(c, nil) {
+		got = append(got, fmt.Sprintf("%s %d %d", ch.Type, len(ch.Old), len(ch.New)))
+	}
+	if want := "[CHANGE 1 1 CHANGE 1 1]"; fmt.Sprint(got) != want {
+		t.Errorf("SplitByRecord() = %v, want %s", got, want)
+	}
+
+	// Unchanged records are left out:
+	if got := SplitByRecord(mkChange("laba.f.com", "A", nil, models.Records{a1, a2}, models.Records{a1, a3}), nil); len(got) != 1 {
+		t.Errorf("SplitByRecord() = %v, want 1 change", got)
+	}
+}
+
 func Test_removeCommon(t *testing.T) {
 	type args struct {
 		existing []targetConfig
@@ -0,0 +1,44 @@
+package diff2
+
+import (
+	"sync"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+// The commands that describe a zone's pending changes (plans, reports,
+// guardrails, etc.) use ByRecordSetUnordered, not the By*() function the
+// provider uses. To see the same differences as the provider (metadata that
+// only the provider compares, for example), they need the ComparableFunc
+// that the provider gave to diff2. RecordCompFunc records it.
+
+var recording = struct {
+	sync.Mutex
+	compFuncs map[*models.DomainConfig]*ComparableFunc
+}{compFuncs: map[*models.DomainConfig]*ComparableFunc{}}
+
+// RecordCompFunc starts recording the ComparableFunc that the By*()
+// functions are called with for dc. The function it returns stops
+// recording and returns the last ComparableFunc used for dc (nil if none).
+func RecordCompFunc(dc *models.DomainConfig) (stop func() ComparableFunc) {
+	var compFunc ComparableFunc
+	recording.Lock()
+	recording.compFuncs[dc] = &compFunc
+	recording.Unlock()
+	return func() ComparableFunc {
+		recording.Lock()
+		delete(recording.compFuncs, dc)
+		recording.Unlock()
+		return compFunc
+	}
+}
+
+// recordCompFunc records that the changes of dc were generated with
+// compFunc, if RecordCompFunc was called for dc.
+func recordCompFunc(dc *models.DomainConfig, compFunc ComparableFunc) {
+	recording.Lock()
+	defer recording.Unlock()
+	if p, ok := recording.compFuncs[dc]; ok {
+		*p = compFunc
+	}
+}
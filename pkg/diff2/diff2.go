@@ -151,6 +151,16 @@ func ByRecordSet(existing models.Records, dc *models.DomainConfig, compFunc Comp
 	return byHelper(analyzeByRecordSet, existing, dc, compFunc)
 }
 
+// ByRecordSetUnordered is like ByRecordSet but the changes are not
+// reordered by dependency.
+//
+// It is not intended for providers. It is used by the commands that need to
+// describe a zone's pending changes (plans, reports, etc.) independently of
+// how the provider will apply them.
+func ByRecordSetUnordered(existing models.Records, dc *models.DomainConfig, compFunc ComparableFunc) (ChangeList, int, error) {
+	return byHelper(analyzeByRecordSetUnordered, existing, dc, compFunc)
+}
+
 // ByLabel takes two lists of records (existing and desired) and
 // returns instructions for turning existing into desired.
 //
@@ -230,6 +240,8 @@ func byHelper(fn func(cc *CompareConfig) (ChangeList, int), existing models.Reco
 
 // byHelperStruct does 90% of the work for the By*() calls.
 func byHelperStruct(fn func(cc *CompareConfig) (ChangeList, int), existing models.Records, dc *models.DomainConfig, compFunc ComparableFunc) (ByResults, error) {
+	recordCompFunc(dc, compFunc)
+
 	// Process NO_PURGE/ENSURE_ABSENT and IGNORE*().
 	desiredPlus, msgs, err := handsoff(
 		dc.Name,
@@ -239,6 +251,8 @@ func byHelperStruct(fn func(cc *CompareConfig) (ChangeList, int), existing model
 		dc.KeepUnknown,
 		dc.IgnoreExternalDNS,
 		dc.ExternalDNSPrefix,
+		dc.OwnerID,
+		dc.OwnerAdopt,
 	)
 	if err != nil {
 		return ByResults{}, err
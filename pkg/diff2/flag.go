@@ -2,3 +2,7 @@ package diff2
 
 // DisableOrdering can be set to true to disable the reordering of the changes.
 var DisableOrdering bool
+
+// NoProvenance can be set to true to omit where in dnsconfig.js each
+// record was defined from the messages.
+var NoProvenance bool
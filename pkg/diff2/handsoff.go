@@ -2,7 +2,8 @@ package diff2
 
 // This file implements the features that tell DNSControl "hands off"
 // foreign-controlled (or shared-control) DNS records.  i.e. the
-// NO_PURGE, ENSURE_ABSENT and IGNORE*() features.
+// NO_PURGE, ENSURE_ABSENT and IGNORE*() features.  (IGNORE_EXTERNAL_DNS and
+// OWNERSHIP are in externaldns.go and ownership.go.)
 
 import (
 	"errors"
@@ -108,6 +109,8 @@ func handsoff(
 	noPurge bool,
 	ignoreExternalDNS bool,
 	externalDNSPrefix string,
+	ownerID string,
+	ownerAdopt bool,
 ) (models.Records, []string, error) {
 	var msgs []string
 
@@ -174,13 +177,80 @@ func handsoff(
 		externalDNSIgnored = filterOutConflicts(externalDNSIgnored, externalDNSConflicts)
 	}
 
+	// Process OWNERSHIP feature:
+	var notOwned, markers models.Records
+	if ownerID != "" {
+		notOwned, markers, err = findOwnership(domain, existing, desired, ownerID, ownerAdopt)
+		if err != nil {
+			return nil, nil, err
+		}
+		notOwned = withoutRecords(notOwned, ignorable, foreign, externalDNSIgnored)
+		if len(notOwned) != 0 {
+			msgs = append(msgs, fmt.Sprintf("%d records not being changed because they are not owned by %q%s", len(notOwned), ownerID, punct))
+			msgs = append(msgs, reportSkips(notOwned, !printer.SkinnyReport)...)
+		}
+	}
+
 	// Add the ignored/foreign items to the desired list so they are not deleted:
 	desired = append(desired, ignorable...)
 	desired = append(desired, foreign...)
 	desired = append(desired, externalDNSIgnored...)
+	desired = append(desired, notOwned...)
+	desired = append(desired, markers...)
 	return desired, msgs, nil
 }
 
+// withoutRecords returns the records of recs that are not in any of the
+// others.
+func withoutRecords(recs models.Records, others ...models.Records) models.Records {
+	seen := map[*models.RecordConfig]bool{}
+	for _, o := range others {
+		for _, rec := range o {
+			seen[rec] = true
+		}
+	}
+	var r models.Records
+	for _, rec := range recs {
+		if !seen[rec] {
+			r = append(r, rec)
+		}
+	}
+	return r
+}
+
+// HandsOff lists the existing records that DNSControl leaves alone, by the
+// feature that protects them.
+type HandsOff struct {
+	Ignored     models.Records // IGNORE*()
+	NoPurge     models.Records // NO_PURGE
+	ExternalDNS models.Records // IGNORE_EXTERNAL_DNS
+	NotOwned    models.Records // OWNERSHIP
+}
+
+// FindHandsOff returns the records in existing that the IGNORE*(),
+// NO_PURGE, IGNORE_EXTERNAL_DNS and OWNERSHIP features of dc protect from
+// deletion.
+// These are the records that handsoff() adds to the desired records.
+func FindHandsOff(existing models.Records, dc *models.DomainConfig) (*HandsOff, error) {
+	ignorable, foreign, err := processIgnoreAndNoPurge(dc.Name, existing, dc.Records, dc.EnsureAbsent, dc.Unmanaged, dc.KeepUnknown)
+	if err != nil {
+		return nil, err
+	}
+	ho := &HandsOff{Ignored: ignorable, NoPurge: foreign}
+	if dc.IgnoreExternalDNS {
+		ext := GetExternalDNSIgnoredRecords(existing, dc.Name, dc.ExternalDNSPrefix)
+		ho.ExternalDNS = filterOutConflicts(ext, findExternalDNSConflicts(dc.Records, ext))
+	}
+	if dc.OwnerID != "" {
+		notOwned, _, err := findOwnership(dc.Name, existing, dc.Records, dc.OwnerID, dc.OwnerAdopt)
+		if err != nil {
+			return nil, err
+		}
+		ho.NotOwned = withoutRecords(notOwned, ho.Ignored, ho.NoPurge, ho.ExternalDNS)
+	}
+	return ho, nil
+}
+
 // reportSkips reports records being skipped, if !full only the first
 // printer.MaxReport are output.
 func reportSkips(recs models.Records, full bool) []string {
@@ -275,6 +275,8 @@ func Test_ignore_external_dns(t *testing.T) {
 		false, // noPurge
 		true,  // ignoreExternalDNS
 		"",    // externalDNSPrefix (empty = default)
+		"",    // ownerID
+		false, // ownerAdopt
 	)
 	if err != nil {
 		t.Fatal(err)
@@ -361,6 +363,8 @@ func Test_ignore_external_dns_custom_prefix(t *testing.T) {
 		false,     // noPurge
 		true,      // ignoreExternalDNS
 		"extdns-", // externalDNSPrefix
+		"",        // ownerID
+		false,     // ownerAdopt
 	)
 	if err != nil {
 		t.Fatal(err)
@@ -413,6 +417,8 @@ func Test_ignore_external_dns_conflict(t *testing.T) {
 		false, // noPurge
 		true,  // ignoreExternalDNS
 		"",    // externalDNSPrefix
+		"",    // ownerID
+		false, // ownerAdopt
 	)
 	if err != nil {
 		t.Fatal(err)
@@ -441,3 +447,36 @@ func Test_ignore_external_dns_conflict(t *testing.T) {
 		t.Errorf("Expected exactly 1 myapp A record in result, got %d", myappCount)
 	}
 }
+
+func Test_FindHandsOff(t *testing.T) {
+	domain := "f.com"
+
+	existing := models.Records{
+		makeTestRecord("a-myapp", "TXT", "heritage=external-dns,external-dns/owner=k8s-cluster", domain),
+		makeTestRecord("myapp", "A", "10.0.0.1", domain),
+		makeTestRecord("ign", "A", "10.0.0.2", domain),
+		makeTestRecord("foreign", "A", "10.0.0.3", domain),
+		makeTestRecord("www", "A", "10.0.0.4", domain),
+	}
+	dc := &models.DomainConfig{
+		Name:              domain,
+		Records:           models.Records{makeTestRecord("www", "A", "10.0.0.5", domain)},
+		Unmanaged:         []*models.UnmanagedConfig{{LabelPattern: "ign"}},
+		KeepUnknown:       true,
+		IgnoreExternalDNS: true,
+	}
+
+	ho, err := FindHandsOff(existing, dc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := showRecs(ho.Ignored); got != "ign A 10.0.0.2\n" {
+		t.Errorf("Ignored: got %q", got)
+	}
+	if got := showRecs(ho.ExternalDNS); !strings.Contains(got, "myapp A 10.0.0.1") {
+		t.Errorf("ExternalDNS: got %q", got)
+	}
+	if got := showRecs(ho.NoPurge); !strings.Contains(got, "foreign A 10.0.0.3") || strings.Contains(got, "www") {
+		t.Errorf("NoPurge: got %q", got)
+	}
+}
@@ -0,0 +1,217 @@
+package diff2
+
+// This file implements the OWNERSHIP feature that lets several dnsconfig.js
+// files (for example, one per team) manage the same zone.
+//
+// For each RecordKey (label and type) that a D() with OWNERSHIP(ownerID)
+// manages, DNSControl maintains a TXT record, the marker:
+//   "heritage=dnscontrol,dnscontrol/owner=<owner-id>"
+//
+// The marker's label is the prefix, the record type and the leftmost label
+// of the record:
+// - "www" A: "_dnscontrol-a-www"
+// - "www.sub" CNAME: "_dnscontrol-cname-www.sub"
+// - "*.dyn" A: "_dnscontrol-a-_wildcard.dyn"
+// - "@" MX: "_dnscontrol-mx"
+//
+// The marker is a sibling (not a child) of the record so that it is not
+// hidden by a delegation (NS) or a DNAME at the record's label.
+//
+// Existing records whose RecordKey has no marker, or a marker of another
+// owner, are not modified or deleted.
+//
+// The SOA record and the NS records at the apex belong to the zone, not to
+// an owner: every D() of the zone has them. They are processed as usual.
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+const (
+	// ownershipHeritage is the start of the content of an ownership marker.
+	ownershipHeritage = "heritage=dnscontrol,"
+	// ownershipOwner precedes the owner ID in the content of an ownership marker.
+	ownershipOwner = "dnscontrol/owner="
+	// ownershipPrefix is the start of the label of an ownership marker.
+	ownershipPrefix = "_dnscontrol-"
+	// ownershipWildcard replaces "*" in the label of an ownership marker.
+	ownershipWildcard = "_wildcard"
+)
+
+// ownershipMarkerLabel returns the (short) label of the marker for the
+// records of type rtype at label.
+func ownershipMarkerLabel(label, rtype string) string {
+	prefix := ownershipPrefix + strings.ToLower(rtype)
+	if label == "@" {
+		return prefix
+	}
+	first, rest, _ := strings.Cut(label, ".")
+	if first == "*" {
+		first = ownershipWildcard
+	}
+	if rest != "" {
+		rest = "." + rest
+	}
+	return prefix + "-" + first + rest
+}
+
+// CheckOwnershipMarkers returns an error for each record of dc (a D() with
+// OWNERSHIP) whose marker would have an invalid name: the marker's first
+// label is longer than the record's, and DNS labels are limited to 63
+// octets (and names to 253).
+func CheckOwnershipMarkers(dc *models.DomainConfig) (errs []error) {
+	for _, rec := range dc.Records {
+		if ownershipExempt(rec, dc.Name) {
+			continue
+		}
+		label := ownershipMarkerLabel(rec.GetLabel(), rec.Type)
+		first, _, _ := strings.Cut(label, ".")
+		if len(first) > 63 {
+			errs = append(errs, fmt.Errorf("%s: OWNERSHIP: the label of the marker of %s %s (%q) is longer than 63 octets; use a shorter label", rec.FilePos, rec.Type, rec.GetLabelFQDN(), first))
+		} else if len(label)+1+len(dc.Name) > 253 {
+			errs = append(errs, fmt.Errorf("%s: OWNERSHIP: the name of the marker of %s %s is longer than 253 octets; use a shorter name", rec.FilePos, rec.Type, rec.GetLabelFQDN()))
+		}
+	}
+	return errs
+}
+
+// ownershipMarkerContent returns the content of the marker of ownerID.
+func ownershipMarkerContent(ownerID string) string {
+	return ownershipHeritage + ownershipOwner + ownerID
+}
+
+// parseOwnershipMarker returns the RecordKey (as "fqdn:TYPE") and the
+// owner ID of rec if rec is an ownership marker.
+func parseOwnershipMarker(rec *models.RecordConfig, domain string) (key, ownerID string, ok bool) {
+	if rec.Type != "TXT" {
+		return "", "", false
+	}
+	content := rec.GetTargetTXTJoined()
+	if !strings.HasPrefix(content, ownershipHeritage) {
+		return "", "", false
+	}
+	for field := range strings.SplitSeq(strings.TrimPrefix(content, ownershipHeritage), ",") {
+		if v, found := strings.CutPrefix(field, ownershipOwner); found {
+			ownerID = v
+		}
+	}
+	first, rest, _ := strings.Cut(rec.GetLabel(), ".")
+	typeAndLabel, found := strings.CutPrefix(first, ownershipPrefix)
+	if !found || ownerID == "" {
+		return "", "", false
+	}
+	rtype, label, hasLabel := strings.Cut(typeAndLabel, "-")
+	if !hasLabel {
+		// The marker of a record at the apex.
+		label = rest
+	} else {
+		if label == ownershipWildcard {
+			label = "*"
+		}
+		if rest != "" {
+			label += "." + rest
+		}
+	}
+	fqdn := domain
+	if label != "" && label != "@" {
+		fqdn = label + "." + domain
+	}
+	return ownershipKey(fqdn, rtype), ownerID, true
+}
+
+// ownershipKey returns the key of the records of type rtype at fqdn.
+func ownershipKey(fqdn, rtype string) string {
+	return strings.ToLower(fqdn) + ":" + strings.ToUpper(rtype)
+}
+
+// ownershipExempt returns true if rec belongs to the zone rather than to an
+// owner.
+func ownershipExempt(rec *models.RecordConfig, domain string) bool {
+	return rec.Type == "SOA" || (rec.Type == "NS" && strings.EqualFold(rec.NameFQDN, domain))
+}
+
+// isOwnershipMarker returns true if rec is an ownership marker.
+func isOwnershipMarker(rec *models.RecordConfig, domain string) bool {
+	_, _, ok := parseOwnershipMarker(rec, domain)
+	return ok
+}
+
+// findOwnership implements the OWNERSHIP feature. It returns the existing
+// records that are not owned by ownerID (and so must be kept as they are),
+// and the markers of the desired records.
+//
+// It is an error for desired to have records at a RecordKey that another
+// owner has records at. The same is true for existing records that have no
+// owner, unless adopt is true: then they become owned by ownerID.
+func findOwnership(domain string, existing, desired models.Records, ownerID string, adopt bool) (notOwned, markers models.Records, err error) {
+	// Who owns what:
+	owners := map[string]string{}
+	for _, rec := range existing {
+		if key, owner, ok := parseOwnershipMarker(rec, domain); ok {
+			owners[key] = owner
+		}
+	}
+
+	// The existing records, by RecordKey:
+	existingKeys := map[string]bool{}
+	for _, rec := range existing {
+		existingKeys[ownershipKey(rec.NameFQDN, rec.Type)] = true
+	}
+
+	// The RecordKeys that are desired must be ours (or no one's if adopt):
+	desiredKeys := map[string]bool{}
+	var conflicts []string
+	for _, rec := range desired {
+		if isOwnershipMarker(rec, domain) || ownershipExempt(rec, domain) {
+			continue
+		}
+		key := ownershipKey(rec.NameFQDN, rec.Type)
+		if desiredKeys[key] {
+			continue
+		}
+		desiredKeys[key] = true
+		markers = append(markers, ownershipMarker(rec, domain, ownerID))
+		owner, hasOwner := owners[key]
+		switch {
+		case hasOwner && owner != ownerID:
+			conflicts = append(conflicts, fmt.Sprintf("    %s %s is owned by %q", rec.GetLabelFQDN(), rec.Type, owner))
+		case !hasOwner && existingKeys[key] && !adopt:
+			conflicts = append(conflicts, fmt.Sprintf("    %s %s has no owner", rec.GetLabelFQDN(), rec.Type))
+		}
+	}
+	if len(conflicts) != 0 {
+		return nil, nil, fmt.Errorf("%d records can not be changed because they are not owned by %q:\n%s\n"+
+			"Remove them from this D(), or (for records that have no owner) use OWNERSHIP(%q, \"adopt\")",
+			len(conflicts), ownerID, strings.Join(conflicts, "\n"), ownerID)
+	}
+
+	// Keep everything that isn't ours:
+	for _, rec := range existing {
+		if _, owner, ok := parseOwnershipMarker(rec, domain); ok {
+			// Other owners' markers are kept. Ours are replaced by markers.
+			if owner != ownerID {
+				notOwned = append(notOwned, rec)
+			}
+			continue
+		}
+		if ownershipExempt(rec, domain) {
+			continue
+		}
+		key := ownershipKey(rec.NameFQDN, rec.Type)
+		if owners[key] != ownerID && !desiredKeys[key] {
+			notOwned = append(notOwned, rec)
+		}
+	}
+	return notOwned, markers, nil
+}
+
+// ownershipMarker returns the marker of the records at rec's RecordKey.
+func ownershipMarker(rec *models.RecordConfig, domain, ownerID string) *models.RecordConfig {
+	m := &models.RecordConfig{Type: "TXT", TTL: rec.TTL}
+	m.SetLabel(ownershipMarkerLabel(rec.GetLabel(), rec.Type), domain)
+	_ = m.SetTargetTXT(ownershipMarkerContent(ownerID))
+	return m
+}
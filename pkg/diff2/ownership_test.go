@@ -0,0 +1,147 @@
+package diff2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+func Test_ownershipMarkerLabel(t *testing.T) {
+	domain := "f.com"
+	for _, tc := range []struct {
+		label, rtype string
+		want         string
+	}{
+		{"@", "MX", "_dnscontrol-mx"},
+		{"www", "A", "_dnscontrol-a-www"},
+		{"www.sub", "CNAME", "_dnscontrol-cname-www.sub"},
+		{"*.dyn", "A", "_dnscontrol-a-_wildcard.dyn"},
+	} {
+		got := ownershipMarkerLabel(tc.label, tc.rtype)
+		if got != tc.want {
+			t.Errorf("ownershipMarkerLabel(%q, %q) = %q, want %q", tc.label, tc.rtype, got, tc.want)
+		}
+
+		// And back:
+		rec := makeTestRecord(tc.label, tc.rtype, "x", domain)
+		m := ownershipMarker(rec, domain, "team-a")
+		key, owner, ok := parseOwnershipMarker(m, domain)
+		if !ok || key != ownershipKey(rec.NameFQDN, rec.Type) || owner != "team-a" {
+			t.Errorf("parseOwnershipMarker(%q) = %q, %q, %v", m.GetLabel(), key, owner, ok)
+		}
+	}
+
+	if _, _, ok := parseOwnershipMarker(makeTestRecord("_dnscontrol-a-www", "TXT", "v=spf1 -all", domain), domain); ok {
+		t.Errorf("parseOwnershipMarker() of an ordinary TXT record = true")
+	}
+}
+
+func TestCheckOwnershipMarkers(t *testing.T) {
+	domain := "f.com"
+	dc := &models.DomainConfig{Name: domain, OwnerID: "team-a", Records: models.Records{
+		makeTestRecord("@", "NS", "ns1.f.com.", domain),
+		// "_dnscontrol-a-" + 49 octets is the longest valid marker label.
+		makeTestRecord(strings.Repeat("a", 49), "A", "1.2.3.4", domain),
+		makeTestRecord(strings.Repeat("b", 50)+".sub", "A", "1.2.3.4", domain),
+		makeTestRecord(strings.Repeat("c", 55), "CNAME", "www.f.com.", domain),
+	}}
+	errs := CheckOwnershipMarkers(dc)
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), "A "+strings.Repeat("b", 50)+".sub.f.com") {
+		t.Errorf("errs[0] = %v", errs[0])
+	}
+	if !strings.Contains(errs[1].Error(), "longer than 63 octets") {
+		t.Errorf("errs[1] = %v", errs[1])
+	}
+}
+
+func Test_findOwnership(t *testing.T) {
+	domain := "f.com"
+	existing := models.Records{
+		makeTestRecord("_dnscontrol-a-www", "TXT", "heritage=dnscontrol,dnscontrol/owner=team-a", domain),
+		makeTestRecord("www", "A", "1.2.3.4", domain),
+		makeTestRecord("_dnscontrol-a-old", "TXT", "heritage=dnscontrol,dnscontrol/owner=team-a", domain),
+		makeTestRecord("old", "A", "1.2.3.6", domain),
+		makeTestRecord("_dnscontrol-cname-api", "TXT", "heritage=dnscontrol,dnscontrol/owner=team-b", domain),
+		makeTestRecord("api", "CNAME", "www.f.com.", domain),
+		makeTestRecord("legacy", "A", "1.2.3.7", domain),
+		makeTestRecord("@", "NS", "ns1.f.com.", domain),
+	}
+	desired := models.Records{
+		makeTestRecord("@", "NS", "ns2.f.com.", domain),
+		makeTestRecord("www", "A", "1.2.3.5", domain),
+		makeTestRecord("new", "A", "1.2.3.8", domain),
+	}
+
+	names := func(recs models.Records) string {
+		var r []string
+		for _, rec := range recs {
+			r = append(r, rec.GetLabel()+" "+rec.Type)
+		}
+		return strings.Join(r, ", ")
+	}
+
+	notOwned, markers, err := findOwnership(domain, existing, desired, "team-a", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(notOwned), "_dnscontrol-cname-api TXT, api CNAME, legacy A"; got != want {
+		t.Errorf("notOwned = %s, want %s", got, want)
+	}
+	if got, want := names(markers), "_dnscontrol-a-www TXT, _dnscontrol-a-new TXT"; got != want {
+		t.Errorf("markers = %s, want %s", got, want)
+	}
+
+	// The records of others can't be changed:
+	desired = append(desired,
+		makeTestRecord("api", "CNAME", "new.f.com.", domain),
+		makeTestRecord("legacy", "A", "1.2.3.9", domain),
+	)
+	_, _, err = findOwnership(domain, existing, desired, "team-a", false)
+	if err == nil || !strings.Contains(err.Error(), `api.f.com CNAME is owned by "team-b"`) || !strings.Contains(err.Error(), "legacy.f.com A has no owner") {
+		t.Errorf("findOwnership() error = %v", err)
+	}
+
+	// Unless they have no owner and adopt is true:
+	_, _, err = findOwnership(domain, existing, desired, "team-a", true)
+	if err == nil || strings.Contains(err.Error(), "legacy") {
+		t.Errorf("findOwnership(adopt) error = %v", err)
+	}
+}
+
+func Test_ownershipChanges(t *testing.T) {
+	domain := "f.com"
+	existing := models.Records{
+		makeTestRecord("_dnscontrol-a-old", "TXT", "heritage=dnscontrol,dnscontrol/owner=team-a", domain),
+		makeTestRecord("old", "A", "1.2.3.6", domain),
+		makeTestRecord("_dnscontrol-a-other", "TXT", "heritage=dnscontrol,dnscontrol/owner=team-b", domain),
+		makeTestRecord("other", "A", "1.2.3.7", domain),
+	}
+	dc := &models.DomainConfig{
+		Name:    domain,
+		Records: models.Records{makeTestRecord("new", "A", "1.2.3.8", domain)},
+		OwnerID: "team-a",
+	}
+	changes, _, err := ByRecord(existing, dc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		if c.Type != REPORT {
+			got = append(got, c.Type.String()+" "+c.Key.NameFQDN+" "+c.Key.Type)
+		}
+	}
+	want := []string{
+		"CREATE _dnscontrol-a-new.f.com TXT",
+		"DELETE _dnscontrol-a-old.f.com TXT",
+		"CREATE new.f.com A",
+		"DELETE old.f.com A",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ByRecord():\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,105 @@
+package dnssort
+
+import "github.com/DNSControl/dnscontrol/v4/pkg/dnsgraph"
+
+// GroupSortResult is the result of SortGroupsUsingGraph.
+type GroupSortResult struct {
+	// Order is the indexes of the groups, in the order they should be processed.
+	Order []int
+	// Unresolved is the indexes of the groups that depend on each other (or
+	// on such groups). They are at the end of Order, in their original order.
+	Unresolved []int
+}
+
+// SortGroupsUsingGraph orders groups of changes (for example, the changes
+// to each zone) so that, across groups, the targets of a record are created
+// before the records that point to them, and deleted after the records that
+// pointed to them are changed. SortUsingGraph does the same within a group.
+//
+// The graph of all the changes is built, and each edge between two changes
+// of different groups becomes an edge between the groups. The groups are
+// then sorted topologically. Groups that don't depend on each other keep
+// their original order.
+func SortGroupsUsingGraph[T dnsgraph.Graphable](groups [][]T) GroupSortResult {
+	type member struct {
+		data  T
+		group int
+	}
+	var all []member
+	for i, g := range groups {
+		for _, data := range g {
+			if data.GetType() == dnsgraph.Change {
+				all = append(all, member{data: data, group: i})
+			}
+		}
+	}
+	datas := make([]T, len(all))
+	for i, m := range all {
+		datas[i] = m.data
+	}
+	graph := dnsgraph.CreateGraph(datas)
+	groupOf := map[*dnsgraph.Node[T]]int{}
+	for i, node := range graph.All {
+		groupOf[node] = all[i].group
+	}
+
+	// after[g] is the set of groups that must be processed before g.
+	after := make([]map[int]bool, len(groups))
+	for i := range after {
+		after[i] = map[int]bool{}
+	}
+	for _, node := range graph.All {
+		for _, edge := range node.Edges {
+			if edge.Direction != dnsgraph.OutgoingEdge {
+				continue
+			}
+			src, dst := groupOf[node], groupOf[edge.Node]
+			if src == dst {
+				continue
+			}
+			switch edge.Dependency.Type {
+			case dnsgraph.ForwardDependency:
+				// The new state of node needs edge.Node: do edge.Node first.
+				after[src][dst] = true
+			case dnsgraph.BackwardDependency:
+				// The old state of node needed edge.Node: do node first.
+				after[dst][src] = true
+			}
+		}
+	}
+
+	var result GroupSortResult
+	done := make([]bool, len(groups))
+	for len(result.Order) < len(groups) {
+		next := -1
+		for g := range groups {
+			if done[g] {
+				continue
+			}
+			ready := true
+			for dep := range after[g] {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = g
+				break
+			}
+		}
+		if next == -1 {
+			// A cycle. Keep the original order of the rest.
+			for g := range groups {
+				if !done[g] {
+					result.Order = append(result.Order, g)
+					result.Unresolved = append(result.Unresolved, g)
+				}
+			}
+			break
+		}
+		done[next] = true
+		result.Order = append(result.Order, next)
+	}
+	return result
+}
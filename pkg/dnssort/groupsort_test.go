@@ -0,0 +1,90 @@
+package dnssort_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/pkg/dnsgraph"
+	"github.com/DNSControl/dnscontrol/v4/pkg/dnsgraph/testutils"
+	"github.com/DNSControl/dnscontrol/v4/pkg/dnssort"
+)
+
+func forward(name string) []dnsgraph.Dependency {
+	return []dnsgraph.Dependency{{Type: dnsgraph.ForwardDependency, NameFQDN: name}}
+}
+
+func backward(name string) []dnsgraph.Dependency {
+	return []dnsgraph.Dependency{{Type: dnsgraph.BackwardDependency, NameFQDN: name}}
+}
+
+func Test_SortGroupsUsingGraph(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		groups         [][]testutils.StubRecord
+		wantOrder      []int
+		wantUnresolved []int
+	}{
+		{
+			name: "independent groups keep their order",
+			groups: [][]testutils.StubRecord{
+				{{NameFQDN: "www.a.com"}},
+				{{NameFQDN: "www.b.com"}},
+			},
+			wantOrder: []int{0, 1},
+		},
+		{
+			name: "creation of the target first",
+			groups: [][]testutils.StubRecord{
+				{{NameFQDN: "www.a.com", Dependencies: forward("new.b.com")}},
+				{{NameFQDN: "new.b.com"}},
+			},
+			wantOrder: []int{1, 0},
+		},
+		{
+			name: "deletion of the target last",
+			groups: [][]testutils.StubRecord{
+				{{NameFQDN: "old.b.com"}, {NameFQDN: "x.b.com"}},
+				{{NameFQDN: "www.a.com", Dependencies: backward("old.b.com")}},
+			},
+			wantOrder: []int{1, 0},
+		},
+		{
+			name: "reports are ignored",
+			groups: [][]testutils.StubRecord{
+				{{NameFQDN: "www.a.com", Dependencies: forward("b.com")}},
+				{{NameFQDN: "b.com", Type: dnsgraph.Report}},
+			},
+			wantOrder: []int{0, 1},
+		},
+		{
+			name: "chain",
+			groups: [][]testutils.StubRecord{
+				{{NameFQDN: "www.a.com", Dependencies: forward("www.b.com")}},
+				{{NameFQDN: "www.b.com", Dependencies: forward("www.c.com")}},
+				{{NameFQDN: "www.c.com"}},
+			},
+			wantOrder: []int{2, 1, 0},
+		},
+		{
+			name: "cycle",
+			groups: [][]testutils.StubRecord{
+				{{NameFQDN: "www.d.com"}},
+				{{NameFQDN: "x.a.com", Dependencies: forward("y.b.com")}},
+				{{NameFQDN: "y.b.com", Dependencies: forward("x.a.com")}},
+			},
+			wantOrder:      []int{0, 1, 2},
+			wantUnresolved: []int{1, 2},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			groups := make([][]dnsgraph.Graphable, len(tc.groups))
+			for i, g := range tc.groups {
+				groups[i] = testutils.StubRecordsAsGraphable(g)
+			}
+			got := dnssort.SortGroupsUsingGraph(groups)
+			if !reflect.DeepEqual(got.Order, tc.wantOrder) || !reflect.DeepEqual(got.Unresolved, tc.wantUnresolved) {
+				t.Errorf("SortGroupsUsingGraph() = %v, %v; want %v, %v", got.Order, got.Unresolved, tc.wantOrder, tc.wantUnresolved)
+			}
+		})
+	}
+}
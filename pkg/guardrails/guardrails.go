@@ -0,0 +1,133 @@
+// Package guardrails implements limits on the size of the changes that push
+// is permitted to make (MAX_CHANGES, MAX_DELETES, --max-changes, etc.).
+package guardrails
+
+import (
+	"fmt"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+)
+
+// Weights controls how much some deletions count towards the limits.
+type Weights struct {
+	ApexNSSOA int // Deleting an NS or SOA record at the apex.
+	RecordSet int // Deleting a record as part of deleting all records of a type at a label.
+}
+
+// DefaultWeights are the weights used if none are specified.
+var DefaultWeights = Weights{ApexNSSOA: 10, RecordSet: 1}
+
+// Counts is the size of a list of changes.
+type Counts struct {
+	Creates  int
+	Modifies int
+	Deletes  int // Weighted.
+}
+
+// Changes returns the total (weighted) number of changes.
+func (c Counts) Changes() int {
+	return c.Creates + c.Modifies + c.Deletes
+}
+
+// Add adds o to c.
+func (c *Counts) Add(o Counts) {
+	c.Creates += o.Creates
+	c.Modifies += o.Modifies
+	c.Deletes += o.Deletes
+}
+
+// Count returns the size of changes, a diff2.ChangeList grouped by RecordSet
+// (diff2.ByRecordSet). Within a CHANGE, a record that is removed and a record
+// that is added are counted as a modification.
+func Count(changes diff2.ChangeList, w Weights) Counts {
+	var c Counts
+	for _, ch := range changes {
+		switch ch.Type {
+		case diff2.CREATE:
+			c.Creates += len(ch.New)
+		case diff2.DELETE:
+			for _, rec := range ch.Old {
+				c.Deletes += weight(rec, w.RecordSet, w)
+			}
+		case diff2.CHANGE:
+			removed, added := setDifference(ch.Old, ch.New), setDifference(ch.New, ch.Old)
+			paired := min(len(removed), len(added))
+			c.Modifies += paired + ttlChanges(ch.Old, ch.New)
+			c.Creates += len(added) - paired
+			for _, rec := range removed[paired:] {
+				c.Deletes += weight(rec, 1, w)
+			}
+		}
+	}
+	return c
+}
+
+// weight returns how much the deletion of rec counts.
+func weight(rec *models.RecordConfig, dflt int, w Weights) int {
+	if (rec.Type == "NS" || rec.Type == "SOA") && rec.GetLabel() == "@" {
+		return max(w.ApexNSSOA, dflt)
+	}
+	return dflt
+}
+
+// setDifference returns the records in a that are not in b (ignoring TTLs).
+func setDifference(a, b models.Records) models.Records {
+	inB := map[string]bool{}
+	for _, rec := range b {
+		inB[rec.ToComparableNoTTL()] = true
+	}
+	var r models.Records
+	for _, rec := range a {
+		if !inB[rec.ToComparableNoTTL()] {
+			r = append(r, rec)
+		}
+	}
+	return r
+}
+
+// ttlChanges returns the number of records that are in both a and b but
+// with a different TTL.
+func ttlChanges(a, b models.Records) int {
+	ttls := map[string]uint32{}
+	for _, rec := range a {
+		ttls[rec.ToComparableNoTTL()] = rec.TTL
+	}
+	n := 0
+	for _, rec := range b {
+		if ttl, ok := ttls[rec.ToComparableNoTTL()]; ok && ttl != rec.TTL {
+			n++
+		}
+	}
+	return n
+}
+
+// Limits are the maximum number of changes permitted. Zero means no limit.
+type Limits struct {
+	MaxChanges int
+	MaxDeletes int
+}
+
+// ForZone returns the limits for dc. The limits set in dnsconfig.js
+// (MAX_CHANGES, MAX_DELETES) override l.
+func (l Limits) ForZone(dc *models.DomainConfig) Limits {
+	if dc.MaxChanges != 0 {
+		l.MaxChanges = dc.MaxChanges
+	}
+	if dc.MaxDeletes != 0 {
+		l.MaxDeletes = dc.MaxDeletes
+	}
+	return l
+}
+
+// Check returns an error if c exceeds the limits. what describes the
+// changes (for example, the zone and provider).
+func (l Limits) Check(what string, c Counts) error {
+	if l.MaxChanges != 0 && c.Changes() > l.MaxChanges {
+		return fmt.Errorf("%s: %d changes exceeds the limit of %d (%d creates, %d modifies, %d deletes)", what, c.Changes(), l.MaxChanges, c.Creates, c.Modifies, c.Deletes)
+	}
+	if l.MaxDeletes != 0 && c.Deletes > l.MaxDeletes {
+		return fmt.Errorf("%s: %d deletes exceeds the limit of %d", what, c.Deletes, l.MaxDeletes)
+	}
+	return nil
+}
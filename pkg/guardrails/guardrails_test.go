@@ -0,0 +1,94 @@
+package guardrails
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+)
+
+func makeRec(label, rtype, content string, ttl uint32) *models.RecordConfig {
+	r := &models.RecordConfig{TTL: ttl}
+	r.SetLabel(label, "example.com")
+	if err := r.PopulateFromString(rtype, content, "example.com"); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func TestCount(t *testing.T) {
+	existing := models.Records{
+		makeRec("@", "NS", "ns1.example.net.", 300),
+		makeRec("@", "NS", "ns2.example.net.", 300),
+		makeRec("www", "A", "1.1.1.1", 300),
+		makeRec("www", "A", "1.1.1.2", 300),
+		makeRec("ttl", "A", "1.1.1.3", 300),
+		makeRec("gone", "TXT", "bye", 300),
+		makeRec("gone", "TXT", "bye2", 300),
+	}
+	desired := models.Records{
+		makeRec("@", "NS", "ns1.example.net.", 300),
+		makeRec("www", "A", "1.1.1.9", 300),
+		makeRec("ttl", "A", "1.1.1.3", 600),
+		makeRec("new", "A", "1.1.1.4", 300),
+	}
+	changes, _, err := diff2.ByRecordSet(existing, &models.DomainConfig{Name: "example.com", Records: desired}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		w    Weights
+		want Counts
+	}{
+		// www: 1 modify + 1 delete; ttl: 1 modify; new: 1 create;
+		// gone: 2 recordset deletes; apex NS: 1 delete.
+		{"unweighted", Weights{ApexNSSOA: 1, RecordSet: 1}, Counts{Creates: 1, Modifies: 2, Deletes: 4}},
+		{"weighted", Weights{ApexNSSOA: 10, RecordSet: 3}, Counts{Creates: 1, Modifies: 2, Deletes: 1 + 6 + 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Count(changes, tt.w); got != tt.want {
+				t.Errorf("Count() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := Counts{Creates: 2, Modifies: 3, Deletes: 5}
+	tests := []struct {
+		name    string
+		limits  Limits
+		wantErr string
+	}{
+		{"no limits", Limits{}, ""},
+		{"within", Limits{MaxChanges: 10, MaxDeletes: 5}, ""},
+		{"changes", Limits{MaxChanges: 9}, "10 changes exceeds the limit of 9"},
+		{"deletes", Limits{MaxDeletes: 4}, "5 deletes exceeds the limit of 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Check("example.com", c)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestForZone(t *testing.T) {
+	l := Limits{MaxChanges: 100, MaxDeletes: 10}
+	got := l.ForZone(&models.DomainConfig{MaxDeletes: 3})
+	if got != (Limits{MaxChanges: 100, MaxDeletes: 3}) {
+		t.Errorf("ForZone() = %+v", got)
+	}
+}
@@ -0,0 +1,323 @@
+package js
+
+// The tests of dnsconfig.js (the *_test.js files that "dnscontrol test"
+// runs) are run by goja, after dnsconfig.js, against the configuration it
+// produced (once validated and normalized). They can use:
+//
+//	test(name, fn)                                  // A test made of the expectations of fn.
+//	expectRecord(domain, name, type[, target])      // The record exists.
+//	expectNoRecord(domain, name[, type[, target]])  // The record doesn't exist.
+//	expectTTL(domain, name, type, ttl)              // The records have this TTL.
+//	expect(condition, message)                      // condition is true.
+//	getRecords(domain)                              // The records of the domain.
+//
+// Each expectation outside of test() is a test of its own.
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/dop251/goja"
+)
+
+// TestResult is the result of a test of a *_test.js file.
+type TestResult struct {
+	Name     string   // The name given to test(), or what the expectation expects.
+	Position string   // Where the test is ("file:line:column").
+	Failures []string // Why the test failed. Empty if it passed.
+}
+
+// Failed returns true if the test failed.
+func (t *TestResult) Failed() bool {
+	return len(t.Failures) != 0
+}
+
+// configTester runs the tests of a file.
+type configTester struct {
+	r       *gojaRunner
+	conf    *models.DNSConfig
+	results []*TestResult
+	current *TestResult // The test() being run, if any.
+}
+
+// RunTests runs the tests of the file (a *_test.js or *_test.ts file)
+// against conf, the configuration of dnsconfig.js once validated and
+// normalized. An error that isn't in a test (a syntax error, an exception
+// outside of test(), ...) is reported as a failed test.
+func RunTests(file string, conf *models.DNSConfig, variables map[string]string) ([]*TestResult, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newGojaRunner(variables)
+	if err != nil {
+		return nil, err
+	}
+	t := &configTester{r: r, conf: conf}
+	for name, fn := range map[string]func(goja.FunctionCall) goja.Value{
+		"test":           t.test,
+		"expectRecord":   t.expectRecord,
+		"expectNoRecord": t.expectNoRecord,
+		"expectTTL":      t.expectTTL,
+		"expect":         t.expect,
+		"getRecords":     t.getRecords,
+	} {
+		if err := r.vm.Set(name, fn); err != nil {
+			return nil, err
+		}
+	}
+
+	path := filepath.Clean(file)
+	name := positionName(path)
+	currentDirectoryOld := currentDirectory
+	currentDirectory = filepath.Dir(path)
+	defer func() { currentDirectory = currentDirectoryOld }()
+
+	if _, err := r.runFile(path, name, string(src)); err != nil {
+		t.fail(name, name, r.error(err).Error())
+	} else if err := r.runLoop(); err != nil {
+		t.fail(name, name, r.error(err).Error())
+	}
+	return t.results, nil
+}
+
+// position returns the position of the code of the test file that is
+// running.
+func (t *configTester) position() string {
+	for _, f := range t.r.vm.CaptureCallStack(0, nil) {
+		if pos, ok := t.r.position(f); ok {
+			return pos
+		}
+	}
+	return ""
+}
+
+// fail records a test that failed outside of test().
+func (t *configTester) fail(name, pos, failure string) {
+	t.results = append(t.results, &TestResult{Name: name, Position: pos, Failures: []string{failure}})
+}
+
+// check records the result of an expectation: a failure of the test()
+// being run, or a test of its own.
+func (t *configTester) check(name string, failure string) goja.Value {
+	if t.current != nil {
+		if failure != "" {
+			t.current.Failures = append(t.current.Failures, fmt.Sprintf("%s: %s (at %s)", name, failure, t.position()))
+		}
+		return t.r.vm.ToValue(failure == "")
+	}
+	result := &TestResult{Name: name, Position: t.position()}
+	if failure != "" {
+		result.Failures = []string{failure}
+	}
+	t.results = append(t.results, result)
+	return t.r.vm.ToValue(failure == "")
+}
+
+func (t *configTester) test(call goja.FunctionCall) goja.Value {
+	name := call.Argument(0).String()
+	fn, ok := goja.AssertFunction(call.Argument(1))
+	if !ok {
+		t.r.throw("test(name, fn): fn must be a function")
+	}
+	if t.current != nil {
+		t.r.throw("test() can't be called in a test")
+	}
+	t.current = &TestResult{Name: name, Position: t.position()}
+	defer func() {
+		t.results = append(t.results, t.current)
+		t.current = nil
+	}()
+	if _, err := fn(goja.Undefined()); err != nil {
+		t.current.Failures = append(t.current.Failures, t.r.error(err).Error())
+	}
+	return goja.Undefined()
+}
+
+func (t *configTester) expectRecord(call goja.FunctionCall) goja.Value {
+	domain, name, rtype, target := t.recordArgs("expectRecord", call, 3)
+	dc := t.domain(domain)
+	desc := describeRecord(domain, name, rtype, target)
+	if dc == nil {
+		return t.check(desc, "no such domain")
+	}
+	if len(matchingRecords(dc, name, rtype, target)) != 0 {
+		return t.check(desc, "")
+	}
+	var others []string
+	for _, rc := range matchingRecords(dc, name, "", "") {
+		others = append(others, rc.Type+" "+rc.GetTargetCombined())
+	}
+	if len(others) == 0 {
+		return t.check(desc, "no such record")
+	}
+	return t.check(desc, "no such record; "+name+" has "+strings.Join(others, ", "))
+}
+
+func (t *configTester) expectNoRecord(call goja.FunctionCall) goja.Value {
+	domain, name, rtype, target := t.recordArgs("expectNoRecord", call, 2)
+	dc := t.domain(domain)
+	desc := "no " + describeRecord(domain, name, rtype, target)
+	if dc == nil {
+		return t.check(desc, "no such domain")
+	}
+	var found []string
+	for _, rc := range matchingRecords(dc, name, rtype, target) {
+		found = append(found, fmt.Sprintf("%s %s %s", rc.Type, rc.GetTargetCombined(), rc.FilePos))
+	}
+	if len(found) != 0 {
+		return t.check(desc, "found "+strings.Join(found, ", "))
+	}
+	return t.check(desc, "")
+}
+
+func (t *configTester) expectTTL(call goja.FunctionCall) goja.Value {
+	domain, name, rtype, _ := t.recordArgs("expectTTL", call, 3)
+	ttl, err := parseTTL(call.Argument(3).Export())
+	if err != nil {
+		t.r.throw("expectTTL: " + err.Error())
+	}
+	dc := t.domain(domain)
+	desc := fmt.Sprintf("%s TTL %d", describeRecord(domain, name, rtype, ""), ttl)
+	if dc == nil {
+		return t.check(desc, "no such domain")
+	}
+	records := matchingRecords(dc, name, rtype, "")
+	if len(records) == 0 {
+		return t.check(desc, "no such record")
+	}
+	var wrong []string
+	for _, rc := range records {
+		if rc.TTL != ttl {
+			wrong = append(wrong, fmt.Sprintf("%s %s has TTL %d", rc.Type, rc.GetTargetCombined(), rc.TTL))
+		}
+	}
+	return t.check(desc, strings.Join(wrong, ", "))
+}
+
+func (t *configTester) expect(call goja.FunctionCall) goja.Value {
+	message := "expect()"
+	if arg := call.Argument(1); !goja.IsUndefined(arg) {
+		message = arg.String()
+	}
+	if call.Argument(0).ToBoolean() {
+		return t.check(message, "")
+	}
+	return t.check(message, "is false")
+}
+
+func (t *configTester) getRecords(call goja.FunctionCall) goja.Value {
+	dc := t.domain(call.Argument(0).String())
+	if dc == nil {
+		t.r.throw("getRecords: no such domain: " + call.Argument(0).String())
+	}
+	records := make([]any, len(dc.Records))
+	for i, rc := range dc.Records {
+		meta := map[string]any{}
+		for k, v := range rc.Metadata {
+			meta[k] = v
+		}
+		records[i] = map[string]any{
+			"name":    rc.GetLabel(),
+			"fqdn":    rc.GetLabelFQDN(),
+			"type":    rc.Type,
+			"target":  rc.GetTargetField(),
+			"value":   rc.GetTargetCombined(),
+			"ttl":     rc.TTL,
+			"meta":    meta,
+			"filepos": rc.FilePos,
+		}
+	}
+	return t.r.vm.ToValue(records)
+}
+
+// recordArgs returns the arguments (domain, name[, type[, target]]) of the
+// function fn, which requires the first required of them.
+func (t *configTester) recordArgs(fn string, call goja.FunctionCall, required int) (domain, name, rtype, target string) {
+	if len(call.Arguments) < required {
+		t.r.throw(fmt.Sprintf("%s requires at least %d arguments", fn, required))
+	}
+	args := make([]string, 4)
+	for i := range args {
+		if arg := call.Argument(i); !goja.IsUndefined(arg) && !goja.IsNull(arg) {
+			args[i] = arg.String()
+		}
+	}
+	return args[0], args[1], args[2], args[3]
+}
+
+// domain returns the domain named name (with its tag, if any), or nil.
+func (t *configTester) domain(name string) *models.DomainConfig {
+	for _, dc := range t.conf.Domains {
+		if dc.Name == name || dc.GetUniqueName() == name {
+			return dc
+		}
+	}
+	return nil
+}
+
+// matchingRecords returns the records of dc named name (a label, "@" or a
+// FQDN), of type rtype and whose target is target ("" for any type or
+// target). The target is the target field (with or without the final dot)
+// or the whole value ("10 mx.example.com." for an MX record).
+func matchingRecords(dc *models.DomainConfig, name, rtype, target string) []*models.RecordConfig {
+	fqdn := strings.TrimSuffix(name, ".")
+	var records []*models.RecordConfig
+	for _, rc := range dc.Records {
+		if rc.GetLabel() != name && !strings.EqualFold(rc.GetLabelFQDN(), fqdn) {
+			continue
+		}
+		if rtype != "" && !strings.EqualFold(rc.Type, rtype) {
+			continue
+		}
+		if target != "" {
+			field := rc.GetTargetField()
+			if field != target && field != target+"." && rc.GetTargetCombined() != target {
+				continue
+			}
+		}
+		records = append(records, rc)
+	}
+	return records
+}
+
+// describeRecord returns a description of the record of an expectation.
+func describeRecord(domain, name, rtype, target string) string {
+	return strings.Join(strings.Fields(strings.Join([]string{domain + ":", name, rtype, target}, " ")), " ")
+}
+
+var ttlRegexp = regexp.MustCompile(`^(\d+)([smhdwny]?)$`)
+
+// parseTTL returns the TTL v: a number of seconds, or a string like the
+// ones TTL() accepts ("300", "5m", "1h", "1d", ...).
+func parseTTL(v any) (uint32, error) {
+	switch v := v.(type) {
+	case int64:
+		if v >= 0 && v <= 1<<32-1 {
+			return uint32(v), nil
+		}
+	case float64:
+		if v >= 0 && v <= 1<<32-1 && v == float64(uint32(v)) {
+			return uint32(v), nil
+		}
+	case string:
+		m := ttlRegexp.FindStringSubmatch(v)
+		if m == nil {
+			break
+		}
+		n, err := strconv.ParseUint(m[1], 10, 32)
+		if err != nil {
+			break
+		}
+		units := map[string]uint64{"": 1, "s": 1, "m": 60, "h": 3600, "d": 86400, "w": 7 * 86400, "n": 30 * 86400, "y": 365 * 86400}
+		if ttl := n * units[m[2]]; ttl <= 1<<32-1 {
+			return uint32(ttl), nil
+		}
+	}
+	return 0, fmt.Errorf("%v is not a valid TTL", v)
+}
@@ -0,0 +1,125 @@
+package js
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/pkg/normalize"
+)
+
+func TestRunTests(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dnsconfig.js": `var REG = NewRegistrar("none");
+D("example.com", REG, DefaultTTL(600),
+    A("@", "192.0.2.1"),
+    A("www", "192.0.2.1", TTL("1h")),
+    MX("@", 10, "mail"),
+    CNAME("blog", "ghs.google.com."),
+);
+D("example.com!internal", REG, A("www", "10.0.0.1"));
+`,
+		"tests/records_test.js": `expectRecord("example.com", "www", "A", "192.0.2.1");
+expectRecord("example.com", "www.example.com.", "a");
+expectRecord("example.com", "@", "MX", "mail.example.com");
+expectRecord("example.com", "@", "MX", "10 mail.example.com.");
+expectRecord("example.com!internal", "www", "A", "10.0.0.1");
+expectNoRecord("example.com", "ftp");
+expectNoRecord("example.com", "www", "AAAA");
+expectRecord("example.com", "www", "A", "192.0.2.2");
+expectNoRecord("example.com", "blog");
+expectRecord("example.org", "www", "A");
+test("TTLs", function () {
+    expectTTL("example.com", "www", "A", 3600);
+    expectTTL("example.com", "www", "A", "1h");
+    expectTTL("example.com", "@", "A", 600);
+});
+test("failures", () => {
+    expectTTL("example.com", "@", "A", "5m");
+    expect(getRecords("example.com").length === 4, "4 records");
+    expect(false, "false");
+});
+test("exception", () => {
+    expect(true, "true");
+    null.x;
+});
+`,
+		"tests/error_test.js": `expect(true, "true");
+throw new Error("oops");
+`,
+	})
+	setEngine(t, EngineGoja)
+	conf, err := ExecuteJavaScript(filepath.Join(dir, "dnsconfig.js"), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := normalize.ValidateAndNormalizeConfig(conf); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	results, err := RunTests(filepath.Join(dir, "tests/records_test.js"), conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, fmt.Sprintf("%s %s %q", r.Name, r.Position, r.Failures))
+	}
+	want := []string{
+		`example.com: www A 192.0.2.1 tests/records_test.js:1:1 []`,
+		`example.com: www.example.com. a tests/records_test.js:2:1 []`,
+		`example.com: @ MX mail.example.com tests/records_test.js:3:1 []`,
+		`example.com: @ MX 10 mail.example.com. tests/records_test.js:4:1 []`,
+		`example.com!internal: www A 10.0.0.1 tests/records_test.js:5:1 []`,
+		`no example.com: ftp tests/records_test.js:6:1 []`,
+		`no example.com: www AAAA tests/records_test.js:7:1 []`,
+		`example.com: www A 192.0.2.2 tests/records_test.js:8:1 ["no such record; www has A 192.0.2.1"]`,
+		`no example.com: blog tests/records_test.js:9:1 ["found CNAME ghs.google.com. [dnsconfig.js:6:5]"]`,
+		`example.org: www A tests/records_test.js:10:1 ["no such domain"]`,
+		`TTLs tests/records_test.js:11:1 []`,
+		`failures tests/records_test.js:16:1 ["example.com: @ A TTL 300: A 192.0.2.1 has TTL 600 (at tests/records_test.js:17:5)" "false: is false (at tests/records_test.js:19:5)"]`,
+		`exception tests/records_test.js:21:1 ["TypeError: Cannot read property 'x' of undefined at tests/records_test.js:23:10"]`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d: %q", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("result %d: got %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	results, err = RunTests(filepath.Join(dir, "tests/error_test.js"), conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Failed() || !results[1].Failed() {
+		t.Fatalf("got %+v, want a success and a failure", results)
+	}
+	if want := "Error: oops at tests/error_test.js:2:7"; results[1].Failures[0] != want {
+		t.Errorf("got %q, want %q", results[1].Failures[0], want)
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	for _, tt := range []struct {
+		v    any
+		want uint32
+		ok   bool
+	}{
+		{int64(300), 300, true},
+		{float64(60), 60, true},
+		{"300", 300, true},
+		{"5m", 300, true},
+		{"1d", 86400, true},
+		{float64(1.5), 0, false},
+		{int64(-1), 0, false},
+		{"5x", 0, false},
+		{true, 0, false},
+	} {
+		got, err := parseTTL(tt.v)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseTTL(%v) = %d, %v; want %d", tt.v, got, err, tt.want)
+		}
+	}
+}
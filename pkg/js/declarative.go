@@ -0,0 +1,304 @@
+package js
+
+// A declarative configuration is a YAML, TOML or JSON document that lists
+// registrars, DNS providers, domains and records. It is loaded by
+// LOAD_YAML() (in helpers.js), which turns it into calls to NewRegistrar(),
+// NewDnsProvider(), D(), A(), ... so that it means exactly what the
+// equivalent dnsconfig.js means. A declarative configuration given to
+// ExecuteJavaScript is run as if dnsconfig.js were LOAD_YAML("file").
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/yaml.v3"
+)
+
+// isDeclarativeFile returns true if file is a declarative configuration
+// (rather than JavaScript).
+func isDeclarativeFile(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml", ".toml", ".json":
+		return true
+	}
+	return false
+}
+
+// declarativeScript returns the JavaScript that loads the declarative
+// configuration file (in currentDirectory).
+func declarativeScript(file string) []byte {
+	name, _ := json.Marshal(file)
+	return []byte(fmt.Sprintf("LOAD_YAML(%s);\n", name))
+}
+
+// declarativeFile is a declarative configuration, as given to LOAD_YAML()
+// in helpers.js.
+type declarativeFile struct {
+	File   string         `json:"file"`   // The name of the file in positions.
+	Config map[string]any `json:"config"` // The content of the file.
+}
+
+// Exposes readDeclarative to Javascript, for LOAD_YAML().
+func readDeclarativeFunc(call otto.FunctionCall) otto.Value {
+	if len(call.ArgumentList) != 1 {
+		throw(call.Otto, "LOAD_YAML takes exactly one argument")
+	}
+	configs, err := readDeclarative(call.Argument(0).String())
+	if err != nil {
+		throw(call.Otto, err.Error())
+	}
+	v, _ := otto.ToValue(configs)
+	return v
+}
+
+// readDeclarative reads the declarative configuration files that match
+// the glob pattern, relative to currentDirectory, and returns them as a
+// JSON array of declarativeFile.
+func readDeclarative(pattern string) (string, error) {
+	path := filepath.Join(currentDirectory, pattern)
+	files := []string{path}
+	if _, err := os.Stat(path); err != nil {
+		if files, err = filepath.Glob(path); err != nil {
+			return "", fmt.Errorf("%s: %w", pattern, err)
+		}
+		if len(files) == 0 {
+			return "", fmt.Errorf("%s: no such file", pattern)
+		}
+		sort.Strings(files)
+	}
+
+	configs := make([]declarativeFile, 0, len(files))
+	for _, f := range files {
+		if !isDeclarativeFile(f) {
+			return "", fmt.Errorf("%s: not a YAML, TOML or JSON file", positionName(f))
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return "", err
+		}
+		name := positionName(f)
+		config, err := parseDeclarative(name, data)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		configs = append(configs, declarativeFile{File: name, Config: config})
+	}
+	out, err := json.Marshal(configs)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// parseDeclarative parses the declarative configuration data (whose format
+// depends on the extension of name), and sets the "filepos" of each record
+// to its position.
+func parseDeclarative(name string, data []byte) (map[string]any, error) {
+	var config map[string]any
+	var positions [][]string // [domain][record]
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		if len(doc.Content) == 0 {
+			return map[string]any{}, nil
+		}
+		yamlLiteralScalars(doc.Content[0])
+		if err := doc.Decode(&config); err != nil {
+			return nil, err
+		}
+		positions = yamlRecordPositions(name, doc.Content[0])
+	case ".toml":
+		if err := toml.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+		tomlLiteralDates(config)
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	// Set the positions of the records (or, if the format doesn't tell
+	// them, their paths in the document).
+	domains, _ := config["domains"].([]any)
+	for i, d := range domains {
+		domain, _ := d.(map[string]any)
+		records, _ := domain["records"].([]any)
+		for j, r := range records {
+			record, ok := r.(map[string]any)
+			if !ok {
+				continue
+			}
+			if i < len(positions) && j < len(positions[i]) {
+				record["filepos"] = positions[i][j]
+			} else {
+				record["filepos"] = fmt.Sprintf("%s:domains[%d].records[%d]", name, i, j)
+			}
+		}
+	}
+	if err := checkJSONValues(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// yamlRecordPositions returns the positions of the records of the domains
+// of the YAML document doc.
+func yamlRecordPositions(name string, doc *yaml.Node) [][]string {
+	domains := yamlMapValue(doc, "domains")
+	if domains == nil || domains.Kind != yaml.SequenceNode {
+		return nil
+	}
+	positions := make([][]string, len(domains.Content))
+	for i, d := range domains.Content {
+		records := yamlMapValue(d, "records")
+		if records == nil || records.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, r := range records.Content {
+			positions[i] = append(positions[i], fmt.Sprintf("%s:%d:%d", name, r.Line, r.Column))
+		}
+	}
+	return positions
+}
+
+// yamlLiteralScalars makes the names, targets and arguments of the records
+// of the YAML document doc strings, as written, rather than what YAML
+// resolves them to: a TXT target 2025-01-01 must not become a timestamp,
+// nor 1.10 the number 1.1. The arguments that are numbers written as Go
+// would format them (10 for the priority of an MX) stay numbers.
+func yamlLiteralScalars(doc *yaml.Node) {
+	domains := yamlMapValue(doc, "domains")
+	if domains == nil || domains.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, d := range domains.Content {
+		records := yamlMapValue(d, "records")
+		if records == nil || records.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, r := range records.Content {
+			for _, key := range []string{"name", "target"} {
+				if n := yamlMapValue(r, key); n != nil && n.Kind == yaml.ScalarNode {
+					n.Tag = "!!str"
+				}
+			}
+			args := yamlMapValue(r, "args")
+			if args == nil {
+				continue
+			}
+			items := []*yaml.Node{args}
+			if args.Kind == yaml.SequenceNode {
+				items = args.Content
+			}
+			for _, n := range items {
+				if n.Kind == yaml.ScalarNode && !yamlExactNumber(n) {
+					n.Tag = "!!str"
+				}
+			}
+		}
+	}
+}
+
+// yamlExactNumber returns true if the YAML scalar n is a number that is
+// written exactly as it is formatted.
+func yamlExactNumber(n *yaml.Node) bool {
+	switch n.ShortTag() {
+	case "!!int":
+		i, err := strconv.ParseInt(n.Value, 10, 64)
+		return err == nil && strconv.FormatInt(i, 10) == n.Value
+	case "!!float":
+		f, err := strconv.ParseFloat(n.Value, 64)
+		return err == nil && strconv.FormatFloat(f, 'f', -1, 64) == n.Value
+	}
+	return false
+}
+
+// tomlLiteralDates replaces the dates and times in the names, targets and
+// arguments of the records of the TOML document config by strings: an
+// unquoted TOML date is a string in the other formats.
+func tomlLiteralDates(config map[string]any) {
+	domains, _ := config["domains"].([]any)
+	for _, d := range domains {
+		domain, _ := d.(map[string]any)
+		records, _ := domain["records"].([]any)
+		for _, r := range records {
+			record, ok := r.(map[string]any)
+			if !ok {
+				continue
+			}
+			for _, key := range []string{"name", "target", "args"} {
+				if v, ok := record[key]; ok {
+					record[key] = tomlLiteralDate(v)
+				}
+			}
+			if args, ok := record["args"].([]any); ok {
+				for i, a := range args {
+					args[i] = tomlLiteralDate(a)
+				}
+			}
+		}
+	}
+}
+
+// tomlLiteralDate returns the TOML value v, or its string if it is a date
+// or a time.
+func tomlLiteralDate(v any) any {
+	switch v := v.(type) {
+	case toml.LocalDate, toml.LocalTime, toml.LocalDateTime:
+		return v.(fmt.Stringer).String()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return v
+}
+
+// yamlMapValue returns the value of key in the YAML mapping m, or nil.
+func yamlMapValue(m *yaml.Node, key string) *yaml.Node {
+	if m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// checkJSONValues returns an error if v can't be passed to JavaScript as
+// JSON (for example a YAML mapping whose keys aren't strings).
+func checkJSONValues(v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, e := range v {
+			if err := checkJSONValues(e); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, e := range v {
+			if err := checkJSONValues(e); err != nil {
+				return err
+			}
+		}
+	case map[any]any:
+		return errors.New("the keys of a mapping must be strings")
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package js
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDeclarative(t *testing.T) {
+	tests := []struct {
+		name, data string
+		want       string // The filepos of the records.
+	}{
+		{"z.yaml", "domains:\n  - name: example.com\n    records:\n      - {type: A, target: 10.0.0.1}\n      - type: A\n        target: 10.0.0.2\n", "[z.yaml:4:9 z.yaml:5:9]"},
+		{"z.toml", "[[domains]]\nname = \"example.com\"\n[[domains.records]]\ntype = \"A\"\n", "[z.toml:domains[0].records[0]]"},
+		{"z.json", `{"domains": [{"name": "example.com", "records": [{"type": "A"}, {"type": "A"}]}]}`, "[z.json:domains[0].records[0] z.json:domains[0].records[1]]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parseDeclarative(tt.name, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range config["domains"].([]any)[0].(map[string]any)["records"].([]any) {
+				got = append(got, r.(map[string]any)["filepos"].(string))
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("got %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDeclarative_errors(t *testing.T) {
+	for name, data := range map[string]string{
+		"z.yaml": "domains: [",
+		"k.yaml": "domains:\n  - 1: x\n",
+		"z.toml": "[domains",
+		"z.json": `{"domains": }`,
+	} {
+		if _, err := parseDeclarative(name, []byte(data)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestDeclarative(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dnsconfig.yaml": `registrars:
+  none:
+domains:
+  - name: example.com
+    registrar: none
+    modifiers: [NO_PURGE]
+    records:
+      - { type: A, name: www, target: 10.0.0.1, ttl: 600 }
+      - { type: MX, args: [10, mail.example.com.] }
+      - { type: IMPORT_TRANSFORM, args: ["10.0.0.0 ~ 10.0.0.255 ~ 192.168.0.0 ~ ", other.example.com., 60] }
+`,
+	})
+	for _, engine := range []string{EngineGoja, EngineOtto} {
+		t.Run(engine, func(t *testing.T) {
+			setEngine(t, engine)
+			conf, err := ExecuteJavaScript(filepath.Join(dir, "dnsconfig.yaml"), true, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := recordsOf(t, conf, "example.com")
+			want := []string{
+				"www A 10.0.0.1 [dnsconfig.yaml:8:9]",
+				"@ MX mail.example.com. [dnsconfig.yaml:9:9]",
+				"@ IMPORT_TRANSFORM other.example.com. [dnsconfig.yaml:10:9]",
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+			if !conf.Domains[0].KeepUnknown || conf.Domains[0].Records[0].TTL != 600 {
+				t.Errorf("modifiers not applied")
+			}
+		})
+	}
+}
+
+func TestDeclarative_errors(t *testing.T) {
+	setEngine(t, EngineGoja)
+	dir := writeFiles(t, map[string]string{
+		"key.yaml":      "domain: []\n",
+		"registrar.yml": "domains:\n  - name: example.com\n",
+		"type.yaml":     "domains:\n  - name: example.com\n    registrar: none\n    records:\n      - { type: NOPE }\n",
+		"modifier.yaml": "domains:\n  - name: example.com\n    registrar: none\n    modifiers: [{ NOPE: 1 }]\n",
+		"require.yaml":  "domains:\n  - name: example.com\n    registrar: none\n    modifiers: [{ require: ./evil.js }]\n",
+		"fetch.yaml":    "domains:\n  - name: example.com\n    registrar: none\n    records:\n      - { type: FETCH, name: https://example.com }\n",
+		"recmod.yaml":   "domains:\n  - name: example.com\n    registrar: none\n    records:\n      - { type: A, target: 10.0.0.1, modifiers: [NO_PURGE] }\n",
+		"noname.yaml":   "domains:\n  - name: example.com\n    registrar: none\n    records:\n      - { type: IMPORT_TRANSFORM, name: www, args: [x, other.example.com., 60] }\n",
+		"itmod.yaml":    "domains:\n  - name: example.com\n    registrar: none\n    records:\n      - { type: A, target: 10.0.0.1, modifiers: [IMPORT_TRANSFORM] }\n",
+		"both.json":     `{"domains": [{"name": "example.com", "registrar": "none", "records": [{"type": "A", "target": "10.0.0.1", "args": []}]}]}`,
+		"invalid.yaml":  "domains:\n  - name: example.com\n    registrar: none\n    records:\n      - { type: MX, target: mx. }\n",
+		"registrars.toml": `[registrars]
+none = "NONE"
+[providers]
+none = { type = "BIND", other = 1 }
+`,
+		"dnsconfig.js": `LOAD_YAML("zones/*.yaml");`,
+	})
+	tests := []struct{ file, want string }{
+		{"key.yaml", "key.yaml: unknown key domain"},
+		{"registrar.yml", "registrar.yml: domain example.com: no registrar"},
+		{"type.yaml", "type.yaml:5:9: unknown record type NOPE"},
+		{"modifier.yaml", "modifier.yaml: domain example.com: unknown modifier NOPE"},
+		{"require.yaml", "require.yaml: domain example.com: unknown modifier require"},
+		{"fetch.yaml", "fetch.yaml:5:9: unknown record type FETCH"},
+		{"recmod.yaml", "recmod.yaml:5:9: unknown modifier NO_PURGE"},
+		{"noname.yaml", "noname.yaml:5:9: IMPORT_TRANSFORM records have no name"},
+		{"itmod.yaml", "itmod.yaml:5:9: unknown modifier IMPORT_TRANSFORM"},
+		{"both.json", "both.json:domains[0].records[0]: target and args are exclusive"},
+		{"invalid.yaml", "invalid.yaml:5:9: "},
+		{"registrars.toml", "registrars.toml: provider none: unknown key other"},
+		{"dnsconfig.js", "zones/*.yaml: no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			_, err := ExecuteJavaScript(filepath.Join(dir, tt.file), true, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,757 @@
+package js
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+	"github.com/DNSControl/dnscontrol/v4/pkg/rfc4183"
+	"github.com/DNSControl/dnscontrol/v4/pkg/transform"
+	"github.com/dop251/goja"
+	"github.com/go-sourcemap/sourcemap"
+	"github.com/robertkrimen/otto/underscore"
+)
+
+// gojaRunner runs dnsconfig.js with goja (see EngineGoja). It provides the
+// same functions as executeOtto: require(), glob(), fetch(), ...
+type gojaRunner struct {
+	vm *goja.Runtime
+
+	// sources are the files that were run, by their name in positions,
+	// prefixes the length of the code that was added at the start of the
+	// first line of the ES modules, and sourceMaps the source maps of the
+	// files that esbuild transformed (see position).
+	sources    map[string]string
+	prefixes   map[string]int
+	sourceMaps map[string]*sourcemap.Consumer
+
+	modules map[string]*goja.Object // The module objects of the imported files, by path.
+
+	// The event loop: the timers, and the fetch() requests in progress,
+	// whose completions are sent to done.
+	timers   map[int64]*gojaTimer
+	timerID  int64
+	done     chan func()
+	inflight int
+
+	rejected []*goja.Promise // The rejected promises that have no handler.
+}
+
+// gojaTimer is a timer of setTimeout(), setInterval() or setImmediate().
+type gojaTimer struct {
+	id       int64
+	when     time.Time
+	interval time.Duration // 0 unless setInterval().
+	fn       goja.Callable
+	args     []goja.Value
+}
+
+// newGojaRunner returns a gojaRunner whose globals are defined, as well as
+// the CLI variables and underscore.js.
+func newGojaRunner(variables map[string]string) (*gojaRunner, error) {
+	r := &gojaRunner{
+		vm:         goja.New(),
+		sources:    map[string]string{},
+		prefixes:   map[string]int{},
+		sourceMaps: map[string]*sourcemap.Consumer{},
+		modules:    map[string]*goja.Object{},
+		timers:     map[int64]*gojaTimer{},
+		done:       make(chan func()),
+	}
+	r.vm.SetPromiseRejectionTracker(r.trackRejection)
+
+	if err := r.defineGlobals(); err != nil {
+		return nil, err
+	}
+
+	// add cli variables to goja
+	for key, value := range variables {
+		if err := r.vm.Set(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := r.vm.RunScript("underscore.js", underscore.Source()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// executeGoja runs script with goja, and returns the resulting conf as JSON.
+func executeGoja(filename string, script []byte, devMode bool, variables map[string]string) (string, error) {
+	r, err := newGojaRunner(variables)
+	if err != nil {
+		return "", err
+	}
+	// run helper script to prime vm and initialize variables
+	if _, err := r.vm.RunScript(helpersJsName, GetHelpers(devMode)); err != nil {
+		return "", err
+	}
+	// The _filePos() of helpers.js parses otto's stack traces.
+	if err := r.vm.Set("_filePos", r.filePos); err != nil {
+		return "", err
+	}
+
+	// run user script
+	if _, err := r.runFile(filepath.Join(currentDirectory, filename), filename, string(script)); err != nil {
+		return "", r.error(err)
+	}
+
+	// wait for event loop to finish
+	if err := r.runLoop(); err != nil {
+		return "", r.error(err)
+	}
+
+	// export conf as string
+	value, err := r.vm.RunString(`JSON.stringify(conf)`)
+	if err != nil {
+		return "", err
+	}
+	return value.String(), nil
+}
+
+// defineGlobals defines the functions that dnsconfig.js and helpers.js use.
+func (r *gojaRunner) defineGlobals() error {
+	console := r.vm.NewObject()
+	for _, name := range []string{"log", "info", "debug", "warn", "error"} {
+		if err := console.Set(name, r.consoleLog); err != nil {
+			return err
+		}
+	}
+
+	globals := map[string]any{
+		"require":         r.require,
+		"REV":             r.reverse,
+		"REVCOMPAT":       r.reverseCompat,
+		"glob":            r.listFiles, // used for require_glob()
+		"PANIC":           r.panic,
+		"HASH":            r.hash,
+		"readDeclarative": r.readDeclarative, // used for LOAD_YAML()
+		"readZoneFile":    r.readZoneFile,    // used for ZONEFILE()
+		"console":         console,
+		"setTimeout":      r.setTimer(false),
+		"setInterval":     r.setTimer(true),
+		"setImmediate":    r.setImmediate,
+		"clearTimeout":    r.clearTimer,
+		"clearInterval":   r.clearTimer,
+		"clearImmediate":  r.clearTimer,
+	}
+	// only define fetch() when explicitly enabled
+	if EnableFetch {
+		globals["fetch"] = r.fetch
+	}
+	for name, v := range globals {
+		if err := r.vm.Set(name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// throw throws a JavaScript Error with the message msg.
+func (r *gojaRunner) throw(msg string) {
+	e, err := r.vm.New(r.vm.Get("Error"), r.vm.ToValue(msg))
+	if err != nil {
+		panic(r.vm.NewGoError(errors.New(msg)))
+	}
+	panic(e)
+}
+
+// error returns err, an error of running dnsconfig.js, with the position
+// in dnsconfig.js (or a file it require()s or imports) where it happened.
+func (r *gojaRunner) error(err error) error {
+	var ex *goja.Exception
+	if !errors.As(err, &ex) || ex.Value() == nil {
+		return err
+	}
+	// goja repeats "SyntaxError: " in the errors of the parser.
+	msg := strings.Replace(ex.Value().String(), "SyntaxError: SyntaxError: ", "SyntaxError: ", 1)
+	for _, f := range ex.Stack() {
+		if pos, ok := r.position(f); ok {
+			return fmt.Errorf("%s at %s", msg, pos)
+		}
+	}
+	return errors.New(msg)
+}
+
+// rethrow throws err, the error of running some JavaScript, in the code
+// that called the native function being run.
+func (r *gojaRunner) rethrow(err error) {
+	var ex *goja.Exception
+	if errors.As(err, &ex) {
+		panic(ex)
+	}
+	r.throw(err.Error())
+}
+
+// runFile runs the file at path, whose source is src and whose name in
+// positions is name. If it is an ES module, it returns its exports.
+func (r *gojaRunner) runFile(path, name, src string) (goja.Value, error) {
+	r.sources[name] = src
+	code, sm, isModule, err := transformFile(name, src)
+	if err != nil {
+		return nil, err
+	}
+	if sm != nil {
+		r.sourceMaps[name] = sm
+	}
+	if !isModule {
+		_, err := r.vm.RunScript(name, code)
+		return nil, err
+	}
+	r.prefixes[name] = len(moduleHead)
+
+	fn, err := r.vm.RunScript(name, moduleHead+code+"\n})")
+	if err != nil {
+		return nil, err
+	}
+	run, ok := goja.AssertFunction(fn)
+	if !ok {
+		return nil, fmt.Errorf("%s: not a module", name)
+	}
+	// The module is recorded before it runs, so that a module that it
+	// imports can import it in turn.
+	module, err := r.newModule(r.vm.NewObject())
+	if err != nil {
+		return nil, err
+	}
+	r.modules[path] = module
+
+	currentDirectoryOld := currentDirectory
+	currentDirectory = filepath.Dir(path)
+	defer func() { currentDirectory = currentDirectoryOld }()
+
+	if _, err := run(goja.Undefined(), module.Get("exports"), r.vm.ToValue(r.importer(filepath.Dir(path))), module); err != nil {
+		return nil, err
+	}
+	return module.Get("exports"), nil
+}
+
+// newModule returns a module object whose exports are exports.
+func (r *gojaRunner) newModule(exports goja.Value) (*goja.Object, error) {
+	module := r.vm.NewObject()
+	if err := module.Set("exports", exports); err != nil {
+		return nil, err
+	}
+	return module, nil
+}
+
+// importer returns the require() of the ES modules in the directory dir,
+// which their imports call: it returns the exports of a module, the value
+// of a JSON file, or an empty object for a script. The name of a module is
+// relative to dir if it starts with ".", else to the directory of
+// dnsconfig.js.
+func (r *gojaRunner) importer(dir string) func(string) goja.Value {
+	return func(spec string) goja.Value {
+		base := configDirectory
+		if strings.HasPrefix(spec, ".") {
+			base = dir
+		}
+		path := moduleFile(filepath.Clean(filepath.Join(base, spec)))
+		if module, ok := r.modules[path]; ok {
+			return module.Get("exports")
+		}
+
+		printer.Debugf("importing: %s (%s)\n", spec, path)
+		data, err := os.ReadFile(filepath.ToSlash(path))
+		if err != nil {
+			r.throw(err.Error())
+		}
+
+		var exports goja.Value
+		if isJSONFile(path) {
+			exports = r.parseJSON(path, data)
+		} else if exports, err = r.runFile(path, positionName(path), string(data)); err != nil {
+			r.rethrow(err)
+		}
+		if exports == nil {
+			// A script: it has no exports.
+			exports = r.vm.NewObject()
+		}
+		if _, ok := r.modules[path]; !ok {
+			module, err := r.newModule(exports)
+			if err != nil {
+				r.rethrow(err)
+			}
+			r.modules[path] = module
+		}
+		return exports
+	}
+}
+
+// moduleFile returns the file of the module path: path, or path with the
+// extension .js or .ts if it has none. As in TypeScript, "x.js" is "x.ts"
+// if only the latter exists.
+func moduleFile(path string) string {
+	exists := func(p string) bool {
+		_, err := os.Stat(p)
+		return err == nil
+	}
+	switch {
+	case exists(path):
+		return path
+	case filepath.Ext(path) == "":
+		if !exists(path+".js") && exists(path+".ts") {
+			return path + ".ts"
+		}
+		return path + ".js"
+	case strings.EqualFold(filepath.Ext(path), ".js"):
+		if ts := strings.TrimSuffix(path, filepath.Ext(path)) + ".ts"; exists(ts) {
+			return ts
+		}
+	}
+	return path
+}
+
+// parseJSON returns the value of the JSON file path, whose content is data.
+func (r *gojaRunner) parseJSON(path string, data []byte) goja.Value {
+	// Like require() with otto, the content is evaluated (which accepts
+	// more than JSON: comments, trailing commas, ...).
+	value, err := r.vm.RunScript(positionName(path), fmt.Sprintf(`JSON.parse(JSON.stringify(%s))`, string(data)))
+	if err != nil {
+		r.throw(fmt.Sprintf("File %s: %s", filepath.Base(path), err.Error()))
+	}
+	return value
+}
+
+func (r *gojaRunner) require(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) != 1 {
+		r.throw("require takes exactly one argument")
+	}
+	file := call.Argument(0).String() // The filename as given by the user
+	relFile, cleanFile := requirePaths(file)
+
+	// Record the old currentDirectory so that we can return there.
+	currentDirectoryOld := currentDirectory
+	// Record the directory path leading up to the file we're about to require.
+	currentDirectory = filepath.Dir(cleanFile)
+	defer func() { currentDirectory = currentDirectoryOld }()
+
+	printer.Debugf("requiring: %s (%s)\n", file, relFile)
+	// quick fix, by replacing to linux slashes, to make it work with windows paths too.
+	data, err := os.ReadFile(filepath.ToSlash(relFile))
+	if err != nil {
+		r.throw(err.Error())
+	}
+
+	// If its a json file return the json value, else default to true
+	if isJSONFile(relFile) {
+		return r.parseJSON(relFile, data)
+	}
+	if module, ok := r.modules[cleanFile]; ok {
+		return module.Get("exports")
+	}
+	exports, err := r.runFile(cleanFile, positionName(cleanFile), string(data))
+	if err != nil {
+		var ex *goja.Exception
+		if errors.As(err, &ex) {
+			panic(ex)
+		}
+		r.throw(fmt.Sprintf("File %s: %s", filepath.Base(relFile), err.Error()))
+	}
+	if exports != nil {
+		// An ES module: return its exports.
+		return exports
+	}
+	return r.vm.ToValue(true)
+}
+
+func (r *gojaRunner) listFiles(call goja.FunctionCall) goja.Value {
+	// Check amount of arguments provided
+	if len(call.Arguments) < 1 || len(call.Arguments) > 3 {
+		r.throw("glob requires at least one argument: folder (string). " +
+			"Optional: recursive (bool) [true], fileExtension (string) [.js]")
+	}
+
+	dir, ok := call.Argument(0).Export().(string)
+	if !ok || dir == "" {
+		r.throw("glob: first argument needs to be a path, provided as string.")
+	}
+
+	recursive := true
+	if arg := call.Argument(1); !goja.IsUndefined(arg) && !goja.IsNull(arg) {
+		if recursive, ok = arg.Export().(bool); !ok {
+			r.throw("glob: second argument, if recursive, needs to be bool.")
+		}
+	}
+
+	fileExtension := ".js"
+	if arg := call.Argument(2); !goja.IsUndefined(arg) && !goja.IsNull(arg) {
+		if fileExtension, ok = arg.Export().(string); !ok {
+			r.throw("glob: third argument, file extension, needs to be a string. * for no filter.")
+		}
+	}
+
+	files, err := globFiles(dir, recursive, fileExtension)
+	if err != nil {
+		r.throw(err.Error())
+	}
+	values := make([]any, len(files))
+	for i, f := range files {
+		values[i] = f
+	}
+	return r.vm.NewArray(values...)
+}
+
+func (r *gojaRunner) readDeclarative(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) != 1 {
+		r.throw("LOAD_YAML takes exactly one argument")
+	}
+	configs, err := readDeclarative(call.Argument(0).String())
+	if err != nil {
+		r.throw(err.Error())
+	}
+	return r.vm.ToValue(configs)
+}
+
+func (r *gojaRunner) readZoneFile(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) != 4 {
+		r.throw("readZoneFile takes exactly four arguments")
+	}
+	records, err := readZoneFile(call.Argument(0).String(), call.Argument(1).String(), call.Argument(2).String(), uint32(call.Argument(3).ToInteger()))
+	if err != nil {
+		r.throw(err.Error())
+	}
+	return r.vm.ToValue(records)
+}
+
+func (r *gojaRunner) panic(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) != 1 {
+		r.throw("PANIC takes exactly one argument")
+	}
+	fmt.Fprintln(os.Stderr, call.Argument(0).String())
+	os.Exit(1)
+	return goja.Undefined()
+}
+
+func (r *gojaRunner) hash(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) != 2 {
+		r.throw("require takes exactly two arguments")
+	}
+	s, err := hashString(call.Argument(0).String(), call.Argument(1).String())
+	if err != nil {
+		r.throw(err.Error())
+	}
+	return r.vm.ToValue(s)
+}
+
+func (r *gojaRunner) reverse(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) != 1 {
+		r.throw("REV takes exactly one argument")
+	}
+	rev, err := transform.ReverseDomainName(call.Argument(0).String())
+	if err != nil {
+		r.throw(err.Error())
+	}
+	return r.vm.ToValue(rev)
+}
+
+func (r *gojaRunner) reverseCompat(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) != 1 {
+		r.throw("REVCOMPAT takes exactly one argument")
+	}
+	if err := rfc4183.SetCompatibilityMode(call.Argument(0).String()); err != nil {
+		r.throw(err.Error())
+	}
+	return goja.Null()
+}
+
+// consoleLog prints its arguments, like console.log() of otto.
+func (r *gojaRunner) consoleLog(call goja.FunctionCall) goja.Value {
+	args := make([]string, len(call.Arguments))
+	for i, a := range call.Arguments {
+		args[i] = a.String()
+	}
+	fmt.Println(strings.Join(args, " "))
+	return goja.Undefined()
+}
+
+// filePos replaces _filePos() of helpers.js: it returns the position
+// ("file:line:column") of the code in dnsconfig.js (or a file it
+// require()s or imports) that called into helpers.js.
+//
+// The column is the one otto reports (where the called function starts,
+// rather than where its arguments start), so that the positions don't
+// depend on the engine.
+func (r *gojaRunner) filePos(goja.FunctionCall) goja.Value {
+	for _, f := range r.vm.CaptureCallStack(0, nil) {
+		if pos, ok := r.position(f); ok {
+			return r.vm.ToValue(pos)
+		}
+	}
+	return r.vm.ToValue("")
+}
+
+// position returns the position ("file:line:column") of the frame f, or
+// false if f is not in dnsconfig.js or a file it require()s or imports.
+func (r *gojaRunner) position(f goja.StackFrame) (string, bool) {
+	name := f.SrcName()
+	if name == helpersJsName || name == "<native>" {
+		return "", false
+	}
+	pos := f.Position()
+	line, col := pos.Line, pos.Column
+	if line == 1 {
+		col = max(col-r.prefixes[name], 1)
+	}
+	if sm := r.sourceMaps[name]; sm != nil {
+		line, col = originalPosition(sm, line, col)
+	}
+	col = calleeColumn(r.sources[name], line, col)
+	if name == "" {
+		name = "line" // Like otto, for ExecuteJavascriptString().
+	}
+	return fmt.Sprintf("%s:%d:%d", name, line, col), true
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// calleeColumn returns the column where the called function starts, in the
+// call whose "(" is at line:col of src: "x.D" in "x.D (...)".
+func calleeColumn(src string, line, col int) int {
+	lines := strings.SplitN(src, "\n", line+1)
+	if line < 1 || line > len(lines) {
+		return col
+	}
+	l := lines[line-1]
+	i := col - 1
+	if i < 0 || i >= len(l) || l[i] != '(' {
+		return col
+	}
+	for i > 0 && (l[i-1] == ' ' || l[i-1] == '\t') {
+		i--
+	}
+	for i > 0 && (isIdentChar(l[i-1]) || l[i-1] == '.') {
+		i--
+	}
+	return i + 1
+}
+
+// trackRejection records the rejected promises that have no handler: they
+// are errors, unless a handler is added later.
+func (r *gojaRunner) trackRejection(p *goja.Promise, op goja.PromiseRejectionOperation) {
+	switch op {
+	case goja.PromiseRejectionReject:
+		r.rejected = append(r.rejected, p)
+	case goja.PromiseRejectionHandle:
+		for i, q := range r.rejected {
+			if q == p {
+				r.rejected = append(r.rejected[:i], r.rejected[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// setTimer returns setTimeout() (or setInterval() if repeat).
+func (r *gojaRunner) setTimer(repeat bool) func(goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		fn, ok := goja.AssertFunction(call.Argument(0))
+		if !ok {
+			r.throw("the first argument of setTimeout/setInterval must be a function")
+		}
+		delay := time.Duration(call.Argument(1).ToFloat() * float64(time.Millisecond))
+		if delay < 0 {
+			delay = 0
+		}
+		var args []goja.Value
+		if len(call.Arguments) > 2 {
+			args = call.Arguments[2:]
+		}
+		t := r.addTimer(fn, delay, args)
+		if repeat {
+			t.interval = max(delay, time.Millisecond)
+		}
+		return r.vm.ToValue(t.id)
+	}
+}
+
+func (r *gojaRunner) setImmediate(call goja.FunctionCall) goja.Value {
+	fn, ok := goja.AssertFunction(call.Argument(0))
+	if !ok {
+		r.throw("the first argument of setImmediate must be a function")
+	}
+	var args []goja.Value
+	if len(call.Arguments) > 1 {
+		args = call.Arguments[1:]
+	}
+	return r.vm.ToValue(r.addTimer(fn, 0, args).id)
+}
+
+func (r *gojaRunner) addTimer(fn goja.Callable, delay time.Duration, args []goja.Value) *gojaTimer {
+	r.timerID++
+	t := &gojaTimer{id: r.timerID, when: time.Now().Add(delay), fn: fn, args: args}
+	r.timers[t.id] = t
+	return t
+}
+
+func (r *gojaRunner) clearTimer(call goja.FunctionCall) goja.Value {
+	delete(r.timers, call.Argument(0).ToInteger())
+	return goja.Undefined()
+}
+
+// runLoop runs the timers and the completions of fetch() until there are
+// none left.
+func (r *gojaRunner) runLoop() error {
+	for {
+		if err := r.checkRejections(); err != nil {
+			return err
+		}
+		if len(r.timers) == 0 && r.inflight == 0 {
+			return nil
+		}
+
+		var next *gojaTimer
+		for _, t := range r.timers {
+			if next == nil || t.when.Before(next.when) || t.when.Equal(next.when) && t.id < next.id {
+				next = t
+			}
+		}
+		var wait <-chan time.Time
+		if next != nil {
+			wait = time.After(time.Until(next.when))
+		}
+
+		select {
+		case f := <-r.done:
+			r.inflight--
+			f()
+		case <-wait:
+			if next.interval > 0 {
+				next.when = time.Now().Add(next.interval)
+			} else {
+				delete(r.timers, next.id)
+			}
+			if _, err := next.fn(goja.Undefined(), next.args...); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkRejections returns an error if a promise was rejected and nothing
+// handles it.
+func (r *gojaRunner) checkRejections() error {
+	if len(r.rejected) == 0 {
+		return nil
+	}
+	reason := r.rejected[0].Result()
+	if obj, ok := reason.(*goja.Object); ok {
+		if stack := obj.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+			return fmt.Errorf("unhandled promise rejection: %s", stack.String())
+		}
+	}
+	return fmt.Errorf("unhandled promise rejection: %s", reason.String())
+}
+
+// fetch is a subset of the Fetch API: fetch(url, {method, headers, body})
+// returns a promise of a response that has ok, status, statusText, url,
+// headers.get(), headers.has(), text() and json().
+func (r *gojaRunner) fetch(call goja.FunctionCall) goja.Value {
+	url := call.Argument(0).String()
+	method := http.MethodGet
+	var body io.Reader
+	headers := map[string]string{}
+	if opts, ok := call.Argument(1).(*goja.Object); ok {
+		if v := opts.Get("method"); v != nil && !goja.IsUndefined(v) {
+			method = strings.ToUpper(v.String())
+		}
+		if v := opts.Get("body"); v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+			body = strings.NewReader(v.String())
+		}
+		if h, ok := opts.Get("headers").(*goja.Object); ok {
+			for _, k := range h.Keys() {
+				headers[k] = h.Get(k).String()
+			}
+		}
+	}
+
+	promise, resolve, reject := r.vm.NewPromise()
+	r.inflight++
+	go func() {
+		resp, data, err := doFetch(method, url, headers, body)
+		r.done <- func() {
+			if err != nil {
+				e, _ := r.vm.New(r.vm.Get("TypeError"), r.vm.ToValue(fmt.Sprintf("fetch %s: %s", url, err)))
+				_ = reject(e)
+				return
+			}
+			_ = resolve(r.fetchResponse(url, resp, data))
+		}
+	}()
+	return r.vm.ToValue(promise)
+}
+
+// doFetch makes the request of fetch(), and reads the response.
+func doFetch(method, url string, headers map[string]string, body io.Reader) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return resp, data, err
+}
+
+// fetchResponse returns the Response object of fetch().
+func (r *gojaRunner) fetchResponse(url string, resp *http.Response, data []byte) *goja.Object {
+	headers := r.vm.NewObject()
+	_ = headers.Set("get", func(name string) goja.Value {
+		if vs := resp.Header.Values(name); len(vs) != 0 {
+			return r.vm.ToValue(strings.Join(vs, ", "))
+		}
+		return goja.Null()
+	})
+	_ = headers.Set("has", func(name string) bool {
+		return len(resp.Header.Values(name)) != 0
+	})
+	_ = headers.Set("keys", func() []string {
+		keys := make([]string, 0, len(resp.Header))
+		for k := range resp.Header {
+			keys = append(keys, strings.ToLower(k))
+		}
+		sort.Strings(keys)
+		return keys
+	})
+
+	res := r.vm.NewObject()
+	_ = res.Set("ok", resp.StatusCode >= 200 && resp.StatusCode < 300)
+	_ = res.Set("status", resp.StatusCode)
+	_ = res.Set("statusText", strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode))))
+	_ = res.Set("url", url)
+	_ = res.Set("headers", headers)
+	_ = res.Set("text", func() goja.Value {
+		p, resolve, _ := r.vm.NewPromise()
+		_ = resolve(string(data))
+		return r.vm.ToValue(p)
+	})
+	_ = res.Set("json", func() goja.Value {
+		p, resolve, reject := r.vm.NewPromise()
+		parse, _ := goja.AssertFunction(r.vm.Get("JSON").ToObject(r.vm).Get("parse"))
+		v, err := parse(goja.Undefined(), r.vm.ToValue(string(data)))
+		var ex *goja.Exception
+		if errors.As(err, &ex) {
+			_ = reject(ex.Value())
+		} else {
+			_ = resolve(v)
+		}
+		return r.vm.ToValue(p)
+	})
+	return res
+}
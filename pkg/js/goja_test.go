@@ -0,0 +1,200 @@
+package js
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+// recordsOf returns "name type target filepos" for each record of the
+// domain domain in conf.
+func recordsOf(t *testing.T, conf *models.DNSConfig, domain string) []string {
+	t.Helper()
+	for _, dc := range conf.Domains {
+		if dc.Name == domain {
+			var recs []string
+			for _, rc := range dc.Records {
+				recs = append(recs, fmt.Sprintf("%s %s %s %s", rc.Name, rc.Type, rc.GetTargetField(), rc.FilePos))
+			}
+			return recs
+		}
+	}
+	t.Fatalf("domain %s not found", domain)
+	return nil
+}
+
+// writeFiles writes the files (name: content) in a new directory, and
+// returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGoja_modernJavaScript(t *testing.T) {
+	setEngine(t, EngineGoja)
+	script := `const REG = NewRegistrar("none");
+const hosts = [{ name: "a", ip: "10.0.0.1" }, { name: "b" }];
+class Host {
+    constructor({ name, ip = "10.0.0.254" }) { this.name = name; this.ip = ip; }
+    record() { return A(this.name, this.ip); }
+}
+D("example.com", REG,
+    ...hosts.map((h) => new Host(h).record()),
+    TXT("t", ` + "`${hosts.length} hosts`" + `),
+    A("c", hosts[2]?.ip ?? "10.0.0.3"),
+);
+`
+	conf, err := ExecuteJavascriptString([]byte(script), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := recordsOf(t, conf, "example.com")
+	want := []string{
+		"a A 10.0.0.1 [line:5:23]",
+		"b A 10.0.0.254 [line:5:23]",
+		"t TXT 2 hosts [line:9:5]",
+		"c A 10.0.0.3 [line:10:5]",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGoja_modules(t *testing.T) {
+	setEngine(t, EngineGoja)
+	dir := writeFiles(t, map[string]string{
+		"dnsconfig.js": `import { mkA, DOMAIN as D1 } from "./lib/records.js";
+import counter, * as records from "./lib/records.js";
+import hosts from "./lib/hosts.json";
+import { isEven } from "./lib/even.js";
+var legacy = require("./lib/legacy.js");
+
+D(D1, NewRegistrar("none"),
+    hosts.map(({ name, ip }) => mkA(name, ip)),
+    records.mkA("ns", "10.0.0.53"),
+    TXT("info", counter() + " " + isEven(4) + " " + legacy.answer),
+);
+`,
+		"lib/records.js": `export const DOMAIN = "example.com";
+
+export function mkA(name, ip) {
+    return A(name, ip);
+}
+
+let count = 0;
+export default function counter() {
+    return ++count;
+}
+`,
+		"lib/hosts.json": `[{"name": "www", "ip": "10.0.0.80"}]`,
+		// An import cycle (even.js isn't done when odd.js imports it):
+		"lib/even.js": `import { isOdd } from "./odd.js";
+export const isEven = (n) => n === 0 || isOdd(n - 1);
+`,
+		"lib/odd.js": `import { isEven } from "./even.js";
+export const isOdd = (n) => n !== 0 && isEven(n - 1);
+`,
+		"lib/legacy.js": `export const answer = 42;`,
+	})
+
+	conf, err := ExecuteJavaScript(filepath.Join(dir, "dnsconfig.js"), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := recordsOf(t, conf, "example.com")
+	want := []string{
+		"www A 10.0.0.80 [lib/records.js:4:12]",
+		"ns A 10.0.0.53 [lib/records.js:4:12]",
+		"info TXT 1 true 42 [dnsconfig.js:10:5]",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGoja_asyncAndTimers(t *testing.T) {
+	setEngine(t, EngineGoja)
+	script := `D("example.com", NewRegistrar("none"));
+setTimeout(() => D_EXTEND("example.com", A("timer", "10.0.0.1")), 5);
+(async () => {
+    const ip = await Promise.resolve("10.0.0.2");
+    D_EXTEND("example.com", A("async", ip));
+})();
+`
+	conf, err := ExecuteJavascriptString([]byte(script), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := recordsOf(t, conf, "example.com")
+	want := []string{"async A 10.0.0.2 [line:5:29]", "timer A 10.0.0.1 [line:2:42]"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGoja_errors(t *testing.T) {
+	setEngine(t, EngineGoja)
+	tests := []struct{ desc, script, want string }{
+		{"throw", "D(\"example.com\", NewRegistrar(\"none\"));\nthrow new Error(\"boom\");", "Error: boom at line:2:7"},
+		{"helpers", `D("example.com", NewRegistrar("none"), TTL("forever"));`, "forever is not a valid duration string at line:1:40"},
+		{"syntax", `D("example.com" NewRegistrar("none"));`, "SyntaxError"},
+		{"unhandled rejection", `Promise.reject(new Error("nope"));`, "unhandled promise rejection: Error: nope"},
+		{"import", `import { x } from "./does-not-exist.js";`, "does-not-exist.js"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := ExecuteJavascriptString([]byte(tt.script), true, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoja_fetch(t *testing.T) {
+	setEngine(t, EngineGoja)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("X-Token") != "t0k" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ip": "10.0.0.7"}`)
+	}))
+	defer srv.Close()
+
+	old := EnableFetch
+	EnableFetch = true
+	defer func() { EnableFetch = old }()
+
+	script := `D("example.com", NewRegistrar("none"));
+FETCH(url, { method: "POST", headers: { "X-Token": "t0k" }, body: "{}" })
+    .then((r) => { if (!r.ok) throw new Error(r.status); return r.json(); })
+    .then(({ ip }) => D_EXTEND("example.com", A("fetched", ip)));
+`
+	conf, err := ExecuteJavascriptString([]byte(script), true, map[string]string{"url": srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := recordsOf(t, conf, "example.com")
+	want := []string{"fetched A 10.0.0.7 [line:4:47]"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
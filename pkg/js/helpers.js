@@ -28,6 +28,10 @@ var conf = {
 
 var defaultArgs = [];
 
+// _global is the global object ("this" is undefined in the functions of
+// this file, because it is in strict mode).
+var _global = this;
+
 function initialize() {
     conf = {
         registrars: [],
@@ -122,6 +126,36 @@ function newDomain(name, registrar) {
     };
 }
 
+// _filePos returns the position ("file:line:column") of the code in
+// dnsconfig.js (or a file it require()s) that called into helpers.js.
+// NB(tlim): Hopefully we can find a better way to do this in the
+// future. Right now we're faking that there was an error just to parse
+// out the line number. That's inefficient but I can't find anything better.
+// This only works with otto: goja replaces it (see filePos in goja.go).
+function _filePos() {
+    var lines = new Error().stack.split('\n');
+    for (var i = 1; i < lines.length; i++) {
+        // "    at func (file:line:column)" or "    at file:line:column"
+        var m = lines[i].match(/^\s*at (?:.* \()?(.*:\d+:\d+)\)?$/);
+        if (m && m[1].indexOf('<helpers.js>') !== 0) {
+            return m[1].replace('<anonymous>', 'line');
+        }
+    }
+    return '';
+}
+
+// _describeCall returns a description of a call to D(), D_EXTEND() or
+// INCLUDE(), for example 'D("example.com") dnsconfig.js:3:1'.
+function _describeCall(fn, name) {
+    return fn + '("' + name + '") ' + _filePos();
+}
+
+// _provenance returns the chain of D()/D_EXTEND()/INCLUDE() calls that
+// records added to the domain d come from.
+function _provenance(d) {
+    return d.provenance ? d.provenance.slice() : [];
+}
+
 function processDargs(m, domain) {
     // for each modifier, if it is a...
     // function: call it with domain
@@ -148,6 +182,7 @@ function processDargs(m, domain) {
 // D(name,registrar): Create a DNS Domain. Use the parameters as records and mods.
 function D(name, registrar) {
     var domain = newDomain(name, registrar);
+    domain.provenance = [_describeCall('D', name)];
     for (var i = 0; i < defaultArgs.length; i++) {
         processDargs(defaultArgs[i], domain);
     }
@@ -182,13 +217,22 @@ function INCLUDE(name) {
             ' was not declared yet and therefore cannot be updated. Use D() before.'
         );
     }
+    var call = _describeCall('INCLUDE', name);
     return function (d) {
-        d.records.push.apply(d.records, domain.obj.records);
+        for (var i = 0; i < domain.obj.records.length; i++) {
+            var r = domain.obj.records[i];
+            d.records.push(
+                _.extend({}, r, {
+                    provenance: _provenance(d).concat([call], r.provenance || []),
+                })
+            );
+        }
     };
 }
 
 // D_EXTEND(name): Update a DNS Domain already added with D(), or subdomain thereof
 function D_EXTEND(name) {
+    var call = _describeCall('D_EXTEND', name);
     var domain = _getDomainObject(name);
     if (domain == null) {
         throw (
@@ -207,10 +251,13 @@ function D_EXTEND(name) {
         name.length - domain.obj.name.length - 1
     );
 
+    var provenance = domain.obj.provenance;
+    domain.obj.provenance = [call];
     for (var i = 1; i < arguments.length; i++) {
         var m = arguments[i];
         processDargs(m, domain.obj);
     }
+    domain.obj.provenance = provenance;
     conf.domains[domain.id] = domain.obj; // let's overwrite the object.
 }
 
@@ -277,7 +324,7 @@ function stringToDuration(v) {
     if (matches == null) {
         throw v + ' is not a valid duration string';
     }
-    unit = 's';
+    var unit = 's';
     if (matches[2]) {
         unit = matches[2];
     }
@@ -291,6 +338,47 @@ function stringToDuration(v) {
     return v;
 }
 
+// STAGED_CHANGE(lowTTL): Change the value of the record in phases: lower
+// the TTL, wait for the old TTL to expire, change the value, raise the TTL.
+function STAGED_CHANGE(lowTTL) {
+    if (lowTTL === undefined) {
+        lowTTL = 60;
+    }
+    if (_.isString(lowTTL)) {
+        lowTTL = stringToDuration(lowTTL);
+    }
+    if (!_.isNumber(lowTTL) || lowTTL <= 0) {
+        throw 'STAGED_CHANGE: the TTL must be a positive number or duration';
+    }
+    return function (r) {
+        r.meta['staged_ttl'] = lowTTL.toString();
+    };
+}
+
+// VALID_FROM(time): The record is only included from this time on.
+function VALID_FROM(t) {
+    return validityModifier('VALID_FROM', 'valid_from', t);
+}
+
+// VALID_UNTIL(time): The record is only included until this time.
+function VALID_UNTIL(t) {
+    return validityModifier('VALID_UNTIL', 'valid_until', t);
+}
+
+// validityModifier returns a record modifier that sets the metadata key to
+// t, a Date or a string that normalize parses.
+function validityModifier(name, key, t) {
+    if (_.isDate(t)) {
+        t = t.toISOString();
+    }
+    if (!_.isString(t) || t === '') {
+        throw name + ': the time must be a string (like "2025-06-20T02:00:00Z") or a Date';
+    }
+    return function (r) {
+        r.meta[key] = t;
+    };
+}
+
 // DefaultTTL(v): Set the default TTL for the domain.
 function DefaultTTL(v) {
     if (_.isString(v)) {
@@ -796,7 +884,7 @@ function getENotationInt(x) {
        1cm = 1e0 == 16 (1^4 + 0) or 0<<4 + 0
        0cm = 0e0 == 0
     */
-    size = x * 100; // get cm value
+    var size = x * 100; // get cm value
 
     // Convert the number to scientific notation
     var exp = Math.floor(Math.log10(size)); // Get the exponent (base 10)
@@ -823,7 +911,7 @@ function getENotationInt(x) {
         exp = 9; // Cap exponent at 9
     }
     // convert it to 4bit:4bit uint8
-    m_e = (mantissa << 4) | (exp & 0xf);
+    var m_e = (mantissa << 4) | (exp & 0xf);
     return m_e;
 }
 
@@ -836,11 +924,11 @@ function locStringBuilder(record, args) {
     // it is a good sanity check to compare with later on down the chain
     // when you're in the weeds with maths.
     // Tests depend on it being present. Changes here must reflect in tests.
-    nsstring = '';
-    ewstring = '';
-    precisionbuffer = '';
-    ns = args.ns.toUpperCase();
-    ew = args.ew.toUpperCase();
+    var nsstring = '';
+    var ewstring = '';
+    var precisionbuffer = '';
+    var ns = args.ns.toUpperCase();
+    var ew = args.ew.toUpperCase();
 
     // Handle N/S coords - can use also s1.toFixed(3)
     nsstring =
@@ -905,14 +993,16 @@ function locStringBuilder(record, args) {
 // Renders LOC type internal properties from D˚M'S" parameters.
 // Change anything here at your peril.
 function locDMSBuilder(record, args) {
-    LOCEquator = Math.pow(2, 31); // RFC 1876, Section 2.
-    LOCPrimeMeridian = Math.pow(2, 31); // RFC 1876, Section 2.
-    LOCHours = 60 * 1000;
-    LOCDegrees = 60 * LOCHours;
-    LOCAltitudeBase = 100000;
-
-    lat = args.d1 * LOCDegrees + args.m1 * LOCHours + args.s1 * 1000;
-    lon = args.d2 * LOCDegrees + args.m2 * LOCHours + args.s2 * 1000;
+    var LOCEquator = Math.pow(2, 31); // RFC 1876, Section 2.
+    var LOCPrimeMeridian = Math.pow(2, 31); // RFC 1876, Section 2.
+    var LOCHours = 60 * 1000;
+    var LOCDegrees = 60 * LOCHours;
+    var LOCAltitudeBase = 100000;
+
+    var lat = args.d1 * LOCDegrees + args.m1 * LOCHours + args.s1 * 1000;
+    var lon = args.d2 * LOCDegrees + args.m2 * LOCHours + args.s2 * 1000;
+    var ns = args.ns.toUpperCase();
+    var ew = args.ew.toUpperCase();
     if (ns == 'N') record.loclatitude = LOCEquator + lat;
     // S
     else record.loclatitude = LOCEquator - lat;
@@ -931,7 +1021,7 @@ function locDMSBuilder(record, args) {
     // Size
     record.locsize = getENotationInt(args.siz);
     // Horizontal Precision
-    m_e = args.hp;
+    var m_e = args.hp;
     record.lochorizpre = getENotationInt(args.hp);
 
     // Vertical Precision
@@ -1183,6 +1273,73 @@ function NO_PURGE(d) {
     d.KeepUnknown = true;
 }
 
+// MAX_CHANGES(n)
+// push refuses to change more than n records of the domain (unless --force).
+function MAX_CHANGES(n) {
+    if (!_.isNumber(n) || n < 1) {
+        throw 'MAX_CHANGES: the limit must be a number >= 1';
+    }
+    return function (d) {
+        d.max_changes = n;
+    };
+}
+
+// MAX_DELETES(n)
+// push refuses to delete more than n records of the domain (unless --force).
+function MAX_DELETES(n) {
+    if (!_.isNumber(n) || n < 1) {
+        throw 'MAX_DELETES: the limit must be a number >= 1';
+    }
+    return function (d) {
+        d.max_deletes = n;
+    };
+}
+
+// ALLOW_DANGLING(targetPattern, ...)
+// Do not warn about records of the domain whose target matches one of the
+// patterns, even if it does not exist or is at a cloud service that is a
+// subdomain takeover risk.
+function ALLOW_DANGLING() {
+    var patterns = Array.prototype.slice.call(arguments);
+    for (var i = 0; i < patterns.length; i++) {
+        if (!_.isString(patterns[i])) {
+            throw 'ALLOW_DANGLING: the patterns must be strings';
+        }
+    }
+    return function (d) {
+        d.allow_dangling = (d.allow_dangling || []).concat(patterns);
+    };
+}
+
+// ZONEFILE(path)
+// Add the records of the zone file path (RFC 1035, with $ORIGIN, $TTL and
+// $INCLUDE) to the domain. The origin is the name of the domain (or of the
+// subdomain of D_EXTEND()). The records without a TTL that no $TTL (or
+// previous TTL) applies to get the DefaultTTL() of the domain. SOA records
+// and NS records at the apex are skipped.
+function ZONEFILE(path) {
+    if (!_.isString(path)) {
+        throw 'ZONEFILE: the path must be a string';
+    }
+    return function (d) {
+        var domain = d.name.split('!')[0];
+        var origin = d.subdomain ? d.subdomain + '.' + domain : domain;
+        var zone = JSON.parse(
+            readZoneFile(path, origin, domain, d.defaultTTL)
+        );
+        var provenance = _provenance(d);
+        for (var i = 0; i < zone.records.length; i++) {
+            zone.records[i].provenance = provenance;
+            d.records.push(zone.records[i]);
+        }
+        for (var i = 0; i < zone.rawrecords.length; i++) {
+            zone.rawrecords[i].metas = [];
+            zone.rawrecords[i].provenance = provenance;
+            d.rawrecords.push(zone.rawrecords[i]);
+        }
+    };
+}
+
 // IGNORE_EXTERNAL_DNS(prefix)
 // When enabled, DNSControl will automatically detect TXT records created by
 // Kubernetes external-dns and ignore both the TXT records and the corresponding
@@ -1206,6 +1363,29 @@ function IGNORE_EXTERNAL_DNS(prefix) {
     };
 }
 
+// OWNERSHIP(ownerID, mode)
+// Makes DNSControl mark the records it manages with TXT records that contain
+// ownerID, and leave the records of other owners (or of no owner) alone.
+// This lets several dnsconfig.js files manage the same zone.
+//
+// Usage:
+//   OWNERSHIP("team-a")           // Refuse to change records that have no owner.
+//   OWNERSHIP("team-a", "adopt")  // Take over the records that have no owner.
+function OWNERSHIP(ownerID, mode) {
+    if (!_.isString(ownerID) || !/^[A-Za-z0-9._-]+$/.test(ownerID)) {
+        throw 'OWNERSHIP: the owner ID must be a non-empty string of letters, digits, ".", "_" and "-"';
+    }
+    if (mode !== undefined && mode !== 'adopt') {
+        throw 'OWNERSHIP: the mode must be "adopt" (or omitted)';
+    }
+    return function (d) {
+        d.owner_id = ownerID;
+        if (mode === 'adopt') {
+            d.owner_adopt = true;
+        }
+    };
+}
+
 // ENSURE_ABSENT_REC()
 // Usage: A("foo", "1.2.3.4", ENSURE_ABSENT_REC())
 function ENSURE_ABSENT_REC() {
@@ -1250,6 +1430,14 @@ function getModifiers(args, start) {
     return mods;
 }
 
+// _recordType marks fn, a function that builds records (made by
+// recordBuilder() or rawrecordBuilder()), as a record type whose first
+// argument is (hasName) or isn't the label, and returns it.
+function _recordType(fn, hasName) {
+    fn._recordType = { hasName: hasName };
+    return fn;
+}
+
 /**
  * Record type builder
  * @param {string} type Record type
@@ -1294,7 +1482,7 @@ function recordBuilder(type, opts) {
         },
     });
 
-    return function () {
+    var fn = function () {
         var parsedArgs = {};
         var modifiers = [];
 
@@ -1341,13 +1529,7 @@ function recordBuilder(type, opts) {
         }
 
         // Record which line called this record type.
-        // NB(tlim): Hopefully we can find a better way to do this in the
-        // future. Right now we're faking that there was an error just to parse
-        // out the line number. That's inefficient but I can't find anything better.
-        // This will certainly break if we change to a different Javascript interpreter.
-        // Hopefully any other interpreter will have a better way to do this.
-        var positionLines = new Error().stack.split('\n');
-        var position = positionLines[positionLines.length - 2];
+        var position = _filePos();
 
         return function (d) {
             var record = {
@@ -1355,6 +1537,7 @@ function recordBuilder(type, opts) {
                 meta: {},
                 ttl: d.defaultTTL,
                 filepos: position,
+                provenance: _provenance(d),
             };
 
             opts.applyModifier(record, modifiers);
@@ -1410,6 +1593,7 @@ function recordBuilder(type, opts) {
             return record;
         };
     };
+    return _recordType(fn, opts.args[0][0] === 'name');
 }
 
 /**
@@ -1624,7 +1808,7 @@ function LOC_BUILDER_DD(value) {
     var lati = ConvertDDToDMS(value.x, false);
     var long = ConvertDDToDMS(value.y, true);
 
-    dms = { lati: lati, long: long };
+    var dms = { lati: lati, long: long };
 
     return LOC_builder_push(value, dms);
 }
@@ -1691,8 +1875,8 @@ function LOC_BUILDER_STR(value) {
 }
 
 function LOC_builder_push(value, dms) {
-    r = []; // The list of records to return.
-    p = {}; // The metaparameters to set on the LOC record.
+    var r = []; // The list of records to return.
+    var p = {}; // The metaparameters to set on the LOC record.
     // rawloc = "";
 
     // Generate a LOC record with the metaparameters.
@@ -1802,16 +1986,16 @@ function SPF_BUILDER(value) {
         value.raw = '_rawspf';
     }
 
-    r = []; // The list of records to return.
-    p = {}; // The metaparameters to set on the main TXT record.
-    rawspf = value.parts.join(' '); // The unaltered SPF settings.
+    var r = []; // The list of records to return.
+    var p = {}; // The metaparameters to set on the main TXT record.
+    var rawspf = value.parts.join(' '); // The unaltered SPF settings.
 
     // If flattening is requested, generate a TXT record with the raw SPF settings.
     if (value.flatten && value.flatten.length > 0) {
         p.flatten = value.flatten.join(',');
         // Only add the raw spf record if it isn't an empty string
         if (value.raw !== '') {
-            rp = {};
+            var rp = {};
             if (value.ttl) {
                 r.push(TXT(value.raw, rawspf, rp, TTL(value.ttl)));
             } else {
@@ -1888,7 +2072,7 @@ function CAA_BUILDER(value) {
     if (value.ttl) {
         CAA_TTL = TTL(value.ttl);
     }
-    r = []; // The list of records to return.
+    var r = []; // The list of records to return.
 
     if (value.iodef) {
         if (value.iodef_critical) {
@@ -2453,11 +2637,346 @@ function require_glob() {
     return files;
 }
 
+// LOAD_YAML(pattern): Load the declarative configuration files (YAML,
+// TOML or JSON) that match the glob pattern. Each file is turned into
+// calls to NewRegistrar(), NewDnsProvider(), D(), ... Returns the names
+// of the files.
+function LOAD_YAML(pattern) {
+    var files = JSON.parse(readDeclarative(pattern));
+    var names = [];
+    for (var i = 0; i < files.length; i++) {
+        _loadDeclarative(files[i].file, files[i].config);
+        names.push(files[i].file);
+    }
+    return names;
+}
+
+// _loadDeclarative loads the declarative configuration config (of file).
+function _loadDeclarative(file, config) {
+    _checkDeclarativeKeys(
+        config,
+        ['registrars', 'providers', 'defaults', 'domains'],
+        file
+    );
+
+    var registrars = config.registrars || {};
+    for (var name in registrars) {
+        _declareProvider(
+            conf.registrars,
+            NewRegistrar,
+            name,
+            registrars[name],
+            file + ': registrar ' + name
+        );
+    }
+    var providers = config.providers || {};
+    for (var name in providers) {
+        _declareProvider(
+            conf.dns_providers,
+            NewDnsProvider,
+            name,
+            providers[name],
+            file + ': provider ' + name
+        );
+    }
+
+    var defaults = _declarativeModifiers(
+        config.defaults,
+        _declarativeDomainModifiers,
+        file + ': defaults'
+    );
+    var domains = config.domains || [];
+    if (!_.isArray(domains)) {
+        throw file + ': domains must be a list';
+    }
+    for (var i = 0; i < domains.length; i++) {
+        _loadDeclarativeDomain(domains[i], defaults, file);
+    }
+}
+
+// _declareProvider declares the registrar or DNS provider name (whose
+// declaration spec is null, a type or { type: ..., meta: ... }) with
+// newFn, unless a registrar or DNS provider with this name is already in
+// list.
+function _declareProvider(list, newFn, name, spec, where) {
+    if (spec === null || _.isString(spec)) {
+        spec = { type: spec };
+    }
+    _checkDeclarativeKeys(spec, ['type', 'meta'], where);
+    var type = spec.type || '-';
+    for (var i = 0; i < list.length; i++) {
+        if (list[i].name === name) {
+            if (spec.type && list[i].type !== '-' && list[i].type !== type) {
+                throw where + ': already declared with type ' + list[i].type;
+            }
+            return;
+        }
+    }
+    if (spec.meta) {
+        newFn(name, type, spec.meta);
+    } else {
+        newFn(name, type);
+    }
+}
+
+// _loadDeclarativeDomain calls D() (or D_EXTEND()) for the domain d of a
+// declarative configuration.
+function _loadDeclarativeDomain(d, defaults, file) {
+    _checkDeclarativeKeys(
+        d,
+        ['name', 'registrar', 'providers', 'extend', 'modifiers', 'records'],
+        file + ': domain'
+    );
+    if (!_.isString(d.name)) {
+        throw file + ': a domain has no name';
+    }
+    var where = file + ': domain ' + d.name;
+
+    var args = [];
+    var providers = d.providers || [];
+    if (_.isString(providers)) {
+        providers = [providers];
+    }
+    if (_.isArray(providers)) {
+        for (var i = 0; i < providers.length; i++) {
+            args.push(DnsProvider(providers[i]));
+        }
+    } else {
+        // { name: number of nameservers }
+        for (var name in providers) {
+            args.push(DnsProvider(name, providers[name]));
+        }
+    }
+    args = args.concat(
+        _declarativeModifiers(d.modifiers, _declarativeDomainModifiers, where)
+    );
+    var records = d.records || [];
+    if (!_.isArray(records)) {
+        throw where + ': records must be a list';
+    }
+    for (var i = 0; i < records.length; i++) {
+        args.push(_declarativeRecord(records[i], where));
+    }
+
+    if (d.extend) {
+        if (d.registrar) {
+            throw where + ': a domain that extends another has no registrar';
+        }
+        D_EXTEND.apply(null, [d.name].concat(args));
+        return;
+    }
+    if (!_.isString(d.registrar)) {
+        throw where + ': no registrar';
+    }
+    D.apply(null, [d.name, d.registrar].concat(defaults, args));
+}
+
+// _declarativeRecord returns the record r of a declarative configuration:
+// the result of r.type(r.name, r.target or ...r.args, TTL(r.ttl), r.meta,
+// ...r.modifiers), whose position is r.filepos.
+function _declarativeRecord(r, where) {
+    _checkDeclarativeKeys(
+        r,
+        ['type', 'name', 'target', 'args', 'ttl', 'meta', 'modifiers', 'filepos'],
+        where + ': record'
+    );
+    where = r.filepos;
+    var builder = _declarativeRecordType(r.type);
+    if (!builder) {
+        throw where + ': unknown record type ' + r.type;
+    }
+
+    var args = [];
+    if (builder._recordType.hasName) {
+        args.push(r.name === undefined ? '@' : String(r.name));
+    } else if (r.name !== undefined) {
+        throw where + ': ' + r.type + ' records have no name';
+    }
+    if (r.args !== undefined) {
+        if (r.target !== undefined) {
+            throw where + ': target and args are exclusive';
+        }
+        args = args.concat(r.args);
+    } else if (r.target !== undefined) {
+        args.push(r.target);
+    }
+    if (r.ttl !== undefined) {
+        args.push(TTL(r.ttl));
+    }
+    if (r.meta !== undefined) {
+        args.push(r.meta);
+    }
+    args = args.concat(
+        _declarativeModifiers(r.modifiers, _declarativeRecordModifiers, where)
+    );
+
+    var record;
+    try {
+        record = builder.apply(null, args);
+    } catch (e) {
+        throw where + ': ' + e;
+    }
+    var position = r.filepos;
+    return function (d) {
+        var n = d.records.length;
+        var nraw = d.rawrecords.length;
+        try {
+            record(d);
+        } catch (e) {
+            throw where + ': ' + e;
+        }
+        for (var i = n; i < d.records.length; i++) {
+            d.records[i].filepos = position;
+        }
+        for (var i = nraw; i < d.rawrecords.length; i++) {
+            d.rawrecords[i].filepos = position;
+        }
+    };
+}
+
+// _declarativeDomainModifiers and _declarativeRecordModifiers are the names
+// of the modifiers that declarative configurations can use. Only these, and
+// the record types (see _declarativeRecordType), can be called: a
+// declarative file must not be able to run other code (require(), FETCH(),
+// ...).
+var _declarativeDomainModifiers = [
+    'ALLOW_DANGLING',
+    'AUTODNSSEC_OFF',
+    'AUTODNSSEC_ON',
+    'CAA_BUILDER',
+    'CF_MANAGE_COMMENTS',
+    'CF_MANAGE_TAGS',
+    'CF_PROXY_DEFAULT_OFF',
+    'CF_PROXY_DEFAULT_ON',
+    'CF_UNIVERSALSSL_OFF',
+    'CF_UNIVERSALSSL_ON',
+    'DefaultTTL',
+    'DISABLE_IGNORE_SAFETY_CHECK',
+    'DKIM_BUILDER',
+    'DMARC_BUILDER',
+    'DnsProvider',
+    'GIDINET_PREMIUM_NS',
+    'IGNORE',
+    'IGNORE_EXTERNAL_DNS',
+    'IGNORE_NAME',
+    'IGNORE_TARGET',
+    'INCLUDE',
+    'LOC_BUILDER_DD',
+    'LOC_BUILDER_DMM_STR',
+    'LOC_BUILDER_DMS_STR',
+    'LOC_BUILDER_STR',
+    'M365_BUILDER',
+    'MAX_CHANGES',
+    'MAX_DELETES',
+    'NAMESERVER',
+    'NAMESERVER_TTL',
+    'NO_PURGE',
+    'OWNERSHIP',
+    'PURGE',
+    'SPF_BUILDER',
+    'ZONEFILE',
+];
+var _declarativeRecordModifiers = [
+    'AUTOSPLIT',
+    'CAA_CRITICAL',
+    'CF_CNAME_FLATTEN_OFF',
+    'CF_CNAME_FLATTEN_ON',
+    'CF_COMMENT',
+    'CF_PROXY_FULL',
+    'CF_PROXY_OFF',
+    'CF_PROXY_ON',
+    'CF_TAGS',
+    'ENSURE_ABSENT_REC',
+    'HEDNS_DDNS_KEY',
+    'HEDNS_DYNAMIC_OFF',
+    'HEDNS_DYNAMIC_ON',
+    'R53_EVALUATE_TARGET_HEALTH',
+    'R53_HEALTH_CHECK_ID',
+    'R53_WEIGHT',
+    'R53_ZONE',
+    'STAGED_CHANGE',
+    'TTL',
+    'VALID_FROM',
+    'VALID_UNTIL',
+];
+
+// _declarativeRecordType returns the record type (the global made by
+// recordBuilder() or rawrecordBuilder()) name, or undefined.
+function _declarativeRecordType(name) {
+    var fn = _.isString(name) ? _global[name] : undefined;
+    return _.isFunction(fn) && _.isObject(fn._recordType) ? fn : undefined;
+}
+
+// _declarativeFunction returns the global name if it is in allowed, or
+// undefined.
+function _declarativeFunction(allowed, name) {
+    if (!_.isString(name) || allowed.indexOf(name) === -1) {
+        return undefined;
+    }
+    return _global[name];
+}
+
+// _declarativeModifiers returns the modifiers of the list of a declarative
+// configuration. A modifier is the name of a global of allowed (NO_PURGE,
+// CF_PROXY_ON, ...), or { name: args }, which calls the function name with
+// args (a list, or a single argument).
+function _declarativeModifiers(list, allowed, where) {
+    if (list === undefined || list === null) {
+        return [];
+    }
+    if (!_.isArray(list)) {
+        list = [list];
+    }
+    var modifiers = [];
+    for (var i = 0; i < list.length; i++) {
+        var m = list[i];
+        if (_.isString(m)) {
+            var value = _declarativeFunction(allowed, m);
+            if (value === undefined) {
+                throw where + ': unknown modifier ' + m;
+            }
+            modifiers.push(value);
+            continue;
+        }
+        var names = _.isObject(m) && !_.isArray(m) ? Object.keys(m) : [];
+        if (names.length !== 1) {
+            throw (
+                where +
+                ': a modifier is a name or { name: args }, not ' +
+                JSON.stringify(m)
+            );
+        }
+        var fn = _declarativeFunction(allowed, names[0]);
+        if (!_.isFunction(fn)) {
+            throw where + ': unknown modifier ' + names[0];
+        }
+        var args = m[names[0]];
+        if (!_.isArray(args)) {
+            args = args === null ? [] : [args];
+        }
+        modifiers.push(fn.apply(null, args));
+    }
+    return modifiers;
+}
+
+// _checkDeclarativeKeys throws an error if obj isn't an object whose keys
+// are in allowed.
+function _checkDeclarativeKeys(obj, allowed, where) {
+    if (!_.isObject(obj) || _.isArray(obj)) {
+        throw where + ': must be a mapping, not ' + JSON.stringify(obj);
+    }
+    for (var key in obj) {
+        if (allowed.indexOf(key) === -1) {
+            throw where + ': unknown key ' + key;
+        }
+    }
+}
+
 // Set default values for CLI variables
 function CLI_DEFAULTS(defaults) {
     for (var key in defaults) {
-        if (typeof this[key] === 'undefined') {
-            this[key] = defaults[key];
+        if (typeof _global[key] === 'undefined') {
+            _global[key] = defaults[key];
         }
     }
 }
@@ -2528,7 +3047,7 @@ var DISABLE_REPEATED_DOMAIN_CHECK = { skip_fqdn_check: 'true' };
 // Go.
 
 function rawrecordBuilder(type) {
-    return function () {
+    var fn = function () {
         // Copy the raw args:
         var rawArgs = [];
         for (var i = 0; i < arguments.length; i++) {
@@ -2536,18 +3055,13 @@ function rawrecordBuilder(type) {
         }
 
         // Record which line called this record type.
-        // NB(tlim): Hopefully we can find a better way to do this in the
-        // future. Right now we're faking that there was an error just to parse
-        // out the line number. That's inefficient but I can't find anything better.
-        // This will certainly break if we change to a different Javascript interpreter.
-        // Hopefully any other interpreter will have a better way to do this.
-        var positionLines = new Error().stack.split('\n');
-        var position = positionLines[positionLines.length - 2];
+        var position = _filePos();
 
         return function (d) {
             var record = {
                 type: type,
                 filepos: position,
+                provenance: _provenance(d),
                 ttl: d.defaultTTL,
             };
 
@@ -2581,6 +3095,7 @@ function rawrecordBuilder(type) {
             return record;
         };
     };
+    return _recordType(fn, true);
 }
 
 // PLEASE KEEP THIS LIST ALPHABETICAL!
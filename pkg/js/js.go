@@ -3,6 +3,7 @@ package js
 import (
 	_ "embed" // Used to embed helpers.js in the binary.
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -34,104 +35,172 @@ var helpersJsFileName = "pkg/js/helpers.js"
 // far as require() is concerned, not the actual os.Getwd().
 var currentDirectory string
 
+// configDirectory is the directory of the file given to ExecuteJavaScript.
+// The positions of records (FilePos) are relative to it.
+var configDirectory string
+
+// helpersJsName is the filename of helpers.js in positions and stack traces.
+// It must not be a valid name for a file the user require()s.
+const helpersJsName = "<helpers.js>"
+
 // EnableFetch sets whether to enable fetch() in JS execution environment.
 var EnableFetch bool = false
 
+// The JavaScript engines that can run dnsconfig.js (see Engine).
+const (
+	// EngineGoja supports modern JavaScript (ES2020 and later: let and
+	// const, arrow functions, template literals, destructuring, classes,
+	// native Promises, async/await), ES modules (import and export) and
+	// TypeScript. It is opt-in until it has shipped in a release.
+	EngineGoja = "goja"
+	// EngineOtto supports ES5 only. It is the default.
+	EngineOtto = "otto"
+)
+
+// Engine is the JavaScript engine that runs dnsconfig.js.
+var Engine = EngineOtto
+
 // ExecuteJavaScript accepts a javascript file and runs it, returning the resulting dnsConfig.
+// A declarative configuration (YAML, TOML or JSON) is loaded with LOAD_YAML().
 func ExecuteJavaScript(file string, devMode bool, variables map[string]string) (*models.DNSConfig, error) {
-	script, err := os.ReadFile(file)
-	if err != nil {
-		return nil, err
+	var script []byte
+	if isDeclarativeFile(file) {
+		if _, err := os.Stat(file); err != nil {
+			return nil, err
+		}
+		script = declarativeScript(filepath.Base(file))
+	} else {
+		var err error
+		if script, err = os.ReadFile(file); err != nil {
+			return nil, err
+		}
 	}
 
 	// Record the directory path leading up to this file.
 	currentDirectory = filepath.Dir(file)
+	configDirectory = currentDirectory
 
-	return ExecuteJavascriptString(script, devMode, variables)
+	return executeJavascript(filepath.Base(file), script, devMode, variables)
 }
 
 // ExecuteJavascriptString accepts a string containing javascript and runs it, returning the resulting dnsConfig.
 func ExecuteJavascriptString(script []byte, devMode bool, variables map[string]string) (*models.DNSConfig, error) {
+	return executeJavascript("", script, devMode, variables)
+}
+
+// executeJavascript runs script. filename is the name used for the script
+// in positions and error messages ("" for none).
+func executeJavascript(filename string, script []byte, devMode bool, variables map[string]string) (*models.DNSConfig, error) {
+	if isTypeScript(filename) && Engine != EngineGoja {
+		return nil, fmt.Errorf("TypeScript requires --js-engine %s", EngineGoja)
+	}
+
+	var str string
+	var err error
+	switch Engine {
+	case EngineGoja:
+		str, err = executeGoja(filename, script, devMode, variables)
+	case EngineOtto:
+		str, err = executeOtto(filename, script, devMode, variables)
+	default:
+		return nil, fmt.Errorf("unknown JavaScript engine %q (valid: %s, %s)", Engine, EngineGoja, EngineOtto)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &models.DNSConfig{}
+	if err = json.Unmarshal([]byte(str), conf); err != nil {
+		return nil, err
+	}
+
+	err = conf.PostProcess()
+	if err != nil {
+		return nil, err
+	}
+	// No need to call FixLegacyDC here. These records were created from dnsconfig.js, not from a provider.
+
+	if err := rtypecontrol.ImportRawRecords(conf.Domains); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+// executeOtto runs script with otto, and returns the resulting conf as JSON.
+func executeOtto(filename string, script []byte, devMode bool, variables map[string]string) (string, error) {
 	vm := otto.New()
 	l := loop.New(vm)
 
 	if err := timers.Define(vm, l); err != nil {
-		return nil, err
+		return "", err
 	}
 	if err := promise.Define(vm, l); err != nil {
-		return nil, err
+		return "", err
 	}
 
 	// only define fetch() when explicitly enabled
 	if EnableFetch {
 		if err := fetch.Define(vm, l); err != nil {
-			return nil, err
+			return "", err
 		}
 	}
 
 	// add functions to otto
 	functions := map[string]any{
-		"require":   require,
-		"REV":       reverse,
-		"REVCOMPAT": reverseCompat,
-		"glob":      listFiles, // used for require_glob()
-		"PANIC":     jsPanic,
-		"HASH":      hashFunc,
+		"require":         require,
+		"REV":             reverse,
+		"REVCOMPAT":       reverseCompat,
+		"glob":            listFiles, // used for require_glob()
+		"PANIC":           jsPanic,
+		"HASH":            hashFunc,
+		"readDeclarative": readDeclarativeFunc, // used for LOAD_YAML()
+		"readZoneFile":    readZoneFileFunc,    // used for ZONEFILE()
 	}
 	for name, fn := range functions {
 		if err := vm.Set(name, fn); err != nil {
-			return nil, err
+			return "", err
 		}
 	}
 
 	// add cli variables to otto
 	for key, value := range variables {
 		if err := vm.Set(key, value); err != nil {
-			return nil, err
+			return "", err
 		}
 	}
 
-	helperJs := GetHelpers(devMode)
+	helperJs, err := vm.Compile(helpersJsName, GetHelpers(devMode))
+	if err != nil {
+		return "", err
+	}
 	// run helper script to prime vm and initialize variables
 	if err := l.Eval(helperJs); err != nil {
-		return nil, err
+		return "", err
 	}
 
 	// run user script
-	if err := l.Eval(script); err != nil {
-		return nil, err
+	var userJs any = script
+	if filename != "" {
+		if userJs, err = vm.Compile(filename, script); err != nil {
+			return "", err
+		}
+	}
+	if err := l.Eval(userJs); err != nil {
+		return "", err
 	}
 
 	// wait for event loop to finish
 	if err := l.Run(); err != nil {
-		return nil, err
+		return "", err
 	}
 
-	// export conf as string and unmarshal
+	// export conf as string
 	value, err := vm.Run(`JSON.stringify(conf)`)
 	if err != nil {
-		return nil, err
-	}
-	str, err := value.ToString()
-	if err != nil {
-		return nil, err
-	}
-	conf := &models.DNSConfig{}
-	if err = json.Unmarshal([]byte(str), conf); err != nil {
-		return nil, err
-	}
-
-	err = conf.PostProcess()
-	if err != nil {
-		return nil, err
+		return "", err
 	}
-	// No need to call FixLegacyDC here. These records were created from dnsconfig.js, not from a provider.
-
-	if err := rtypecontrol.ImportRawRecords(conf.Domains); err != nil {
-		return nil, err
-	}
-
-	return conf, nil
+	return value.ToString()
 }
 
 // GetHelpers returns the contents of helpers.js, or the embedded version.
@@ -154,13 +223,9 @@ func require(call otto.FunctionCall) otto.Value {
 		throw(call.Otto, "require takes exactly one argument")
 	}
 	file := call.Argument(0).String() // The filename as given by the user
-
-	// relFile is the file we're actually going to pass to ReadFile().
-	// It defaults to the user-provided name unless it is relative.
-	relFile := file
-	cleanFile := filepath.Clean(filepath.Join(currentDirectory, file))
-	if strings.HasPrefix(file, ".") {
-		relFile = cleanFile
+	relFile, cleanFile := requirePaths(file)
+	if isTypeScript(relFile) {
+		throw(call.Otto, fmt.Sprintf("%s: TypeScript requires --js-engine %s", file, EngineGoja))
 	}
 
 	// Record the old currentDirectory so that we can return there.
@@ -178,12 +243,15 @@ func require(call otto.FunctionCall) otto.Value {
 	value := otto.TrueValue()
 
 	// If its a json file return the json value, else default to true
-	ext := strings.ToLower(filepath.Ext(relFile))
-	if strings.HasSuffix(ext, "json") || strings.HasSuffix(ext, "json5") {
+	if isJSONFile(relFile) {
 		cmd := fmt.Sprintf(`JSON.parse(JSON.stringify(%s))`, string(data))
 		value, err = call.Otto.Run(cmd)
 	} else {
-		_, err = call.Otto.Run(string(data))
+		var script *otto.Script
+		script, err = call.Otto.Compile(positionName(cleanFile), data)
+		if err == nil {
+			_, err = call.Otto.Run(script)
+		}
 	}
 
 	if err != nil {
@@ -196,6 +264,38 @@ func require(call otto.FunctionCall) otto.Value {
 	return value
 }
 
+// requirePaths returns the file to read for require(file) (relFile) and
+// its clean path (cleanFile), which is relative to the directory of the
+// file that calls require().
+func requirePaths(file string) (relFile, cleanFile string) {
+	// relFile is the file we're actually going to pass to ReadFile().
+	// It defaults to the user-provided name unless it is relative.
+	relFile = file
+	cleanFile = filepath.Clean(filepath.Join(currentDirectory, file))
+	if strings.HasPrefix(file, ".") {
+		relFile = cleanFile
+	}
+	return relFile, cleanFile
+}
+
+// isJSONFile returns true if require() returns the content of file (rather
+// than running it).
+func isJSONFile(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	return strings.HasSuffix(ext, "json") || strings.HasSuffix(ext, "json5")
+}
+
+// positionName returns the name of file as it appears in positions: relative
+// to the directory of dnsconfig.js, if possible.
+func positionName(file string) string {
+	if configDirectory != "" {
+		if rel, err := filepath.Rel(configDirectory, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+	}
+	return filepath.ToSlash(file)
+}
+
 func listFiles(call otto.FunctionCall) otto.Value {
 	// Check amount of arguments provided
 	if len(call.ArgumentList) < 1 || len(call.ArgumentList) > 3 {
@@ -210,15 +310,6 @@ func listFiles(call otto.FunctionCall) otto.Value {
 		throw(call.Otto, "glob: first argument needs to be a path, provided as string.")
 	}
 	dir := call.Argument(0).String() // Path where to start listing
-	printer.Debugf("listFiles: cd: %s, user: %s \n", currentDirectory, dir)
-	// now we always prepend the current directory we're working in, which is being set within
-	// the func ExecuteJavascript() above. So when require("domains/load_all.js") is being used,
-	// where glob("customer1/") is being used, we basically search for files in domains/customer1/.
-	dir = filepath.ToSlash(filepath.Join(currentDirectory, dir))
-
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		throw(call.Otto, "glob: provided path does not exist.")
-	}
 
 	// Second: Recursive?
 	recursive := true
@@ -235,15 +326,43 @@ func listFiles(call otto.FunctionCall) otto.Value {
 	if call.Argument(2).IsDefined() && !call.Argument(2).IsNull() {
 		if call.Argument(2).IsString() {
 			fileExtension = call.Argument(2).String() // Which file extension to filter for.
-			if !strings.HasPrefix(fileExtension, ".") {
-				// If it doesn't start with a dot, probably user forgot it and we do it instead.
-				fileExtension = "." + fileExtension
-			}
 		} else {
 			throw(call.Otto, "glob: third argument, file extension, needs to be a string. * for no filter.")
 		}
 	}
 
+	files, err := globFiles(dir, recursive, fileExtension)
+	if err != nil {
+		throw(call.Otto, err.Error())
+	}
+
+	// let's pass the data back to the JS engine.
+	value, err := call.Otto.ToValue(files)
+	if err != nil {
+		throw(call.Otto, fmt.Sprintf("converting value failed: %v", err.Error()))
+	}
+
+	return value
+}
+
+// globFiles returns the files in dir (relative to the directory of the
+// file that calls glob()) whose extension is fileExtension ("*" for all).
+func globFiles(dir string, recursive bool, fileExtension string) ([]string, error) {
+	printer.Debugf("listFiles: cd: %s, user: %s \n", currentDirectory, dir)
+	// now we always prepend the current directory we're working in, which is being set within
+	// the func ExecuteJavascript() above. So when require("domains/load_all.js") is being used,
+	// where glob("customer1/") is being used, we basically search for files in domains/customer1/.
+	dir = filepath.ToSlash(filepath.Join(currentDirectory, dir))
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, errors.New("glob: provided path does not exist.")
+	}
+
+	if fileExtension != "*" && !strings.HasPrefix(fileExtension, ".") {
+		// If it doesn't start with a dot, probably user forgot it and we do it instead.
+		fileExtension = "." + fileExtension
+	}
+
 	// Now we're doing the actual work: Listing files.
 	// Folders are ending with a slash. Can be identified later on from the user with JavaScript.
 	// Additionally, when more smart logic required, user can use regex in JS.
@@ -272,16 +391,9 @@ func listFiles(call otto.FunctionCall) otto.Value {
 		return err
 	})
 	if err != nil {
-		throw(call.Otto, fmt.Sprintf("dirwalk failed: %v", err.Error()))
+		return nil, fmt.Errorf("dirwalk failed: %v", err.Error())
 	}
-
-	// let's pass the data back to the JS engine.
-	value, err := call.Otto.ToValue(files)
-	if err != nil {
-		throw(call.Otto, fmt.Sprintf("converting value failed: %v", err.Error()))
-	}
-
-	return value
+	return files, nil
 }
 
 func jsPanic(call otto.FunctionCall) otto.Value {
@@ -31,6 +31,24 @@ func init() {
 }
 
 func TestParsedFiles(t *testing.T) {
+	testParsedFiles(t)
+}
+
+// TestParsedFilesGoja runs the parse tests with goja, which must give the
+// same results as otto.
+func TestParsedFilesGoja(t *testing.T) {
+	setEngine(t, EngineGoja)
+	testParsedFiles(t)
+}
+
+// setEngine sets Engine until the end of the test t.
+func setEngine(t *testing.T, engine string) {
+	old := Engine
+	Engine = engine
+	t.Cleanup(func() { Engine = old })
+}
+
+func testParsedFiles(t *testing.T) {
 	files, err := os.ReadDir(testDir)
 	if err != nil {
 		t.Fatal(err)
@@ -173,3 +191,22 @@ func TestErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestCLIDefaults(t *testing.T) {
+	script := `CLI_DEFAULTS({ ip: "10.0.0.1", name: "a" });
+D("example.com", "reg", A(name, ip));
+`
+	for _, engine := range []string{EngineGoja, EngineOtto} {
+		t.Run(engine, func(t *testing.T) {
+			setEngine(t, engine)
+			conf, err := ExecuteJavascriptString([]byte(script), true, map[string]string{"ip": "10.0.0.2"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rc := conf.Domains[0].Records[0]
+			if rc.GetLabel() != "a" || rc.GetTargetField() != "10.0.0.2" {
+				t.Errorf("got %s %s, want a 10.0.0.2", rc.GetLabel(), rc.GetTargetField())
+			}
+		})
+	}
+}
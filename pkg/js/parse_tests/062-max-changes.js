@@ -0,0 +1,5 @@
+D("foo.com", "none",
+    MAX_CHANGES(50),
+    MAX_DELETES(5),
+    A("@", "1.2.3.4")
+);
@@ -0,0 +1,6 @@
+D("foo.com", "none",
+    ALLOW_DANGLING("legacy.cloudapp.net", "*.old.foo.com"),
+    ALLOW_DANGLING("**.github.io"),
+    CNAME("www", "legacy.cloudapp.net."),
+    CNAME("docs", "foo.github.io.")
+);
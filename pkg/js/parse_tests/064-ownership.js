@@ -0,0 +1,2 @@
+D("foo.com", "none", OWNERSHIP("team-a"), A("www", "1.2.3.4"));
+D("bar.com", "none", OWNERSHIP("team-b", "adopt"), A("www", "1.2.3.4"));
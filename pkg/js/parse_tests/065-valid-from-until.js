@@ -0,0 +1,6 @@
+D("example.com", "none",
+    A("new", "1.2.3.4", VALID_FROM("2025-06-20T02:00:00Z")),
+    A("old", "1.2.3.5", VALID_UNTIL("2025-06-20T02:00:00Z")),
+    A("future", "1.2.3.6", VALID_FROM("2999-01-01")),
+    TXT("promo", "spring sale", VALID_FROM("2025-03-01"), VALID_UNTIL(new Date(Date.UTC(2999, 0, 1))))
+);
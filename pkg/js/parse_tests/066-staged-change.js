@@ -0,0 +1,4 @@
+D("example.com", "none",
+    A("www", "1.2.3.4", TTL("1h"), STAGED_CHANGE()),
+    CNAME("api", "api.example.net.", STAGED_CHANGE("5m"))
+);
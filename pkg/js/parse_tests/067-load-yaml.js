@@ -0,0 +1,8 @@
+var REG = NewRegistrar("none");
+var DNS = NewDnsProvider("bind", "BIND");
+
+D("example.com", REG, DnsProvider(DNS),
+    A("@", "10.0.0.1"),
+);
+
+LOAD_YAML("declarative/*");
@@ -0,0 +1,4 @@
+var REG = NewRegistrar("none");
+var DNS = NewDnsProvider("bind", "BIND");
+
+LOAD_YAML("literals/*");
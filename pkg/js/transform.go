@@ -0,0 +1,113 @@
+package js
+
+// goja doesn't support ES modules (import and export) or TypeScript. A file
+// that uses them is transformed by esbuild: the types of TypeScript are
+// stripped, and an ES module becomes a CommonJS module, which runs in a
+// function of its own (see gojaRunner.runFile):
+//
+//	(function (exports, require, module) { "use strict"; ... })
+//
+// whose require() finds the files that the module imports (see
+// gojaRunner.importer), and whose exports are module.exports. A file that doesn't use import or export is a script,
+// which runs in the global scope.
+//
+// The positions in the JavaScript that esbuild generates are mapped back to
+// the source with the source map that esbuild generates too, so that the
+// positions of the records and of the errors are in the original file.
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/go-sourcemap/sourcemap"
+)
+
+// moduleHead is the start of the function that a module runs in.
+const moduleHead = `(function (exports, require, module) { "use strict"; `
+
+// isTypeScript returns true if file is TypeScript.
+func isTypeScript(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".ts", ".mts", ".cts":
+		return true
+	}
+	return false
+}
+
+// transformFile transforms src (whose name in positions is name) for goja.
+// It returns the code, its source map (nil if the code is src), and whether
+// it is an ES module, whose code is the body of a CommonJS module. A
+// JavaScript script is returned as it is.
+func transformFile(name, src string) (string, *sourcemap.Consumer, bool, error) {
+	loader, kind := api.LoaderJS, "SyntaxError"
+	if isTypeScript(name) {
+		loader, kind = api.LoaderTS, "TypeScript"
+	}
+	outfile := name + ".js"
+	result := api.Build(api.BuildOptions{
+		Stdin:       &api.StdinOptions{Contents: src, Sourcefile: name, Loader: loader},
+		Format:      api.FormatCommonJS,
+		Target:      api.ES2020,
+		Charset:     api.CharsetUTF8,
+		Sourcemap:   api.SourceMapExternal,
+		TsconfigRaw: "{}", // The settings in tsconfig.json are ignored.
+		Outfile:     outfile,
+		Metafile:    true,
+		LogLevel:    api.LogLevelSilent,
+	})
+	if len(result.Errors) != 0 {
+		var msgs []string
+		for _, m := range result.Errors {
+			if m.Location != nil {
+				msgs = append(msgs, fmt.Sprintf("%s:%d:%d: %s", name, m.Location.Line, m.Location.Column+1, m.Text))
+			} else {
+				msgs = append(msgs, fmt.Sprintf("%s: %s", name, m.Text))
+			}
+		}
+		return "", nil, false, fmt.Errorf("%s: %s", kind, strings.Join(msgs, "; "))
+	}
+
+	// The format of the input is "esm" if it uses import or export.
+	var meta struct {
+		Inputs map[string]struct {
+			Format string `json:"format"`
+		} `json:"inputs"`
+	}
+	if err := json.Unmarshal([]byte(result.Metafile), &meta); err != nil {
+		return "", nil, false, fmt.Errorf("%s: metafile: %w", name, err)
+	}
+	isModule := false
+	for _, in := range meta.Inputs {
+		isModule = isModule || in.Format == "esm"
+	}
+	if !isModule && loader == api.LoaderJS {
+		return src, nil, false, nil
+	}
+
+	var code, smap []byte
+	for _, f := range result.OutputFiles {
+		if strings.HasSuffix(f.Path, ".map") {
+			smap = f.Contents
+		} else {
+			code = f.Contents
+		}
+	}
+	sm, err := sourcemap.Parse(outfile+".map", smap)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("%s: source map: %w", name, err)
+	}
+	return string(code), sm, isModule, nil
+}
+
+// originalPosition returns the position in the source of the position
+// line:col of the JavaScript that sm maps.
+func originalPosition(sm *sourcemap.Consumer, line, col int) (int, int) {
+	// The columns of source maps start at 0.
+	if _, _, l, c, ok := sm.Source(line, col-1); ok {
+		return l, c + 1
+	}
+	return line, col
+}
@@ -0,0 +1,48 @@
+package js
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransformFile_module(t *testing.T) {
+	src := "import { a } from \"./x.js\";\n\nexport const b = a + 1;\nA(\"@\", b);\n"
+	code, sm, isModule, err := transformFile("m.js", src)
+	if err != nil || !isModule {
+		t.Fatalf("transformFile: %v, %v", isModule, err)
+	}
+	for _, want := range []string{`require("./x.js")`, "module.exports"} {
+		if !strings.Contains(code, want) {
+			t.Errorf("%q not in %q", want, code)
+		}
+	}
+	// The A() is mapped back to line 4.
+	lines := strings.Split(code, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, "A(") {
+			if l, c := originalPosition(sm, i+1, 1); l != 4 || c != 1 {
+				t.Errorf("got %d:%d, want 4:1", l, c)
+			}
+		}
+	}
+}
+
+func TestTransformFile_script(t *testing.T) {
+	src := "var x = { import: 1 }; x.import; D(\"export\");\nfunction f() { return require(\"./y.js\"); }"
+	code, sm, isModule, err := transformFile("s.js", src)
+	if err != nil || isModule || sm != nil || code != src {
+		t.Errorf("got %q, %v, %v, %v; want the script as it is", code, sm, isModule, err)
+	}
+}
+
+func TestTransformFile_errors(t *testing.T) {
+	for _, src := range []string{
+		"import { a } \"./x.js\";",
+		"import x from y;",
+		"var s = \"unterminated;\nexport default 1;",
+	} {
+		if _, _, _, err := transformFile("m.js", src); err == nil || !strings.HasPrefix(err.Error(), "SyntaxError: m.js:") {
+			t.Errorf("%q: got error %v", src, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package js
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTranspileTypeScript(t *testing.T) {
+	src := "interface Host {\n    name: string;\n    ip?: string;\n}\nconst h: Host = { name: \"a\" };\nD(\"example.com\", NewRegistrar(\"none\"), A(h.name, h.ip ?? \"10.0.0.1\"));\n"
+	code, sm, isModule, err := transformFile("t.ts", src)
+	if err != nil || isModule {
+		t.Fatalf("transformFile: %v, %v", isModule, err)
+	}
+	if strings.Contains(code, "interface") || strings.Contains(code, ": Host") {
+		t.Errorf("types not stripped: %q", code)
+	}
+	// The D() is on line 2 of the JavaScript, and on line 6 of the TypeScript.
+	lines := strings.Split(code, "\n")
+	if len(lines) < 2 || !strings.HasPrefix(lines[1], "D(") {
+		t.Fatalf("unexpected code: %q", code)
+	}
+	if l, c := originalPosition(sm, 2, 1); l != 6 || c != 1 {
+		t.Errorf("got %d:%d, want 6:1", l, c)
+	}
+}
+
+func TestTranspileTypeScript_errors(t *testing.T) {
+	_, _, _, err := transformFile("t.ts", "const x: number = ;")
+	if err == nil || !strings.HasPrefix(err.Error(), "TypeScript: t.ts:1:19: ") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestTypeScript(t *testing.T) {
+	setEngine(t, EngineGoja)
+	dir := writeFiles(t, map[string]string{
+		"dnsconfig.ts": `import { mkA, type Host } from "./lib/hosts.js";
+import { DOMAIN } from "./lib/domain";
+require("./lib/legacy.ts");
+
+const hosts: Host[] = [{ name: "www", ip: "10.0.0.80" }];
+
+D(DOMAIN, NewRegistrar("none"),
+    hosts.map(mkA),
+    TXT("t", legacy as string),
+);
+`,
+		"lib/hosts.ts": `export interface Host {
+    name: string;
+    ip: string;
+}
+
+export function mkA(h: Host): RecordModifier {
+    return A(h.name, h.ip);
+}
+`,
+		"lib/domain.ts": `export const DOMAIN: string = "example.com";`,
+		"lib/legacy.ts": `var legacy: string = "old";`,
+	})
+
+	conf, err := ExecuteJavaScript(filepath.Join(dir, "dnsconfig.ts"), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := recordsOf(t, conf, "example.com")
+	want := []string{
+		"www A 10.0.0.80 [lib/hosts.ts:7:12]",
+		"t TXT old [dnsconfig.ts:9:5]",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTypeScript_errors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dnsconfig.ts": "const n: number = 1;\nthrow new Error(`bad ${n}`);\n",
+		"syntax.ts":    "const n: number = ;\n",
+		"dnsconfig.js": `require("./lib.ts");`,
+		"lib.ts":       "var x: number = 1;",
+	})
+	tests := []struct{ desc, engine, file, want string }{
+		{"throw", EngineGoja, "dnsconfig.ts", "Error: bad 1 at dnsconfig.ts:2:7"},
+		{"syntax", EngineGoja, "syntax.ts", "TypeScript: syntax.ts:1:19"},
+		{"otto", EngineOtto, "dnsconfig.ts", "TypeScript requires --js-engine goja"},
+		{"otto require", EngineOtto, "dnsconfig.js", "TypeScript requires --js-engine goja"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			setEngine(t, tt.engine)
+			_, err := ExecuteJavaScript(filepath.Join(dir, tt.file), true, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
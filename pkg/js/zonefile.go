@@ -0,0 +1,215 @@
+package js
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/dnsrr"
+	"github.com/DNSControl/dnscontrol/v4/pkg/rtypeinfo"
+	dnsv1 "github.com/miekg/dns"
+	"github.com/robertkrimen/otto"
+)
+
+// Exposes readZoneFile to Javascript, for ZONEFILE().
+func readZoneFileFunc(call otto.FunctionCall) otto.Value {
+	if len(call.ArgumentList) != 4 {
+		throw(call.Otto, "readZoneFile takes exactly four arguments")
+	}
+	ttl, _ := call.Argument(3).ToInteger()
+	records, err := readZoneFile(call.Argument(0).String(), call.Argument(1).String(), call.Argument(2).String(), uint32(ttl))
+	if err != nil {
+		throw(call.Otto, err.Error())
+	}
+	v, _ := otto.ToValue(records)
+	return v
+}
+
+// zoneFileRecords are the records of a zone file, as given to ZONEFILE() in
+// helpers.js: records of the legacy types (as in the IR), and raw records
+// of the modern types (as the ones that rawrecordBuilder() makes).
+type zoneFileRecords struct {
+	Records    []*models.RecordConfig `json:"records"`
+	RawRecords []zoneFileRawRecord    `json:"rawrecords"`
+}
+
+type zoneFileRawRecord struct {
+	Type    string `json:"type"`
+	TTL     uint32 `json:"ttl"`
+	Args    []any  `json:"args"`
+	FilePos string `json:"filepos"`
+}
+
+// readZoneFile reads the zone file (relative to currentDirectory) whose
+// origin (until a $ORIGIN) is origin, and returns the records in it as
+// records of domain, as JSON zoneFileRecords. The records without a TTL
+// that no $TTL or previous TTL applies to get defaultTTL (or
+// models.DefaultTTL if it is 0). SOA
+// records, and NS records at the apex, are skipped: the DNS providers
+// manage them.
+func readZoneFile(file, origin, domain string, defaultTTL uint32) (string, error) {
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(currentDirectory, file)
+	}
+	fsys := &zoneFileFS{dir: filepath.Dir(path)}
+	f, err := fsys.Open(filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if defaultTTL == 0 {
+		defaultTTL = models.DefaultTTL
+	}
+	zr := zoneFileRecords{Records: []*models.RecordConfig{}, RawRecords: []zoneFileRawRecord{}}
+	zp := dnsv1.NewZoneParser(f, dnsv1.Fqdn(origin), filepath.Base(path))
+	zp.SetDefaultTTL(defaultTTL)
+	zp.SetIncludeAllowed(true)
+	zp.SetIncludeFS(fsys)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		position := fsys.position()
+		header := rr.Header()
+		rtype := dnsv1.TypeToString[header.Rrtype]
+		if rtype == "SOA" || (rtype == "NS" && strings.EqualFold(header.Name, dnsv1.Fqdn(domain))) {
+			continue
+		}
+
+		if rtypeinfo.IsModernType(rtype) {
+			var rc models.RecordConfig
+			rc.SetLabelFromFQDN(strings.TrimSuffix(header.Name, "."), domain)
+			args := []any{rc.GetLabel()}
+			for i := 1; i <= dnsv1.NumField(rr); i++ {
+				args = append(args, dnsv1.Field(rr, i))
+			}
+			zr.RawRecords = append(zr.RawRecords, zoneFileRawRecord{Type: rtype, TTL: header.Ttl, Args: args, FilePos: position})
+			continue
+		}
+
+		rc, err := dnsrr.RRtoRCTxtBug(rr, domain)
+		if err != nil {
+			return "", fmt.Errorf("%s: %s: %w", position, rr.String(), err)
+		}
+		rc.FilePos = position
+		zr.Records = append(zr.Records, &rc)
+	}
+	if err := zp.Err(); err != nil {
+		return "", err
+	}
+
+	out, err := json.Marshal(zr)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// zoneFileFS opens the files of a zone file (the file itself and the files
+// of its $INCLUDEs) in dir, which are named relative to dir, as
+// zoneFileReaders. The zone parser reads them byte by byte, so when it
+// returns a record, the reader that was read last is at its end.
+type zoneFileFS struct {
+	dir  string
+	last *zoneFileReader // The reader that was read last.
+}
+
+func (fsys *zoneFileFS) Open(name string) (fs.File, error) {
+	path := filepath.Join(fsys.dir, filepath.FromSlash(name))
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &zoneFileReader{File: f, br: bufio.NewReader(f), fsys: fsys, name: positionName(path), line: 1, lineStart: true}, nil
+}
+
+// position returns the position (file:line) of the entry of the zone file
+// that was read last.
+func (fsys *zoneFileFS) position() string {
+	if fsys.last == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", fsys.last.name, fsys.last.entryLine)
+}
+
+// zoneFileReader reads a zone file, and keeps track of the line where the
+// entry (a directive or a record, which can span several lines) that is
+// being read starts.
+type zoneFileReader struct {
+	*os.File
+	br   *bufio.Reader
+	fsys *zoneFileFS
+	name string // The name of the file in positions.
+
+	line      int  // The current line.
+	entryLine int  // The line where the current entry starts.
+	lineStart bool // Whether nothing but blanks was read since a new entry could start.
+	comment   bool
+	quoted    bool
+	escaped   bool
+	parens    int
+}
+
+func (r *zoneFileReader) Read(p []byte) (int, error) {
+	for i := range p {
+		b, err := r.ReadByte()
+		if err != nil {
+			return i, err
+		}
+		p[i] = b
+	}
+	return len(p), nil
+}
+
+func (r *zoneFileReader) ReadByte() (byte, error) {
+	b, err := r.br.ReadByte()
+	if err != nil {
+		return b, err
+	}
+	r.fsys.last = r
+	r.scan(b)
+	return b, nil
+}
+
+// scan updates the state of r after b was read.
+func (r *zoneFileReader) scan(b byte) {
+	if b == '\n' {
+		r.line++
+		r.comment, r.escaped = false, false
+		r.lineStart = r.parens == 0 && !r.quoted
+		return
+	}
+	if r.comment {
+		return
+	}
+	if !r.quoted && !r.escaped {
+		switch b {
+		case ' ', '\t', '\r':
+			return
+		case ';':
+			r.comment = true
+			return
+		}
+	}
+	if r.lineStart {
+		r.entryLine = r.line
+		r.lineStart = false
+	}
+	switch {
+	case r.escaped:
+		r.escaped = false
+	case b == '\\':
+		r.escaped = true
+	case b == '"':
+		r.quoted = !r.quoted
+	case r.quoted:
+	case b == '(':
+		r.parens++
+	case b == ')' && r.parens > 0:
+		r.parens--
+	}
+}
@@ -0,0 +1,165 @@
+package js
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+// recordsJSON returns the records of the domain domain in conf as JSON,
+// without their positions.
+func recordsJSON(t *testing.T, conf *models.DNSConfig, domain string) []string {
+	t.Helper()
+	for _, dc := range conf.Domains {
+		if dc.Name == domain {
+			var recs []string
+			for _, rc := range dc.Records {
+				rc := *rc
+				rc.FilePos = ""
+				rc.Provenance = nil
+				b, err := json.Marshal(&rc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				recs = append(recs, string(b))
+			}
+			return recs
+		}
+	}
+	t.Fatalf("domain %s not found", domain)
+	return nil
+}
+
+func TestZoneFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dnsconfig.js": `var REG = NewRegistrar("none");
+D("example.com", REG, ZONEFILE("zones/example.com.zone"));
+D("example.org", REG, DefaultTTL(600),
+    A("@", "192.0.2.1"),
+    CNAME("www", "example.org.", TTL(300)),
+    MX("@", 10, "mail.example.org."),
+    TXT("@", ["v=spf1 -all", "x"]),
+    SRV("_sip._tcp", 10, 60, 5060, "sip.example.org."),
+    CAA("@", "issue", "letsencrypt.org", CAA_CRITICAL),
+    NS("sub", "ns.other.net."),
+    A("mail", "192.0.2.25"),
+    AAAA("host.lab", "2001:db8::1"),
+    DS("@", 12345, 13, 2, "ABCDEF0123456789"),
+);
+D("example.net", REG);
+D_EXTEND("lab.example.net", ZONEFILE("zones/lab.zone"));
+`,
+		"zones/example.com.zone": `$TTL 600
+@          IN SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 300
+@          IN NS  ns1.example.com.
+@          IN A   192.0.2.1
+www    300 IN CNAME @
+@          IN MX  10 mail
+@          IN TXT "v=spf1 -all" "x"
+_sip._tcp  IN SRV 10 60 5060 sip.example.com.
+@          IN CAA 128 issue "letsencrypt.org"
+sub        IN NS  ns.other.net.
+$INCLUDE inc/mail.zone
+$ORIGIN lab.example.com.
+host       IN AAAA 2001:db8::1
+$ORIGIN example.com.
+@          IN DS  12345 13 2 ABCDEF0123456789
+`,
+		"zones/inc/mail.zone": "mail IN A 192.0.2.25\n",
+		"zones/lab.zone":      "host 60 IN A 192.0.2.7\n",
+	})
+	for _, engine := range []string{EngineGoja, EngineOtto} {
+		t.Run(engine, func(t *testing.T) {
+			setEngine(t, engine)
+			conf, err := ExecuteJavaScript(filepath.Join(dir, "dnsconfig.js"), true, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Join(recordsJSON(t, conf, "example.com"), "\n")
+			want := strings.ReplaceAll(strings.Join(recordsJSON(t, conf, "example.org"), "\n"), "example.org", "example.com")
+			if got != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+			got = strings.Join(recordsOf(t, conf, "example.com")[:1], "")
+			if want := "@ A 192.0.2.1 [zones/example.com.zone:4]"; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+			got = strings.Join(recordsOf(t, conf, "example.net"), "")
+			if want := "host.lab A 192.0.2.7 [zones/lab.zone:1]"; got != want {
+				t.Errorf("D_EXTEND: got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestZoneFile_positions(t *testing.T) {
+	setEngine(t, EngineGoja)
+	dir := writeFiles(t, map[string]string{
+		"dnsconfig.js": `D("example.com", "none", DefaultTTL(600), ZONEFILE("zones/example.com.zone"));`,
+		"zones/example.com.zone": `; No $TTL: the DefaultTTL() of the domain.
+@ IN SOA ns1.example.com. hostmaster.example.com. (
+        1 ; serial
+        7200 3600 1209600 300 )
+
+@       IN A   192.0.2.1
+        IN TXT "a ; b" ( "c"
+                 "d" )
+www 300 IN CNAME @
+$INCLUDE inc/mail.zone
+$GENERATE 1-2 host$ 120 A 192.0.2.$
+$TTL 60
+last    IN A   192.0.2.9
+`,
+		"zones/inc/mail.zone": "\n\nmail IN A 192.0.2.25\n",
+	})
+	conf, err := ExecuteJavaScript(filepath.Join(dir, "dnsconfig.js"), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rc := range conf.Domains[0].Records {
+		got = append(got, fmt.Sprintf("%s %s %d %s", rc.GetLabel(), rc.Type, rc.TTL, rc.FilePos))
+	}
+	want := []string{
+		"@ A 600 [zones/example.com.zone:6]",
+		"@ TXT 600 [zones/example.com.zone:7]",
+		"www CNAME 300 [zones/example.com.zone:9]",
+		"mail A 300 [zones/inc/mail.zone:3]",
+		"host1 A 120 [zones/example.com.zone:11]",
+		"host2 A 120 [zones/example.com.zone:11]",
+		"last A 60 [zones/example.com.zone:13]",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestZoneFile_errors(t *testing.T) {
+	setEngine(t, EngineGoja)
+	dir := writeFiles(t, map[string]string{
+		"syntax.js":    `D("example.com", "none", ZONEFILE("syntax.zone"));`,
+		"syntax.zone":  "@ IN A 192.0.2.1\nwww IN A 192.0.2.x\n",
+		"missing.js":   `D("example.com", "none", ZONEFILE("missing.zone"));`,
+		"convert.js":   `D("example.com", "none", ZONEFILE("convert.zone"));`,
+		"convert.zone": "@ 300 IN A 192.0.2.1\nwww 300 IN SPF \"v=spf1 -all\"\n",
+		"path.js":      `D("example.com", "none", ZONEFILE(1));`,
+	})
+	tests := []struct{ file, want string }{
+		{"syntax.js", "syntax.zone: dns: bad A A: \"192.0.2.x\" at line: 2:"},
+		{"convert.js", "convert.zone:2: www.example.com.\t300\tIN\tSPF\t\"v=spf1 -all\": "},
+		{"missing.js", "missing.zone: no such file"},
+		{"path.js", "ZONEFILE: the path must be a string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			_, err := ExecuteJavaScript(filepath.Join(dir, tt.file), true, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
 	"github.com/DNSControl/dnscontrol/v4/pkg/providers"
 	"github.com/DNSControl/dnscontrol/v4/pkg/transform"
 	dnsv1 "github.com/miekg/dns"
@@ -360,6 +361,11 @@ type Warning struct {
 	error
 }
 
+// NewWarning returns err as a Warning.
+func NewWarning(err error) Warning {
+	return Warning{err}
+}
+
 // ValidateAndNormalizeConfig performs and normalization and/or validation of the IR.
 func ValidateAndNormalizeConfig(config *models.DNSConfig) (errs []error) {
 	err := processSplitHorizonDomains(config)
@@ -367,6 +373,11 @@ func ValidateAndNormalizeConfig(config *models.DNSConfig) (errs []error) {
 		return []error{err}
 	}
 
+	// Remove the records that VALID_FROM() or VALID_UNTIL() exclude now.
+	if errs := applyValidity(config, Now()); len(errs) != 0 {
+		return errs
+	}
+
 	for _, domain := range config.Domains {
 		pTypes := []string{}
 		for _, provider := range domain.DNSProviderInstances {
@@ -577,6 +588,10 @@ func ValidateAndNormalizeConfig(config *models.DNSConfig) (errs []error) {
 		}
 		// Verify AutoDNSSEC is valid.
 		errs = append(errs, checkAutoDNSSEC(d)...)
+		// Check that the OWNERSHIP markers have valid names
+		if d.OwnerID != "" {
+			errs = append(errs, diff2.CheckOwnershipMarkers(d)...)
+		}
 	}
 
 	// At this point we've munged anything that needs to be munged, and
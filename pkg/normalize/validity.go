@@ -0,0 +1,79 @@
+package normalize
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+// Now returns the time at which VALID_FROM() and VALID_UNTIL() are
+// evaluated. It is replaced to simulate a run at another time (preview --at).
+var Now = time.Now
+
+// validityLayouts are the formats accepted by VALID_FROM(), VALID_UNTIL()
+// and preview --at. The ones without a time zone are in UTC.
+var validityLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTime parses a time in one of the formats accepted by VALID_FROM()
+// and VALID_UNTIL().
+func ParseTime(s string) (time.Time, error) {
+	for _, layout := range validityLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a valid time; use a format like \"2006-01-02T15:04:05Z\", \"2006-01-02T15:04\" (UTC) or \"2006-01-02\" (UTC)", s)
+}
+
+// applyValidity removes from each domain the records whose VALID_FROM() is
+// after now or whose VALID_UNTIL() is not after now.
+func applyValidity(config *models.DNSConfig, now time.Time) (errs []error) {
+	for _, domain := range config.Domains {
+		var kept models.Records
+		for _, rec := range domain.Records {
+			valid, err := validAt(rec, now)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s %s.%s: %w", rec.FilePos, rec.Type, rec.GetLabel(), domain.Name, err))
+				continue
+			}
+			if valid {
+				kept = append(kept, rec)
+			}
+		}
+		domain.Records = kept
+	}
+	return errs
+}
+
+// validAt returns true if rec is to be included at now.
+func validAt(rec *models.RecordConfig, now time.Time) (bool, error) {
+	var from, until time.Time
+	var err error
+	if s := rec.Metadata["valid_from"]; s != "" {
+		if from, err = ParseTime(s); err != nil {
+			return false, fmt.Errorf("VALID_FROM: %w", err)
+		}
+	}
+	if s := rec.Metadata["valid_until"]; s != "" {
+		if until, err = ParseTime(s); err != nil {
+			return false, fmt.Errorf("VALID_UNTIL: %w", err)
+		}
+	}
+	if !from.IsZero() && !until.IsZero() && !until.After(from) {
+		return false, fmt.Errorf("VALID_UNTIL(%q) is not after VALID_FROM(%q)", rec.Metadata["valid_until"], rec.Metadata["valid_from"])
+	}
+	if !from.IsZero() && now.Before(from) {
+		return false, nil
+	}
+	if !until.IsZero() && !now.Before(until) {
+		return false, nil
+	}
+	return true, nil
+}
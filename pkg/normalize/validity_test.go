@@ -0,0 +1,75 @@
+package normalize
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+func TestApplyValidity(t *testing.T) {
+	rec := func(label, from, until string) *models.RecordConfig {
+		r := &models.RecordConfig{Type: "A", Metadata: map[string]string{}}
+		r.SetLabel(label, "example.com")
+		if from != "" {
+			r.Metadata["valid_from"] = from
+		}
+		if until != "" {
+			r.Metadata["valid_until"] = until
+		}
+		return r
+	}
+	config := func() *models.DNSConfig {
+		return &models.DNSConfig{Domains: []*models.DomainConfig{{
+			Name: "example.com",
+			Records: models.Records{
+				rec("always", "", ""),
+				rec("new", "2025-06-20T02:00:00Z", ""),
+				rec("old", "", "2025-06-20T02:00:00Z"),
+				rec("window", "2025-06-19", "2025-06-21 12:00"),
+				rec("local", "2025-06-20T04:00:00+02:00", ""),
+			},
+		}}}
+	}
+	labels := func(c *models.DNSConfig) string {
+		var r []string
+		for _, rec := range c.Domains[0].Records {
+			r = append(r, rec.GetLabel())
+		}
+		return strings.Join(r, " ")
+	}
+
+	for _, tt := range []struct {
+		now  string
+		want string
+	}{
+		{"2025-06-18T00:00:00Z", "always old"},
+		{"2025-06-20T01:59:59Z", "always old window"},
+		{"2025-06-20T02:00:00Z", "always new window local"},
+		{"2025-06-21T12:00:00Z", "always new local"},
+	} {
+		now, err := ParseTime(tt.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := config()
+		if errs := applyValidity(c, now); len(errs) != 0 {
+			t.Fatalf("applyValidity(%s) errors: %v", tt.now, errs)
+		}
+		if got := labels(c); got != tt.want {
+			t.Errorf("applyValidity(%s) = %q, want %q", tt.now, got, tt.want)
+		}
+	}
+
+	c := &models.DNSConfig{Domains: []*models.DomainConfig{{
+		Name:    "example.com",
+		Records: models.Records{rec("bad", "tomorrow", ""), rec("backwards", "2025-06-21", "2025-06-20")},
+	}}}
+	errs := applyValidity(c, time.Now())
+	if len(errs) != 2 ||
+		!strings.Contains(errs[0].Error(), `VALID_FROM: "tomorrow" is not a valid time`) ||
+		!strings.Contains(errs[1].Error(), `VALID_UNTIL("2025-06-20") is not after VALID_FROM("2025-06-21")`) {
+		t.Errorf("applyValidity() errors = %v", errs)
+	}
+}
@@ -0,0 +1,297 @@
+// Package plan serializes the changes that "preview" found so that a later
+// "push" can verify that it is about to do exactly what was reviewed.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+)
+
+// Version is the version of the plan file format.
+const Version = 1
+
+// Plan is the machine-readable output of "preview --plan-out".
+type Plan struct {
+	Version int         `json:"version"`
+	Created time.Time   `json:"created"`
+	Zones   []*ZonePlan `json:"zones"`
+
+	mu sync.Mutex
+}
+
+// ZonePlan is the plan for one zone at one provider (or registrar).
+type ZonePlan struct {
+	Zone      string `json:"zone"`                // The zone's UniqueName.
+	Provider  string `json:"provider,omitempty"`  // The DNS provider's name (creds.json key).
+	Registrar string `json:"registrar,omitempty"` // The registrar's name (creds.json key).
+
+	// Existing is a snapshot of the records at the provider when the plan was made.
+	Existing []Record `json:"existing,omitempty"`
+	// Changes are the diff2 changes that turn Existing into the desired records.
+	Changes []Change `json:"changes,omitempty"`
+	// Corrections are the messages of corrections that are not generated by
+	// diff2 (registrar corrections).
+	Corrections []string `json:"corrections,omitempty"`
+}
+
+// Record is a DNS record as stored in a plan.
+type Record struct {
+	Name  string `json:"name"` // FQDN, without the trailing dot.
+	Type  string `json:"type"`
+	TTL   uint32 `json:"ttl"`
+	Value string `json:"value"` // The record's comparable rdata.
+}
+
+// Change is a diff2.Change as stored in a plan.
+type Change struct {
+	Verb string   `json:"verb"`
+	Name string   `json:"name"`
+	Type string   `json:"type"`
+	Old  []Record `json:"old,omitempty"`
+	New  []Record `json:"new,omitempty"`
+	Msgs []string `json:"msgs,omitempty"`
+}
+
+// New returns an empty plan.
+func New() *Plan {
+	return &Plan{Version: Version, Created: time.Now().UTC()}
+}
+
+// Add adds a ZonePlan to the plan in a thread-safe way.
+func (p *Plan) Add(zp *ZonePlan) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Zones = append(p.Zones, zp)
+}
+
+// Find returns the ZonePlan for zone at provider, or nil if there is none.
+func (p *Plan) Find(zone, provider string) *ZonePlan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, zp := range p.Zones {
+		if zp.Zone == zone && zp.Provider == provider {
+			return zp
+		}
+	}
+	return nil
+}
+
+// FindRegistrar returns the ZonePlan for zone at registrar, or nil if there is none.
+func (p *Plan) FindRegistrar(zone, registrar string) *ZonePlan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, zp := range p.Zones {
+		if zp.Zone == zone && zp.Registrar == registrar {
+			return zp
+		}
+	}
+	return nil
+}
+
+// ForProvider generates the ZonePlan for a zone at a DNS provider.
+func ForProvider(zone, provider string, existing models.Records, changes diff2.ChangeList) *ZonePlan {
+	return &ZonePlan{
+		Zone:     zone,
+		Provider: provider,
+		Existing: FromRecords(existing),
+		Changes:  FromChanges(changes),
+	}
+}
+
+// ForRegistrar generates the ZonePlan for a zone at a registrar.
+func ForRegistrar(zone, registrar string, corrections []string) *ZonePlan {
+	return &ZonePlan{
+		Zone:        zone,
+		Registrar:   registrar,
+		Corrections: corrections,
+	}
+}
+
+// FromRecords converts models.Records to a sorted list of Record.
+func FromRecords(recs models.Records) []Record {
+	if len(recs) == 0 {
+		return nil
+	}
+	r := make([]Record, 0, len(recs))
+	for _, rec := range recs {
+		r = append(r, Record{
+			Name:  rec.NameFQDN,
+			Type:  rec.Type,
+			TTL:   rec.TTL,
+			Value: rec.ToComparableNoTTL(),
+		})
+	}
+	sortRecords(r)
+	return r
+}
+
+// FromChanges converts a diff2.ChangeList to a list of Change. REPORTs are
+// not actions and are therefore skipped.
+func FromChanges(changes diff2.ChangeList) []Change {
+	var r []Change
+	for _, c := range changes {
+		if c.Type == diff2.REPORT {
+			continue
+		}
+		r = append(r, Change{
+			Verb: c.Type.String(),
+			Name: c.Key.NameFQDN,
+			Type: c.Key.Type,
+			Old:  FromRecords(c.Old),
+			New:  FromRecords(c.New),
+			Msgs: stripMsgs(c.Msgs),
+		})
+	}
+	slices.SortStableFunc(r, func(a, b Change) int {
+		return strings.Compare(a.sortKey(), b.sortKey())
+	})
+	return r
+}
+
+// Write writes the plan to filename as JSON.
+func (p *Plan) Write(filename string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	slices.SortStableFunc(p.Zones, func(a, b *ZonePlan) int {
+		return strings.Compare(a.sortKey(), b.sortKey())
+	})
+
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(p)
+}
+
+// Read reads a plan that was written by Write.
+func Read(filename string) (*Plan, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	p := &Plan{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("plan %q: %w", filename, err)
+	}
+	if p.Version != Version {
+		return nil, fmt.Errorf("plan %q: unsupported version %d (expected %d)", filename, p.Version, Version)
+	}
+	return p, nil
+}
+
+// CheckExisting returns an error if existing (the live zone) does not match
+// the snapshot stored in the plan.
+func (zp *ZonePlan) CheckExisting(existing models.Records) error {
+	if diffs := diffRecords(zp.Existing, FromRecords(existing)); len(diffs) != 0 {
+		return fmt.Errorf("zone %q at %q changed since the plan was made:\n%s", zp.Zone, zp.Provider, strings.Join(diffs, "\n"))
+	}
+	return nil
+}
+
+// CheckChanges returns an error if changes do not match the changes stored in the plan.
+func (zp *ZonePlan) CheckChanges(changes diff2.ChangeList) error {
+	return zp.checkChanges(FromChanges(changes))
+}
+
+func (zp *ZonePlan) checkChanges(live []Change) error {
+	planned := map[string]Change{}
+	for _, c := range zp.Changes {
+		planned[c.sortKey()] = c
+	}
+	var diffs []string
+	for _, c := range live {
+		k := c.sortKey()
+		p, ok := planned[k]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("  unplanned %s", k))
+			continue
+		}
+		delete(planned, k)
+		if !slices.Equal(p.Old, c.Old) || !slices.Equal(p.New, c.New) {
+			diffs = append(diffs, fmt.Sprintf("  different %s", k))
+		}
+	}
+	for k := range planned {
+		diffs = append(diffs, fmt.Sprintf("  missing %s", k))
+	}
+	if len(diffs) != 0 {
+		slices.Sort(diffs)
+		return fmt.Errorf("changes for zone %q at %q do not match the plan:\n%s", zp.Zone, zp.Provider, strings.Join(diffs, "\n"))
+	}
+	return nil
+}
+
+// CheckCorrections returns an error if the correction messages do not match the plan.
+func (zp *ZonePlan) CheckCorrections(corrections []string) error {
+	if !slices.Equal(zp.Corrections, corrections) {
+		return fmt.Errorf("corrections for zone %q at %q do not match the plan: planned %q, found %q", zp.Zone, zp.Registrar, zp.Corrections, corrections)
+	}
+	return nil
+}
+
+// String returns the record in a format similar to a zonefile line.
+func (r Record) String() string {
+	return fmt.Sprintf("%s %d %s %s", r.Name, r.TTL, r.Type, r.Value)
+}
+
+func (c Change) sortKey() string {
+	return c.Name + ":" + c.Type + ":" + c.Verb
+}
+
+func (zp *ZonePlan) sortKey() string {
+	return zp.Zone + "\t" + zp.Provider + "\t" + zp.Registrar
+}
+
+func sortRecords(r []Record) {
+	slices.SortFunc(r, func(a, b Record) int {
+		return strings.Compare(a.String(), b.String())
+	})
+}
+
+// diffRecords returns a human-readable list of the differences between a and b.
+// Both lists must be sorted.
+func diffRecords(a, b []Record) []string {
+	var diffs []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i].String() < b[j].String()):
+			diffs = append(diffs, "  - "+a[i].String())
+			i++
+		case i == len(a) || (j < len(b) && a[i].String() > b[j].String()):
+			diffs = append(diffs, "  + "+b[j].String())
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return diffs
+}
+
+// stripMsgs removes the terminal formatting from diff2 messages.
+func stripMsgs(msgs []string) []string {
+	if len(msgs) == 0 {
+		return nil
+	}
+	r := make([]string, len(msgs))
+	for i, m := range msgs {
+		r[i] = printer.StripColors(m)
+	}
+	return r
+}
@@ -0,0 +1,116 @@
+package plan
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+)
+
+func makeRec(label, rtype, content string) *models.RecordConfig {
+	origin := "example.com"
+	r := models.RecordConfig{TTL: 300}
+	r.SetLabel(label, origin)
+	if err := r.PopulateFromString(rtype, content, origin); err != nil {
+		panic(err)
+	}
+	return &r
+}
+
+func makeChanges(t *testing.T, existing, desired models.Records) diff2.ChangeList {
+	t.Helper()
+	dc := &models.DomainConfig{Name: "example.com", Records: desired}
+	changes, _, err := diff2.ByRecordSetUnordered(existing, dc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return changes
+}
+
+func TestRoundTrip(t *testing.T) {
+	existing := models.Records{makeRec("www", "A", "1.2.3.4"), makeRec("old", "A", "5.6.7.8")}
+	desired := models.Records{makeRec("www", "A", "1.2.3.5"), makeRec("new", "CNAME", "www")}
+	changes := makeChanges(t, existing, desired)
+
+	p := New()
+	p.Add(ForProvider("example.com", "bind", existing, changes))
+	p.Add(ForRegistrar("example.com", "none", []string{"Update nameservers"}))
+
+	filename := filepath.Join(t.TempDir(), "plan.json")
+	if err := p.Write(filename); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zp := got.Find("example.com", "bind")
+	if zp == nil {
+		t.Fatal("zone plan not found")
+	}
+	if len(zp.Changes) != 3 {
+		t.Errorf("expected 3 changes, got %d: %+v", len(zp.Changes), zp.Changes)
+	}
+	if err := zp.CheckExisting(existing); err != nil {
+		t.Errorf("CheckExisting: unexpected error: %s", err)
+	}
+	if err := zp.CheckChanges(changes); err != nil {
+		t.Errorf("CheckChanges: unexpected error: %s", err)
+	}
+
+	rp := got.FindRegistrar("example.com", "none")
+	if rp == nil {
+		t.Fatal("registrar plan not found")
+	}
+	if err := rp.CheckCorrections([]string{"Update nameservers"}); err != nil {
+		t.Errorf("CheckCorrections: unexpected error: %s", err)
+	}
+}
+
+func TestCheckExisting(t *testing.T) {
+	existing := models.Records{makeRec("www", "A", "1.2.3.4")}
+	zp := ForProvider("example.com", "bind", existing, nil)
+
+	live := models.Records{makeRec("www", "A", "1.2.3.4"), makeRec("sneaky", "A", "9.9.9.9")}
+	err := zp.CheckExisting(live)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "+ sneaky.example.com 300 A 9.9.9.9") {
+		t.Errorf("error does not describe the difference: %s", err)
+	}
+}
+
+func TestCheckChanges(t *testing.T) {
+	existing := models.Records{makeRec("www", "A", "1.2.3.4")}
+	planned := makeChanges(t, existing, models.Records{makeRec("www", "A", "1.2.3.5")})
+	zp := ForProvider("example.com", "bind", existing, planned)
+
+	tests := []struct {
+		name    string
+		desired models.Records
+		wantErr string
+	}{
+		{"same", models.Records{makeRec("www", "A", "1.2.3.5")}, ""},
+		{"different", models.Records{makeRec("www", "A", "1.2.3.6")}, "different www.example.com:A:CHANGE"},
+		{"unplanned", models.Records{makeRec("www", "A", "1.2.3.5"), makeRec("api", "A", "1.2.3.5")}, "unplanned api.example.com:A:CREATE"},
+		{"missing", models.Records{makeRec("www", "A", "1.2.3.4")}, "missing www.example.com:A:CHANGE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := zp.CheckChanges(makeChanges(t, existing, tt.desired))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
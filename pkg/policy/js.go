@@ -0,0 +1,174 @@
+package policy
+
+// A .js policy file is run by the JavaScript engine that runs dnsconfig.js
+// (js.Engine): otto (ES5) by default, or goja (modern JavaScript). Rules
+// are declared with
+//
+//	RULE(name, options)
+//	RULE(name, function (zone) { ... })
+//	RULE(name, options, function (zone) { ... })
+//
+// where options has the same fields as a Rule. The function is called with
+// each zone (in the same format as "dnscontrol print-ir") and returns
+// nothing, a message or a list of messages.
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/DNSControl/dnscontrol/v4/pkg/js"
+	"github.com/dop251/goja"
+	"github.com/robertkrimen/otto"
+)
+
+// ruleFunc runs a rule defined by a JavaScript function with a zone (as
+// JSON), and returns the messages the function returned.
+type ruleFunc func(zone []byte) ([]string, error)
+
+const ruleUsage = "RULE(name, options, function) requires a name and options or a function"
+
+// parseJS runs a .js policy file.
+func parseJS(name, script string) (*Policy, error) {
+	if js.Engine == js.EngineOtto {
+		return parseOtto(name, script)
+	}
+	return parseGoja(name, script)
+}
+
+// parseGoja runs a .js policy file with goja.
+func parseGoja(name, script string) (*Policy, error) {
+	p := &Policy{}
+	vm := goja.New()
+	parse, _ := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("parse"))
+	err := vm.Set("RULE", func(call goja.FunctionCall) goja.Value {
+		args := call.Arguments
+		if len(args) < 2 {
+			panic(vm.NewTypeError(ruleUsage))
+		}
+		if _, ok := args[0].Export().(string); !ok {
+			panic(vm.NewTypeError(ruleUsage))
+		}
+		r := &Rule{}
+		if _, isFunc := goja.AssertFunction(args[1]); !isFunc && isObject(args[1]) {
+			if err := exportTo(args[1].Export(), r); err != nil {
+				panic(vm.NewTypeError(fmt.Sprintf("RULE(%q): %s", args[0].String(), err)))
+			}
+		}
+		if fn, ok := goja.AssertFunction(args[len(args)-1]); ok {
+			r.fn = func(zone []byte) ([]string, error) {
+				z, err := parse(goja.Undefined(), vm.ToValue(string(zone)))
+				if err != nil {
+					return nil, err
+				}
+				result, err := fn(goja.Null(), z)
+				if err != nil {
+					return nil, fmt.Errorf("rule failed: %w", err)
+				}
+				if goja.IsUndefined(result) || goja.IsNull(result) {
+					return nil, nil
+				}
+				if obj, ok := result.(*goja.Object); ok && obj.ClassName() == "Array" {
+					var msgs []string
+					if err := exportTo(result.Export(), &msgs); err != nil {
+						return nil, fmt.Errorf("rule must return a string or a list of strings: %w", err)
+					}
+					return msgs, nil
+				}
+				return []string{result.String()}, nil
+			}
+		}
+		r.Name = args[0].String()
+		p.Rules = append(p.Rules, r)
+		return goja.Undefined()
+	})
+	if err != nil {
+		return nil, err
+	}
+	prg, err := goja.Compile(name, script, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := vm.RunProgram(prg); err != nil {
+		return nil, err
+	}
+	return p, p.validate()
+}
+
+// parseOtto runs a .js policy file with otto.
+func parseOtto(name, script string) (*Policy, error) {
+	p := &Policy{}
+	vm := otto.New()
+	err := vm.Set("RULE", func(call otto.FunctionCall) otto.Value {
+		args := call.ArgumentList
+		if len(args) < 2 || !args[0].IsString() {
+			panic(call.Otto.MakeTypeError(ruleUsage))
+		}
+		r := &Rule{}
+		if args[1].IsObject() && args[1].Class() != "Function" {
+			if err := exportObject(call.Otto, args[1], r); err != nil {
+				panic(call.Otto.MakeTypeError(fmt.Sprintf("RULE(%q): %s", args[0].String(), err)))
+			}
+		}
+		if fn := args[len(args)-1]; fn.IsFunction() {
+			r.fn = func(zone []byte) ([]string, error) {
+				z, err := vm.Call("JSON.parse", nil, string(zone))
+				if err != nil {
+					return nil, err
+				}
+				result, err := fn.Call(otto.NullValue(), z)
+				if err != nil {
+					return nil, fmt.Errorf("rule failed: %w", err)
+				}
+				switch {
+				case result.IsUndefined() || result.IsNull():
+					return nil, nil
+				case result.Class() == "Array":
+					var msgs []string
+					if err := exportObject(vm, result, &msgs); err != nil {
+						return nil, fmt.Errorf("rule must return a string or a list of strings: %w", err)
+					}
+					return msgs, nil
+				}
+				return []string{result.String()}, nil
+			}
+		}
+		r.Name = args[0].String()
+		p.Rules = append(p.Rules, r)
+		return otto.UndefinedValue()
+	})
+	if err != nil {
+		return nil, err
+	}
+	s, err := vm.Compile(name, script)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := vm.Run(s); err != nil {
+		return nil, err
+	}
+	return p, p.validate()
+}
+
+// exportObject converts the JavaScript object v to the Go value dst.
+func exportObject(vm *otto.Otto, v otto.Value, dst any) error {
+	j, err := vm.Call("JSON.stringify", nil, v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(j.String()), dst)
+}
+
+// isObject returns true if v is a JavaScript object.
+func isObject(v goja.Value) bool {
+	_, ok := v.(*goja.Object)
+	return ok
+}
+
+// exportTo converts v (an exported JavaScript value) to the Go value dst.
+func exportTo(v any, dst any) error {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(j, dst)
+}
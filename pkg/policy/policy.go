@@ -0,0 +1,288 @@
+// Package policy evaluates organisational policies ("every zone must have
+// CAA records", "no wildcard MX records", etc.) against the normalized
+// DNSConfig. The rules are read from a JSON, YAML or JavaScript file.
+package policy
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"gopkg.in/yaml.v3"
+)
+
+// The checks a Rule can perform.
+const (
+	CheckRequire      = "require"       // The zone must have at least one matching record.
+	CheckForbid       = "forbid"        // Matching records are not allowed.
+	CheckMinTTL       = "min_ttl"       // Matching records must have a TTL of at least TTL.
+	CheckMaxTTL       = "max_ttl"       // Matching records must have a TTL of at most TTL.
+	CheckTargetSuffix = "target_suffix" // The target of matching records must be in one of Suffixes.
+)
+
+// The levels of a Rule.
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+)
+
+// Rule is one policy rule.
+type Rule struct {
+	Name    string `json:"name" yaml:"name"`
+	Level   string `json:"level,omitempty" yaml:"level,omitempty"`     // LevelError (default) or LevelWarning.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"` // Replaces the description of a violation.
+	Check   string `json:"check,omitempty" yaml:"check,omitempty"`     // One of the Check* constants.
+
+	// Filters. A rule applies to the records (and, for CheckRequire, the
+	// zones) that match all of them. Patterns are matched with path.Match.
+	Zones    []string `json:"zones,omitempty" yaml:"zones,omitempty"`   // Patterns of zone names. Default: all.
+	Labels   []string `json:"labels,omitempty" yaml:"labels,omitempty"` // Patterns of short names ("@" is the apex). Default: all.
+	Types    []string `json:"types,omitempty" yaml:"types,omitempty"`   // Record types. Default: all (CNAME for CheckTargetSuffix).
+	Wildcard bool     `json:"wildcard,omitempty" yaml:"wildcard,omitempty"`
+
+	TTL      uint32   `json:"ttl,omitempty" yaml:"ttl,omitempty"`           // CheckMinTTL, CheckMaxTTL
+	Suffixes []string `json:"suffixes,omitempty" yaml:"suffixes,omitempty"` // CheckTargetSuffix
+
+	fn ruleFunc // Rules defined by a function in a .js file.
+}
+
+// File is the format of a JSON or YAML policy file.
+type File struct {
+	Rules []*Rule `json:"rules" yaml:"rules"`
+}
+
+// Policy is a set of rules.
+type Policy struct {
+	Rules []*Rule
+}
+
+// Violation is a record or zone that does not comply with a rule.
+type Violation struct {
+	Rule    string
+	Warning bool
+	Zone    string
+	Record  string // The record ("www.example.com A 1.2.3.4"), if the violation is about one.
+	FilePos string // Where the record is defined.
+	Message string
+}
+
+func (v Violation) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "policy %q: %s", v.Rule, v.Zone)
+	if v.Record != "" {
+		fmt.Fprintf(&b, ": %s", v.Record)
+	}
+	if v.FilePos != "" {
+		fmt.Fprintf(&b, " %s", v.FilePos)
+	}
+	fmt.Fprintf(&b, ": %s", v.Message)
+	return b.String()
+}
+
+// Load reads a policy file. The format is determined by the extension:
+// .json, .yaml (or .yml) or .js.
+func Load(filename string) (*Policy, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var p *Policy
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".json":
+		p, err = parseData(b, json.Unmarshal)
+	case ".yaml", ".yml":
+		p, err = parseData(b, yaml.Unmarshal)
+	case ".js":
+		p, err = parseJS(filepath.Base(filename), string(b))
+	default:
+		return nil, fmt.Errorf("policy file %q: unknown extension %q (expected .json, .yaml, .yml or .js)", filename, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("policy file %q: %w", filename, err)
+	}
+	return p, nil
+}
+
+func parseData(b []byte, unmarshal func([]byte, any) error) (*Policy, error) {
+	var f File
+	if err := unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	p := &Policy{Rules: f.Rules}
+	return p, p.validate()
+}
+
+func (p *Policy) validate() error {
+	var errs []error
+	for i, r := range p.Rules {
+		if r.Name == "" {
+			errs = append(errs, fmt.Errorf("rule #%d: no name", i+1))
+			continue
+		}
+		if r.Level == "" {
+			r.Level = LevelError
+		}
+		if r.Level != LevelError && r.Level != LevelWarning {
+			errs = append(errs, fmt.Errorf("rule %q: level %q is not %q or %q", r.Name, r.Level, LevelError, LevelWarning))
+		}
+		for _, pat := range slices.Concat(r.Zones, r.Labels) {
+			if _, err := path.Match(pat, ""); err != nil {
+				errs = append(errs, fmt.Errorf("rule %q: pattern %q: %w", r.Name, pat, err))
+			}
+		}
+		for i, t := range r.Types {
+			r.Types[i] = strings.ToUpper(t)
+		}
+		if r.fn != nil {
+			if r.Check != "" {
+				errs = append(errs, fmt.Errorf("rule %q: has both a check and a function", r.Name))
+			}
+			continue
+		}
+		switch r.Check {
+		case CheckRequire, CheckForbid:
+		case CheckMinTTL, CheckMaxTTL:
+			if r.TTL == 0 {
+				errs = append(errs, fmt.Errorf("rule %q: %s requires a ttl", r.Name, r.Check))
+			}
+		case CheckTargetSuffix:
+			if len(r.Suffixes) == 0 {
+				errs = append(errs, fmt.Errorf("rule %q: %s requires suffixes", r.Name, r.Check))
+			}
+			if len(r.Types) == 0 {
+				r.Types = []string{"CNAME"}
+			}
+		case "":
+			errs = append(errs, fmt.Errorf("rule %q: no check", r.Name))
+		default:
+			errs = append(errs, fmt.Errorf("rule %q: unknown check %q", r.Name, r.Check))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Evaluate returns the violations of the policy by the zones of cfg.
+func (p *Policy) Evaluate(cfg *models.DNSConfig) []Violation {
+	var vs []Violation
+	for _, dc := range cfg.Domains {
+		for _, r := range p.Rules {
+			if !matchAny(r.Zones, dc.Name) {
+				continue
+			}
+			if r.fn != nil {
+				vs = append(vs, r.evaluateFunc(dc)...)
+			} else {
+				vs = append(vs, r.evaluate(dc)...)
+			}
+		}
+	}
+	return vs
+}
+
+func (r *Rule) evaluate(dc *models.DomainConfig) []Violation {
+	var vs []Violation
+	violation := func(rec *models.RecordConfig, msg string) {
+		v := Violation{Rule: r.Name, Warning: r.Level == LevelWarning, Zone: dc.GetUniqueName(), Message: cmp.Or(r.Message, msg)}
+		if rec != nil {
+			v.Record = fmt.Sprintf("%s %s %s", rec.GetLabelFQDN(), rec.Type, rec.ToComparableNoTTL())
+			v.FilePos = rec.FilePos
+		}
+		vs = append(vs, v)
+	}
+
+	var found bool
+	for _, rec := range dc.Records {
+		if !r.matches(rec) {
+			continue
+		}
+		found = true
+		switch r.Check {
+		case CheckForbid:
+			violation(rec, "record is not allowed")
+		case CheckMinTTL:
+			if rec.TTL < r.TTL {
+				violation(rec, fmt.Sprintf("TTL %d is less than %d", rec.TTL, r.TTL))
+			}
+		case CheckMaxTTL:
+			if rec.TTL > r.TTL {
+				violation(rec, fmt.Sprintf("TTL %d is more than %d", rec.TTL, r.TTL))
+			}
+		case CheckTargetSuffix:
+			if !hasSuffix(rec.GetTargetField(), r.Suffixes) {
+				violation(rec, fmt.Sprintf("target is not in %s", strings.Join(r.Suffixes, ", ")))
+			}
+		}
+	}
+	if r.Check == CheckRequire && !found {
+		what := "records"
+		if len(r.Types) != 0 {
+			what = strings.Join(r.Types, "/") + " records"
+		}
+		violation(nil, "zone has no "+what)
+	}
+	return vs
+}
+
+// matches returns true if rec passes the filters of r.
+func (r *Rule) matches(rec *models.RecordConfig) bool {
+	if len(r.Types) != 0 && !slices.Contains(r.Types, rec.Type) {
+		return false
+	}
+	if r.Wildcard && !strings.HasPrefix(rec.GetLabel(), "*") {
+		return false
+	}
+	return matchAny(r.Labels, rec.GetLabel())
+}
+
+// evaluateFunc runs a rule defined by a JavaScript function.
+func (r *Rule) evaluateFunc(dc *models.DomainConfig) []Violation {
+	violation := func(msg string) Violation {
+		return Violation{Rule: r.Name, Warning: r.Level == LevelWarning, Zone: dc.GetUniqueName(), Message: cmp.Or(r.Message, msg)}
+	}
+
+	zone, err := json.Marshal(dc)
+	if err != nil {
+		return []Violation{violation(err.Error())}
+	}
+	msgs, err := r.fn(zone)
+	if err != nil {
+		return []Violation{violation(err.Error())}
+	}
+
+	var vs []Violation
+	for _, m := range msgs {
+		if m != "" {
+			vs = append(vs, violation(m))
+		}
+	}
+	return vs
+}
+
+// matchAny returns true if patterns is empty or s matches one of them.
+func matchAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	s = strings.ToLower(s)
+	return slices.ContainsFunc(patterns, func(pat string) bool {
+		ok, _ := path.Match(strings.ToLower(pat), s)
+		return ok
+	})
+}
+
+// hasSuffix returns true if the hostname target is one of suffixes or a
+// subdomain of one of them.
+func hasSuffix(target string, suffixes []string) bool {
+	target = strings.ToLower(strings.TrimSuffix(target, "."))
+	return slices.ContainsFunc(suffixes, func(suffix string) bool {
+		suffix = strings.ToLower(strings.Trim(suffix, "."))
+		return target == suffix || strings.HasSuffix(target, "."+suffix)
+	})
+}
@@ -0,0 +1,252 @@
+package policy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/js"
+	"gopkg.in/yaml.v3"
+)
+
+func makeRec(label, rtype, content string, ttl uint32) *models.RecordConfig {
+	r := &models.RecordConfig{TTL: ttl}
+	r.SetLabel(label, "example.com")
+	if err := r.PopulateFromString(rtype, content, "example.com"); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func testConfig() *models.DNSConfig {
+	dc := &models.DomainConfig{
+		Name: "example.com",
+		Records: models.Records{
+			makeRec("@", "A", "1.2.3.4", 30),
+			makeRec("@", "MX", "10 mx.example.com.", 300),
+			makeRec("*", "MX", "10 mx.example.com.", 300),
+			makeRec("www", "CNAME", "lb.example.net.", 300),
+			makeRec("shop", "CNAME", "shops.myshopify.com.", 300),
+		},
+	}
+	dc.Records[4].FilePos = "[dnsconfig.js:7:5]"
+	dc.PostProcess()
+	return &models.DNSConfig{Domains: []*models.DomainConfig{dc}}
+}
+
+func errorStrings(vs []Violation) []string {
+	var r []string
+	for _, v := range vs {
+		s := v.Error()
+		if v.Warning {
+			s = "WARNING: " + s
+		}
+		r = append(r, s)
+	}
+	return r
+}
+
+func checkViolations(t *testing.T, p *Policy, want []string) {
+	t.Helper()
+	got := errorStrings(p.Evaluate(testConfig()))
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Evaluate():\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		want []string
+	}{
+		{
+			"require missing",
+			Rule{Name: "caa", Check: CheckRequire, Types: []string{"caa"}},
+			[]string{`policy "caa": example.com: zone has no CAA records`},
+		},
+		{
+			"require present",
+			Rule{Name: "mx", Check: CheckRequire, Types: []string{"MX"}},
+			nil,
+		},
+		{
+			"require other zones",
+			Rule{Name: "caa", Check: CheckRequire, Types: []string{"CAA"}, Zones: []string{"*.org"}},
+			nil,
+		},
+		{
+			"forbid wildcard",
+			Rule{Name: "no-wildcard-mx", Check: CheckForbid, Types: []string{"MX"}, Wildcard: true, Level: LevelWarning},
+			[]string{`WARNING: policy "no-wildcard-mx": example.com: *.example.com MX 10 mx.example.com.: record is not allowed`},
+		},
+		{
+			"min ttl apex",
+			Rule{Name: "apex-ttl", Check: CheckMinTTL, TTL: 60, Labels: []string{"@"}},
+			[]string{`policy "apex-ttl": example.com: example.com A 1.2.3.4: TTL 30 is less than 60`},
+		},
+		{
+			"max ttl",
+			Rule{Name: "max-ttl", Check: CheckMaxTTL, TTL: 100, Types: []string{"A", "CNAME"}, Message: "TTL is too long"},
+			[]string{
+				`policy "max-ttl": example.com: www.example.com CNAME lb.example.net.: TTL is too long`,
+				`policy "max-ttl": example.com: shop.example.com CNAME shops.myshopify.com. [dnsconfig.js:7:5]: TTL is too long`,
+			},
+		},
+		{
+			"target suffix",
+			Rule{Name: "cname", Check: CheckTargetSuffix, Suffixes: []string{"example.net."}},
+			[]string{`policy "cname": example.com: shop.example.com CNAME shops.myshopify.com. [dnsconfig.js:7:5]: target is not in example.net.`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Policy{Rules: []*Rule{&tt.rule}}
+			if err := p.validate(); err != nil {
+				t.Fatal(err)
+			}
+			checkViolations(t, p, tt.want)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		rule    Rule
+		wantErr string
+	}{
+		{Rule{Check: CheckForbid}, "no name"},
+		{Rule{Name: "x"}, "no check"},
+		{Rule{Name: "x", Check: "nope"}, `unknown check "nope"`},
+		{Rule{Name: "x", Check: CheckMinTTL}, "requires a ttl"},
+		{Rule{Name: "x", Check: CheckTargetSuffix}, "requires suffixes"},
+		{Rule{Name: "x", Check: CheckForbid, Level: "fatal"}, `level "fatal"`},
+		{Rule{Name: "x", Check: CheckForbid, Labels: []string{"["}}, `pattern "["`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantErr, func(t *testing.T) {
+			err := (&Policy{Rules: []*Rule{&tt.rule}}).validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	f := File{Rules: []*Rule{
+		{Name: "caa", Check: CheckRequire, Types: []string{"CAA"}},
+		{Name: "apex-ttl", Level: LevelWarning, Check: CheckMinTTL, TTL: 60, Labels: []string{"@"}},
+	}}
+	want := []string{
+		`policy "caa": example.com: zone has no CAA records`,
+		`WARNING: policy "apex-ttl": example.com: example.com A 1.2.3.4: TTL 30 is less than 60`,
+	}
+	jsonData, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlData, err := yaml.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for name, data := range map[string][]byte{
+		"policy.json": jsonData,
+		"policy.yaml": yamlData,
+		"policy.js": []byte(`
+RULE("caa", {check: "require", types: ["CAA"]});
+RULE("apex-ttl", {level: "warning"}, function (zone) {
+    var msgs = [];
+    zone.records.forEach(function (r) {
+        if (r.name === "@" && r.ttl < 60) {
+            msgs.push(zone.name + " " + r.type + " " + r.target + ": TTL " + r.ttl + " is less than 60");
+        }
+    });
+    return msgs;
+});
+`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(dir, name)
+			if err := os.WriteFile(filename, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			p, err := Load(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkViolations(t, p, want)
+		})
+	}
+}
+
+// setEngine makes js.Engine engine during the test.
+func setEngine(t *testing.T, engine string) {
+	old := js.Engine
+	js.Engine = engine
+	t.Cleanup(func() { js.Engine = old })
+}
+
+func TestLoadJSErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, engine := range []string{js.EngineGoja, js.EngineOtto} {
+		for name, script := range map[string]string{
+			"no name":   `RULE(function () {});`,
+			"exception": `RULE("x", function () { throw new Error("boom"); });`,
+			"not text":  `RULE("x", function () { return [{}]; });`,
+		} {
+			t.Run(engine+"/"+name, func(t *testing.T) {
+				setEngine(t, engine)
+				filename := filepath.Join(dir, "policy.js")
+				if err := os.WriteFile(filename, []byte(script), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				p, err := Load(filename)
+				if name == "no name" {
+					if err == nil {
+						t.Error("expected an error")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := map[string]string{"exception": "boom", "not text": "a list of strings"}[name]
+				vs := p.Evaluate(testConfig())
+				if len(vs) != 1 || !strings.Contains(vs[0].Message, want) {
+					t.Errorf("Evaluate() = %v, want a violation about %q", vs, want)
+				}
+			})
+		}
+	}
+}
+
+func TestLoadModernJS(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "policy.js")
+	script := `const minTTL = 60;
+RULE("apex-ttl", { level: "warning" }, ({ name, records }) =>
+    records
+        .filter((r) => r.name === "@" && r.ttl < minTTL)
+        .map((r) => ` + "`${name} ${r.type} ${r.target ?? \"\"}: TTL ${r.ttl} is less than ${minTTL}`" + `));
+`
+	if err := os.WriteFile(filename, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	setEngine(t, js.EngineGoja)
+	p, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, p, []string{`WARNING: policy "apex-ttl": example.com: example.com A 1.2.3.4: TTL 30 is less than 60`})
+
+	// otto, when dnsconfig.js is run by otto, only supports ES5.
+	setEngine(t, js.EngineOtto)
+	if _, err := Load(filename); err == nil {
+		t.Error("otto: expected a syntax error")
+	}
+}
@@ -0,0 +1,243 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+// ChangePrinter is implemented by printers that can output the
+// structured details of each change (not just the correction messages).
+type ChangePrinter interface {
+	PrintChange(c ChangeEvent)
+}
+
+// Event is one line of output from the JSONPrinter.
+type Event struct {
+	Event     string    `json:"event"` // domain, provider, registrar, provider_end, report, correction, correction_end, change, message
+	Time      time.Time `json:"time"`
+	Domain    string    `json:"domain,omitempty"`
+	Provider  string    `json:"provider,omitempty"`
+	Registrar string    `json:"registrar,omitempty"`
+
+	Skip        bool         `json:"skip,omitempty"`        // provider, registrar
+	Corrections *int         `json:"corrections,omitempty"` // provider_end
+	Index       *int         `json:"index,omitempty"`       // report, correction
+	Msg         string       `json:"msg,omitempty"`         // report, correction, message
+	Details     []string     `json:"details,omitempty"`     // report, correction
+	Level       string       `json:"level,omitempty"`       // message: debug, info, warning, error
+	Success     *bool        `json:"success,omitempty"`     // correction_end
+	Error       string       `json:"error,omitempty"`       // provider_end, correction_end
+	Duration    float64      `json:"duration,omitempty"`    // correction_end (seconds)
+	Change      *ChangeEvent `json:"change,omitempty"`      // change
+}
+
+// ChangeEvent describes one change (see diff2.Change) in a structured way.
+type ChangeEvent struct {
+	Verb    string        `json:"verb"` // CREATE, CHANGE, DELETE
+	Name    string        `json:"name"` // FQDN of the label
+	Type    string        `json:"type"` // rtype ("" if the change is for the entire label)
+	Old     []RecordEvent `json:"old,omitempty"`
+	New     []RecordEvent `json:"new,omitempty"`
+	OnlyTTL bool          `json:"only_ttl,omitempty"` // The only change is the TTL.
+	Msgs    []string      `json:"msgs,omitempty"`
+}
+
+// RecordEvent is the data of a record in a ChangeEvent.
+type RecordEvent struct {
+	Type       string   `json:"type"`
+	TTL        uint32   `json:"ttl"`
+	Data       string   `json:"data"`
+	FilePos    string   `json:"filepos,omitempty"`    // Where the (desired) record is defined.
+	Provenance []string `json:"provenance,omitempty"` // The D()/D_EXTEND()/INCLUDE() calls that produced it.
+}
+
+// JSONPrinter is a CLI that outputs a stream of JSON objects, one per line
+// (NDJSON), instead of human-readable text.
+type JSONPrinter struct {
+	Writer  io.Writer
+	Verbose bool
+
+	mu        sync.Mutex
+	enc       *json.Encoder
+	domain    string
+	provider  string
+	registrar string
+	corrStart time.Time
+	corrIndex int
+}
+
+// NewJSONPrinter returns a JSONPrinter that writes to w.
+func NewJSONPrinter(w io.Writer, verbose bool) *JSONPrinter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &JSONPrinter{Writer: w, Verbose: verbose, enc: enc}
+}
+
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+
+// StripColors removes terminal formatting (ANSI escapes) from s.
+func StripColors(s string) string {
+	return ansiRe.ReplaceAllString(s, "")
+}
+
+// emit writes the event. The caller must hold p.mu.
+func (p *JSONPrinter) emit(e Event) {
+	e.Time = time.Now().UTC()
+	if e.Domain == "" {
+		e.Domain = p.domain
+	}
+	if e.Provider == "" && e.Registrar == "" && e.Event != "message" && e.Event != "domain" {
+		e.Provider = p.provider
+		e.Registrar = p.registrar
+	}
+	_ = p.enc.Encode(e)
+}
+
+func intPtr(i int) *int { return &i }
+
+func splitMsg(s string) []string {
+	var r []string
+	for _, l := range strings.Split(StripColors(s), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			r = append(r, l)
+		}
+	}
+	return r
+}
+
+// StartDomain is called at the start of each domain.
+func (p *JSONPrinter) StartDomain(dc *models.DomainConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.domain = dc.UniqueName
+	p.provider, p.registrar = "", ""
+	p.emit(Event{Event: "domain"})
+}
+
+// StartDNSProvider is called at the start of each new provider.
+func (p *JSONPrinter) StartDNSProvider(name string, skip bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.provider, p.registrar = name, ""
+	p.emit(Event{Event: "provider", Skip: skip})
+}
+
+// StartRegistrar is called at the start of each new registrar.
+func (p *JSONPrinter) StartRegistrar(name string, skip bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.provider, p.registrar = "", name
+	p.emit(Event{Event: "registrar", Skip: skip})
+}
+
+// EndProvider is called at the end of each provider.
+func (p *JSONPrinter) EndProvider(name string, numCorrections int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := Event{Event: "provider_end", Corrections: intPtr(numCorrections)}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	p.emit(e)
+}
+
+// EndProvider2 is called at the end of each provider.
+func (p *JSONPrinter) EndProvider2(name string, numCorrections int) {
+	p.EndProvider(name, numCorrections, nil)
+}
+
+// PrintChange outputs the structured details of a change.
+func (p *JSONPrinter) PrintChange(c ChangeEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.emit(Event{Event: "change", Change: &c})
+}
+
+// PrintCorrection is called to print/format each correction.
+func (p *JSONPrinter) PrintCorrection(i int, c *models.Correction) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.corrStart = time.Now()
+	p.corrIndex = i + 1
+	p.emit(Event{Event: "correction", Index: intPtr(i + 1), Msg: StripColors(c.Msg), Details: splitMsg(c.Msg)})
+}
+
+// PrintReport is called to print/format each non-mutating correction (diff2.REPORT).
+func (p *JSONPrinter) PrintReport(i int, c *models.Correction) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.emit(Event{Event: "report", Index: intPtr(i + 1), Msg: StripColors(c.Msg), Details: splitMsg(c.Msg)})
+}
+
+// EndCorrection is called at the end of each correction.
+func (p *JSONPrinter) EndCorrection(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	success := err == nil
+	e := Event{
+		Event:    "correction_end",
+		Index:    intPtr(p.corrIndex),
+		Success:  &success,
+		Duration: time.Since(p.corrStart).Seconds(),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	p.emit(e)
+}
+
+// PromptToRun is not supported in JSON mode. It always returns false.
+func (p *JSONPrinter) PromptToRun() bool {
+	return false
+}
+
+func (p *JSONPrinter) message(level, format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	msg := strings.TrimSpace(StripColors(fmt.Sprintf(format, args...)))
+	if msg == "" {
+		return
+	}
+	p.emit(Event{Event: "message", Level: level, Msg: msg})
+}
+
+// Debugf is called to print/format debug information.
+func (p *JSONPrinter) Debugf(format string, args ...any) {
+	if p.Verbose {
+		p.message("debug", format, args...)
+	}
+}
+
+// Printf is called to print/format information.
+func (p *JSONPrinter) Printf(format string, args ...any) {
+	p.message("info", format, args...)
+}
+
+// Println is called to print/format information.
+func (p *JSONPrinter) Println(lines ...string) {
+	p.message("info", "%s", strings.Join(lines, " "))
+}
+
+// Warnf is called to print/format a warning.
+func (p *JSONPrinter) Warnf(format string, args ...any) {
+	p.message("warning", format, args...)
+}
+
+// Errorf is called to print/format an error.
+func (p *JSONPrinter) Errorf(format string, args ...any) {
+	p.message("error", format, args...)
+}
+
+// PrintfIf is called to optionally print/format a message.
+func (p *JSONPrinter) PrintfIf(prnt bool, format string, args ...any) {
+	if prnt {
+		p.message("info", format, args...)
+	}
+}
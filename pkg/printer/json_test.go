@@ -0,0 +1,57 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeEvents(t *testing.T, s string) []Event {
+	t.Helper()
+	var events []Event
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %q is not JSON: %s", line, err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestJSONPrinter(t *testing.T) {
+	output := &bytes.Buffer{}
+	p := NewJSONPrinter(output, false)
+
+	p.StartDomain(&models.DomainConfig{Name: "example.com", UniqueName: "example.com"})
+	p.StartDNSProvider("bind", false)
+	p.PrintChange(ChangeEvent{Verb: "CREATE", Name: "www.example.com", Type: "A", New: []RecordEvent{{Type: "A", TTL: 300, Data: "1.2.3.4"}}})
+	p.PrintCorrection(0, &models.Correction{Msg: "\x1b[32m+ CREATE www.example.com A 1.2.3.4 ttl=300\x1b[0m"})
+	p.EndCorrection(errors.New("boom"))
+	p.Debugf("not shown\n")
+	p.Printf("hello\n")
+	p.EndProvider2("bind", 1)
+
+	events := decodeEvents(t, output.String())
+	var names []string
+	for _, e := range events {
+		names = append(names, e.Event)
+		assert.Equal(t, "example.com", e.Domain)
+	}
+	assert.Equal(t, []string{"domain", "provider", "change", "correction", "correction_end", "message", "provider_end"}, names)
+
+	assert.Equal(t, "bind", events[2].Provider)
+	assert.Equal(t, "1.2.3.4", events[2].Change.New[0].Data)
+	assert.Equal(t, "+ CREATE www.example.com A 1.2.3.4 ttl=300", events[3].Msg)
+	assert.Equal(t, 1, *events[4].Index)
+	assert.False(t, *events[4].Success)
+	assert.Equal(t, "boom", events[4].Error)
+	assert.Equal(t, "info", events[5].Level)
+	assert.Equal(t, "hello", events[5].Msg)
+	assert.Equal(t, 1, *events[6].Corrections)
+}
@@ -32,6 +32,20 @@ type ZoneLister interface {
 	ListZones() ([]string, error)
 }
 
+// ZoneVersioner should be implemented by providers that can tell whether a
+// zone changed without downloading its records (for example, from the SOA
+// serial or an API etag). This lets "preview --state-cache" use the records
+// it saved the last time instead of calling GetZoneRecords.
+//
+// Implement it only if GetZoneRecordsCorrections works with the records
+// returned by an earlier GetZoneRecords call (it doesn't depend on state
+// that GetZoneRecords sets, such as record IDs in RecordConfig.Original).
+type ZoneVersioner interface {
+	// ZoneVersion returns a token that changes whenever the records of
+	// the zone change, or "" if it can not tell.
+	ZoneVersion(dc *models.DomainConfig) (string, error)
+}
+
 // RegistrarInitializer is a function to create a registrar. Function will be passed the unprocessed json payload from the configuration file for the given provider.
 type RegistrarInitializer func(map[string]string) (Registrar, error)
 
@@ -0,0 +1,289 @@
+// Package ratelimit provides an http.RoundTripper for the API clients of
+// providers. It limits the rate of requests, retries the requests that the
+// API rejected because of the rate (429) or a temporary failure (with a
+// jittered exponential backoff, or as the Retry-After header says), and
+// fails fast when the API is down (a circuit breaker).
+//
+// A provider adopts it by wrapping its http.Client:
+//
+//	client := &http.Client{Timeout: 30 * time.Second}
+//	if err := ratelimit.WrapClient(client, m); err != nil {
+//		return nil, err
+//	}
+//
+// where m is the provider's creds.json entry. Its optional "rate_limit"
+// field sets the rate (Ex: "5/s", "300/m").
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/pkg/printer"
+	"golang.org/x/time/rate"
+)
+
+// CredsKey is the creds.json field that sets the rate limit.
+const CredsKey = "rate_limit"
+
+// ErrCircuitOpen is returned when the API failed too many times in a row;
+// requests are not sent until Config.BreakerCooldown has passed.
+var ErrCircuitOpen = errors.New("too many consecutive failures; not sending requests for a while")
+
+// Config is the configuration of a Transport.
+type Config struct {
+	Rate  rate.Limit // Requests per second. rate.Inf (or 0) means no limit.
+	Burst int        // Requests that may be sent at once.
+
+	MaxRetries int           // Retries of a request.
+	MinBackoff time.Duration // The first delay between retries. It doubles at each retry.
+	MaxBackoff time.Duration // The longest delay between retries (unless Retry-After says more).
+	MaxWait    time.Duration // The longest delay that Retry-After can ask for.
+
+	BreakerThreshold int           // Consecutive failures that open the circuit (0 = never).
+	BreakerCooldown  time.Duration // How long the circuit stays open.
+}
+
+// DefaultConfig is the configuration when creds.json doesn't set one.
+var DefaultConfig = Config{
+	Rate:             rate.Inf,
+	MaxRetries:       5,
+	MinBackoff:       time.Second,
+	MaxBackoff:       time.Minute,
+	MaxWait:          5 * time.Minute,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+}
+
+// ConfigFromCreds returns DefaultConfig with the rate limit of the
+// creds.json entry m (if any).
+func ConfigFromCreds(m map[string]string) (Config, error) {
+	cfg := DefaultConfig
+	if s := m[CredsKey]; s != "" {
+		r, err := ParseRate(s)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Rate = r
+		cfg.Burst = max(1, int(math.Ceil(float64(r))))
+	}
+	return cfg, nil
+}
+
+// ParseRate parses a rate such as "5/s", "300/m", "1000/h" or "1/2s".
+func ParseRate(s string) (rate.Limit, error) {
+	n, per, ok := strings.Cut(s, "/")
+	count, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+	if !ok || err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: want requests/period (Ex: \"5/s\", \"300/m\")", CredsKey, s)
+	}
+	per = strings.TrimSpace(per)
+	if per != "" && (per[0] < '0' || per[0] > '9') {
+		per = "1" + per
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: want requests/period (Ex: \"5/s\", \"300/m\")", CredsKey, s)
+	}
+	return rate.Limit(count / d.Seconds()), nil
+}
+
+// WrapClient makes client use a Transport configured by the creds.json
+// entry m.
+func WrapClient(client *http.Client, m map[string]string) error {
+	cfg, err := ConfigFromCreds(m)
+	if err != nil {
+		return err
+	}
+	client.Transport = New(client.Transport, cfg)
+	return nil
+}
+
+// Transport is an http.RoundTripper that limits the rate of requests,
+// retries them and stops sending them when the API is down.
+type Transport struct {
+	base    http.RoundTripper
+	cfg     Config
+	limiter *rate.Limiter
+
+	mu        sync.Mutex
+	failures  int       // Consecutive failures.
+	openUntil time.Time // The circuit is open until then.
+	probing   bool      // A request is testing whether the API is back (half-open).
+
+	sleep func(ctx context.Context, d time.Duration) error // For tests.
+}
+
+// New returns a Transport that sends the requests with base (or
+// http.DefaultTransport if base is nil).
+func New(base http.RoundTripper, cfg Config) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if cfg.Rate == 0 {
+		cfg.Rate = rate.Inf
+	}
+	return &Transport{
+		base:    base,
+		cfg:     cfg,
+		limiter: rate.NewLimiter(cfg.Rate, max(1, cfg.Burst)),
+		sleep:   sleep,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.allow(); err != nil {
+		return nil, err
+	}
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		r := req
+		if attempt > 0 && req.Body != nil {
+			// The body was consumed by the previous attempt.
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(ctx)
+			r.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		retry := retryable(req, resp, err)
+		t.record(resp, err)
+		if !retry || attempt >= t.cfg.MaxRetries {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt)
+		if resp != nil {
+			if ra, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				wait = min(ra, t.cfg.MaxWait)
+			}
+			printer.Debugf("ratelimit: %s %s: %s; retrying in %s\n", req.Method, req.URL.Redacted(), resp.Status, wait.Round(time.Millisecond))
+			// The response is discarded.
+			resp.Body.Close()
+		} else {
+			printer.Debugf("ratelimit: %s %s: %s; retrying in %s\n", req.Method, req.URL.Redacted(), err, wait.Round(time.Millisecond))
+		}
+		if err := t.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		if err := t.allow(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable returns true if req can be sent again after resp or err.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false // The body can't be sent again.
+	}
+	if err != nil {
+		// The request may have been processed: only retry if doing it
+		// twice is harmless.
+		return idempotent(req.Method) && req.Context().Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		// The request was not processed.
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent(req.Method)
+	}
+	return false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// backoff returns the delay before retry number attempt+1: an exponential
+// backoff with jitter (between half and all of it).
+func (t *Transport) backoff(attempt int) time.Duration {
+	d := t.cfg.MinBackoff << min(attempt, 30)
+	if d <= 0 || d > t.cfg.MaxBackoff {
+		d = t.cfg.MaxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter parses a Retry-After header: a number of seconds or a date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, t.Sub(now)), true
+	}
+	return 0, false
+}
+
+// allow returns ErrCircuitOpen if requests must not be sent.
+func (t *Transport) allow() error {
+	if t.cfg.BreakerThreshold == 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failures < t.cfg.BreakerThreshold {
+		return nil
+	}
+	if time.Now().Before(t.openUntil) || t.probing {
+		return ErrCircuitOpen
+	}
+	// Half-open: let one request test whether the API is back.
+	t.probing = true
+	return nil
+}
+
+// record updates the circuit breaker with the result of a request. Server
+// errors and network errors are failures; a rate limit (429) is not.
+func (t *Transport) record(resp *http.Response, err error) {
+	if t.cfg.BreakerThreshold == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.probing = false
+	if err != nil || resp.StatusCode >= 500 {
+		t.failures++
+		if t.failures >= t.cfg.BreakerThreshold {
+			t.openUntil = time.Now().Add(t.cfg.BreakerCooldown)
+		}
+		return
+	}
+	t.failures = 0
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
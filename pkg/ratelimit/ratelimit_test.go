@@ -0,0 +1,260 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    rate.Limit
+		wantErr bool
+	}{
+		{in: "5/s", want: 5},
+		{in: "300/m", want: 5},
+		{in: "3600/h", want: 1},
+		{in: "1/2s", want: 0.5},
+		{in: " 10 / 1s ", want: 10},
+		{in: "5", wantErr: true},
+		{in: "0/s", wantErr: true},
+		{in: "x/s", wantErr: true},
+		{in: "5/fortnight", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRate(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseRate(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigFromCreds(t *testing.T) {
+	cfg, err := ConfigFromCreds(map[string]string{"token": "x"})
+	if err != nil || cfg.Rate != rate.Inf {
+		t.Errorf("no rate_limit: got %v, %v", cfg.Rate, err)
+	}
+	cfg, err = ConfigFromCreds(map[string]string{CredsKey: "300/m"})
+	if err != nil || cfg.Rate != 5 || cfg.Burst != 5 {
+		t.Errorf("300/m: got %v burst %d, %v", cfg.Rate, cfg.Burst, err)
+	}
+	if _, err := ConfigFromCreds(map[string]string{CredsKey: "fast"}); err == nil {
+		t.Error("invalid rate_limit: no error")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in     string
+		want   time.Duration
+		wantOK bool
+	}{
+		{in: "", wantOK: false},
+		{in: "3", want: 3 * time.Second, wantOK: true},
+		{in: "Wed, 01 Jan 2025 00:00:10 GMT", want: 10 * time.Second, wantOK: true},
+		{in: "Tue, 31 Dec 2024 23:59:00 GMT", want: 0, wantOK: true},
+		{in: "soon", wantOK: false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.in, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// testTransport returns a Transport that doesn't sleep. The delays it
+// would have slept are appended to slept.
+func testTransport(cfg Config, slept *[]time.Duration) *Transport {
+	t := New(nil, cfg)
+	t.sleep = func(ctx context.Context, d time.Duration) error {
+		*slept = append(*slept, d)
+		return ctx.Err()
+	}
+	return t
+}
+
+// statusServer returns a server that answers with the statuses in turn (the
+// last one forever), and counts the requests.
+func statusServer(t *testing.T, count *atomic.Int32, statuses ...int) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(count.Add(1)) - 1
+		status := statuses[min(n, len(statuses)-1)]
+		body, _ := io.ReadAll(r.Body)
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "2")
+		}
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRoundTrip_retries(t *testing.T) {
+	var count atomic.Int32
+	srv := statusServer(t, &count, 429, 503, 200)
+	var slept []time.Duration
+	client := &http.Client{Transport: testTransport(DefaultConfig, &slept)}
+
+	resp, err := client.Post(srv.URL, "application/json", strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || string(body) != `{"a":1}` {
+		t.Errorf("got %d %q, want 200 with the body sent again", resp.StatusCode, body)
+	}
+	if count.Load() != 3 {
+		t.Errorf("got %d requests, want 3", count.Load())
+	}
+	if len(slept) != 2 || slept[0] != 2*time.Second {
+		t.Errorf("slept %v, want Retry-After (2s) then a backoff", slept)
+	}
+	if slept[1] < DefaultConfig.MinBackoff || slept[1] > 2*DefaultConfig.MinBackoff {
+		t.Errorf("backoff %v not within [%v, %v]", slept[1], DefaultConfig.MinBackoff, 2*DefaultConfig.MinBackoff)
+	}
+}
+
+func TestRoundTrip_notIdempotent(t *testing.T) {
+	// A 502 to a POST is not retried: the request may have been processed.
+	var count atomic.Int32
+	srv := statusServer(t, &count, 502, 200)
+	var slept []time.Duration
+	client := &http.Client{Transport: testTransport(DefaultConfig, &slept)}
+
+	resp, err := client.Post(srv.URL, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 502 || count.Load() != 1 {
+		t.Errorf("got %d after %d requests, want 502 after 1", resp.StatusCode, count.Load())
+	}
+
+	// The same to a GET is retried.
+	count.Store(0)
+	resp, err = client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 || count.Load() != 2 {
+		t.Errorf("got %d after %d requests, want 200 after 2", resp.StatusCode, count.Load())
+	}
+}
+
+func TestRoundTrip_maxRetries(t *testing.T) {
+	var count atomic.Int32
+	srv := statusServer(t, &count, 429)
+	var slept []time.Duration
+	cfg := DefaultConfig
+	cfg.MaxRetries = 2
+	client := &http.Client{Transport: testTransport(cfg, &slept)}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 429 || count.Load() != 3 {
+		t.Errorf("got %d after %d requests, want 429 after 3", resp.StatusCode, count.Load())
+	}
+}
+
+func TestRoundTrip_circuitBreaker(t *testing.T) {
+	var count atomic.Int32
+	srv := statusServer(t, &count, 500, 500, 500, 200)
+	var slept []time.Duration
+	cfg := DefaultConfig
+	cfg.BreakerThreshold = 3
+	cfg.BreakerCooldown = time.Hour
+	tr := testTransport(cfg, &slept)
+	client := &http.Client{Transport: tr}
+
+	for range 3 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v, want ErrCircuitOpen", err)
+	}
+	if count.Load() != 3 {
+		t.Errorf("got %d requests, want 3 (none while the circuit is open)", count.Load())
+	}
+
+	// After the cooldown, one request is let through; it succeeds and the
+	// circuit is closed.
+	tr.mu.Lock()
+	tr.openUntil = time.Now()
+	tr.mu.Unlock()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("got %d, want 200", resp.StatusCode)
+	}
+	if err := tr.allow(); err != nil {
+		t.Errorf("circuit still open: %v", err)
+	}
+}
+
+func TestRoundTrip_rateLimit(t *testing.T) {
+	var count atomic.Int32
+	srv := statusServer(t, &count, 200)
+	cfg := DefaultConfig
+	cfg.Rate = 20
+	cfg.Burst = 1
+	client := &http.Client{Transport: New(nil, cfg)}
+
+	start := time.Now()
+	for range 3 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	// 3 requests at 20/s with a burst of 1 take at least 100ms.
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("3 requests took %v, want at least 100ms", d)
+	}
+}
+
+func TestRoundTrip_contextCanceled(t *testing.T) {
+	var count atomic.Int32
+	srv := statusServer(t, &count, 429)
+	client := &http.Client{Transport: New(nil, DefaultConfig)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	start := time.Now()
+	if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("took %v; the Retry-After delay was not interrupted", d)
+	}
+}
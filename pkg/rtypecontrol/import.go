@@ -16,12 +16,13 @@ func ImportRawRecords(domains []*models.DomainConfig) error {
 			filePos := models.FixPosition(rawRec.FilePos)
 
 			rec, err := NewRecordConfigFromRaw(FromRawOpts{
-				Type:    rawRec.Type,
-				TTL:     rawRec.TTL,
-				Args:    rawRec.Args,
-				Metas:   rawRec.Metas,
-				DCN:     dc.DomainNameVarieties(),
-				FilePos: filePos,
+				Type:       rawRec.Type,
+				TTL:        rawRec.TTL,
+				Args:       rawRec.Args,
+				Metas:      rawRec.Metas,
+				DCN:        dc.DomainNameVarieties(),
+				FilePos:    filePos,
+				Provenance: rawRec.Provenance,
 			})
 			if err != nil {
 				return fmt.Errorf("error processing record at %s [%s(%s)]: %v",
@@ -69,12 +70,13 @@ func stutters(name, domain string) bool {
 // FromRawOpts contains the options for creating a RecordConfig from raw data.
 // Except Type and Args, all fields are optional.
 type FromRawOpts struct {
-	Type    string                          // (required) Record type (e.g., "A", "CNAME")
-	TTL     uint32                          // Time to live
-	Args    []any                           // (required) Arguments for the record
-	Metas   []map[string]any                // Metadata for the record
-	DCN     *domaintags.DomainNameVarieties // Domain name varieties
-	FilePos string                          // Position in the file where this record was defined
+	Type       string                          // (required) Record type (e.g., "A", "CNAME")
+	TTL        uint32                          // Time to live
+	Args       []any                           // (required) Arguments for the record
+	Metas      []map[string]any                // Metadata for the record
+	DCN        *domaintags.DomainNameVarieties // Domain name varieties
+	FilePos    string                          // Position in the file where this record was defined
+	Provenance []string                        // The D()/D_EXTEND()/INCLUDE() calls that added the record
 }
 
 // NewRecordConfigFromRaw creates a new RecordConfig from the raw ([]any) args,
@@ -96,10 +98,11 @@ func NewRecordConfigFromRaw(opts FromRawOpts) (*models.RecordConfig, error) {
 
 	// Create as much of the RecordConfig as we can now. Allow New() to fill in the reset.
 	rec := &models.RecordConfig{
-		Type:     t,
-		TTL:      ttl,
-		Metadata: map[string]string{},
-		FilePos:  FilePos,
+		Type:       t,
+		TTL:        ttl,
+		Metadata:   map[string]string{},
+		FilePos:    FilePos,
+		Provenance: opts.Provenance,
 	}
 
 	// Set the label names:
@@ -0,0 +1,278 @@
+// Package staged implements STAGED_CHANGE(): changing the value of a record
+// in phases so that resolvers never cache the old value for long after the
+// change.
+//
+// The phases of a record (a RecordKey: label and type) are, one per push:
+//
+//  1. Lower: the old value is kept, with the low TTL.
+//  2. Wait: nothing is changed until the old TTL has passed since phase 1.
+//  3. Change: the new value is set, still with the low TTL.
+//  4. Raise: the TTL is set to the desired TTL (this is a normal TTL-only
+//     change, see diff2.Change.HintOnlyTTL).
+//
+// When phase 1 was pushed is kept in a state file, since it can't be
+// learned from the provider.
+package staged
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+// MetaLowTTL is the metadata key that STAGED_CHANGE() sets to the low TTL.
+const MetaLowTTL = "staged_ttl"
+
+// State is the state of the records that are being changed in phases.
+type State struct {
+	Entries []*Entry `json:"entries"`
+
+	mu      sync.Mutex
+	pending map[string][]*Entry // By zone and provider. See Commit.
+	changed bool
+}
+
+// Entry is a record whose TTL was lowered (phase 1).
+type Entry struct {
+	Zone      string    `json:"zone"`     // The zone's UniqueName.
+	Provider  string    `json:"provider"` // The DNS provider's name (creds.json key).
+	Name      string    `json:"name"`     // FQDN
+	Type      string    `json:"type"`
+	OldTTL    uint32    `json:"old_ttl"`
+	LowTTL    uint32    `json:"low_ttl"`
+	LoweredAt time.Time `json:"lowered_at"`
+}
+
+// ReadyAt returns when the old TTL has passed since the TTL was lowered.
+func (e *Entry) ReadyAt() time.Time {
+	return e.LoweredAt.Add(time.Duration(e.OldTTL) * time.Second)
+}
+
+// Load reads the state from filename. A missing file is an empty state.
+func Load(filename string) (*State, error) {
+	s := &State{}
+	b, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return s, nil
+}
+
+// Save writes the state to filename if it changed since Load. The file is
+// removed when no record is being changed.
+func (s *State) Save(filename string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.changed {
+		return nil
+	}
+	if len(s.Entries) == 0 {
+		if err := os.Remove(filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(b, '\n'), 0o600)
+}
+
+func pendingKey(zone, provider string) string {
+	return zone + "\t" + provider
+}
+
+// find returns the entry of the records of type rtype at name.
+func (s *State) find(zone, provider, name, rtype string) *Entry {
+	for _, e := range s.Entries {
+		if e.Zone == zone && e.Provider == provider && e.Name == name && e.Type == rtype {
+			return e
+		}
+	}
+	return nil
+}
+
+// remove removes the entry of the records of type rtype at name.
+func (s *State) remove(zone, provider, name, rtype string) {
+	n := len(s.Entries)
+	s.Entries = slices.DeleteFunc(s.Entries, func(e *Entry) bool {
+		return e.Zone == zone && e.Provider == provider && e.Name == name && e.Type == rtype
+	})
+	s.changed = s.changed || len(s.Entries) != n
+}
+
+// Commit records that the changes of zone at provider were pushed: the
+// records whose TTL was lowered start waiting.
+func (s *State) Commit(zone, provider string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := pendingKey(zone, provider)
+	for _, e := range s.pending[key] {
+		s.remove(e.Zone, e.Provider, e.Name, e.Type)
+		s.Entries = append(s.Entries, e)
+		s.changed = true
+	}
+	delete(s.pending, key)
+}
+
+// Apply replaces the desired records of dc (the zone zone at provider) that
+// have STAGED_CHANGE() by those of the current phase, given the existing
+// records and the time. It returns a message for each record that is not
+// changed to its desired state yet.
+//
+// The records whose TTL is lowered are only recorded in the state when
+// Commit is called.
+func (s *State) Apply(zone, provider string, existing models.Records, dc *models.DomainConfig, now time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existingByKey := map[models.RecordKey]models.Records{}
+	for _, rec := range existing {
+		existingByKey[rec.Key()] = append(existingByKey[rec.Key()], rec)
+	}
+	desiredByKey := map[models.RecordKey]models.Records{}
+	var keys []models.RecordKey
+	for _, rec := range dc.Records {
+		k := rec.Key()
+		if _, ok := desiredByKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		desiredByKey[k] = append(desiredByKey[k], rec)
+	}
+
+	var msgs []string
+	var pending []*Entry
+	replace := map[models.RecordKey]models.Records{}
+	for _, k := range keys {
+		desired := desiredByKey[k]
+		lowTTL, ok, err := stagedLowTTL(desired)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", k.NameFQDN, k.Type, err)
+		}
+		if !ok {
+			continue
+		}
+		old := existingByKey[k]
+		if len(old) == 0 || sameValues(old, desired) {
+			// A new record, or the value was changed: nothing to stage.
+			// If the TTL is still low, it is raised as usual.
+			s.remove(zone, provider, k.NameFQDN, k.Type)
+			continue
+		}
+
+		e := s.find(zone, provider, k.NameFQDN, k.Type)
+		oldTTL := maxTTL(old)
+		switch {
+		case (e == nil || oldTTL >= e.OldTTL) && oldTTL > lowTTL:
+			// Phase 1 (or phase 1 was not pushed after all):
+			replace[k] = withTTL(old, lowTTL)
+			e = &Entry{Zone: zone, Provider: provider, Name: k.NameFQDN, Type: k.Type, OldTTL: oldTTL, LowTTL: lowTTL, LoweredAt: now}
+			pending = append(pending, e)
+			msgs = append(msgs, fmt.Sprintf("STAGED_CHANGE: %s %s: lowering the TTL from %d to %d; the value can be changed after %s",
+				k.NameFQDN, k.Type, oldTTL, lowTTL, e.ReadyAt().UTC().Format(time.RFC3339)))
+		case e == nil:
+			// The TTL is already low, but when it was lowered is unknown
+			// (the state file was lost, or it was lowered by hand): the
+			// old value may still be cached with a higher TTL, so wait as
+			// if it was lowered now.
+			replace[k] = withTTL(old, 0)
+			e = &Entry{Zone: zone, Provider: provider, Name: k.NameFQDN, Type: k.Type, OldTTL: max(oldTTL, maxTTL(desired)), LowTTL: lowTTL, LoweredAt: now}
+			pending = append(pending, e)
+			msgs = append(msgs, fmt.Sprintf("STAGED_CHANGE: %s %s: the TTL is already %d, but when it was lowered is unknown; the value can be changed after %s",
+				k.NameFQDN, k.Type, oldTTL, e.ReadyAt().UTC().Format(time.RFC3339)))
+		case now.Before(e.ReadyAt()):
+			// Phase 2:
+			replace[k] = withTTL(old, 0)
+			msgs = append(msgs, fmt.Sprintf("STAGED_CHANGE: %s %s: waiting for the old TTL (%d) to expire; the value can be changed after %s",
+				k.NameFQDN, k.Type, e.OldTTL, e.ReadyAt().UTC().Format(time.RFC3339)))
+		default:
+			// Phase 3 (the TTL was lowered long enough ago):
+			replace[k] = withTTL(desired, lowTTL)
+			if desired[0].TTL != lowTTL {
+				msgs = append(msgs, fmt.Sprintf("STAGED_CHANGE: %s %s: changing the value with TTL %d; the TTL will be set to %d by the next push",
+					k.NameFQDN, k.Type, lowTTL, desired[0].TTL))
+			}
+		}
+	}
+	if s.pending == nil {
+		s.pending = map[string][]*Entry{}
+	}
+	s.pending[pendingKey(zone, provider)] = pending
+
+	if len(replace) != 0 {
+		var recs models.Records
+		for _, k := range keys {
+			if r, ok := replace[k]; ok {
+				recs = append(recs, r...)
+			} else {
+				recs = append(recs, desiredByKey[k]...)
+			}
+		}
+		dc.Records = recs
+	}
+	return msgs, nil
+}
+
+// stagedLowTTL returns the low TTL of STAGED_CHANGE() if any of recs has it.
+func stagedLowTTL(recs models.Records) (uint32, bool, error) {
+	for _, rec := range recs {
+		if v, ok := rec.Metadata[MetaLowTTL]; ok {
+			ttl, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return 0, false, fmt.Errorf("STAGED_CHANGE: invalid TTL %q", v)
+			}
+			return uint32(ttl), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// sameValues returns true if a and b have the same values, ignoring TTLs.
+func sameValues(a, b models.Records) bool {
+	values := func(recs models.Records) []string {
+		r := make([]string, len(recs))
+		for i, rec := range recs {
+			r[i] = rec.ToComparableNoTTL()
+		}
+		slices.Sort(r)
+		return r
+	}
+	return slices.Equal(values(a), values(b))
+}
+
+// maxTTL returns the highest TTL of recs.
+func maxTTL(recs models.Records) uint32 {
+	var m uint32
+	for _, rec := range recs {
+		m = max(m, rec.TTL)
+	}
+	return m
+}
+
+// withTTL returns copies of recs with the TTL ttl (or their TTL if ttl is 0).
+func withTTL(recs models.Records, ttl uint32) models.Records {
+	r := make(models.Records, len(recs))
+	for i, rec := range recs {
+		c, _ := rec.Copy()
+		if ttl != 0 {
+			c.TTL = ttl
+		}
+		r[i] = c
+	}
+	return r
+}
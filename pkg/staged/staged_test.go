@@ -0,0 +1,140 @@
+package staged
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+func makeRec(label, rtype, content string, ttl uint32, lowTTL string) *models.RecordConfig {
+	r := &models.RecordConfig{TTL: ttl, Metadata: map[string]string{}}
+	r.SetLabel(label, "example.com")
+	if err := r.PopulateFromString(rtype, content, "example.com"); err != nil {
+		panic(err)
+	}
+	if lowTTL != "" {
+		r.Metadata[MetaLowTTL] = lowTTL
+	}
+	return r
+}
+
+// records returns recs as "label type value ttl".
+func records(recs models.Records) string {
+	var r []string
+	for _, rec := range recs {
+		r = append(r, fmt.Sprintf("%s %s %s %d", rec.GetLabel(), rec.Type, rec.GetTargetCombined(), rec.TTL))
+	}
+	return strings.Join(r, ", ")
+}
+
+func TestApply(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+	start := time.Date(2025, 6, 20, 2, 0, 0, 0, time.UTC)
+
+	// The provider has:
+	existing := models.Records{
+		makeRec("www", "A", "1.2.3.4", 3600, ""),
+		makeRec("mail", "A", "1.2.3.9", 3600, ""),
+	}
+	// dnsconfig.js has:
+	desired := func() *models.DomainConfig {
+		return &models.DomainConfig{Name: "example.com", Records: models.Records{
+			makeRec("www", "A", "5.6.7.8", 3600, "60"),
+			makeRec("mail", "A", "1.2.3.10", 3600, ""),
+			makeRec("new", "A", "1.2.3.11", 3600, "60"),
+		}}
+	}
+
+	run := func(now time.Time, push bool, wantRecs string, wantMsgs ...string) {
+		t.Helper()
+		s, err := Load(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dc := desired()
+		msgs, err := s.Apply("example.com", "mem", existing, dc, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := records(dc.Records); got != wantRecs {
+			t.Errorf("at %s: records = %s, want %s", now, got, wantRecs)
+		}
+		if got, want := strings.Join(msgs, "\n"), strings.Join(wantMsgs, "\n"); got != want {
+			t.Errorf("at %s: messages:\n%s\nwant:\n%s", now, got, want)
+		}
+		if push {
+			existing = dc.Records
+			s.Commit("example.com", "mem")
+			if err := s.Save(filename); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// preview: nothing is saved.
+	run(start, false, "www A 1.2.3.4 60, mail A 1.2.3.10 3600, new A 1.2.3.11 3600",
+		"STAGED_CHANGE: www.example.com A: lowering the TTL from 3600 to 60; the value can be changed after 2025-06-20T03:00:00Z")
+	// push: phase 1.
+	run(start, true, "www A 1.2.3.4 60, mail A 1.2.3.10 3600, new A 1.2.3.11 3600",
+		"STAGED_CHANGE: www.example.com A: lowering the TTL from 3600 to 60; the value can be changed after 2025-06-20T03:00:00Z")
+	// phase 2:
+	run(start.Add(30*time.Minute), true, "www A 1.2.3.4 60, mail A 1.2.3.10 3600, new A 1.2.3.11 3600",
+		"STAGED_CHANGE: www.example.com A: waiting for the old TTL (3600) to expire; the value can be changed after 2025-06-20T03:00:00Z")
+	// phase 3:
+	run(start.Add(time.Hour), true, "www A 5.6.7.8 60, mail A 1.2.3.10 3600, new A 1.2.3.11 3600",
+		"STAGED_CHANGE: www.example.com A: changing the value with TTL 60; the TTL will be set to 3600 by the next push")
+	// phase 4 (a normal TTL change) and done:
+	run(start.Add(2*time.Hour), true, "www A 5.6.7.8 3600, mail A 1.2.3.10 3600, new A 1.2.3.11 3600")
+	if s, _ := Load(filename); len(s.Entries) != 0 {
+		t.Errorf("entries left: %v", s.Entries)
+	}
+}
+
+func TestApplyLoweringNotPushed(t *testing.T) {
+	s := &State{}
+	existing := models.Records{makeRec("www", "A", "1.2.3.4", 3600, "")}
+	start := time.Date(2025, 6, 20, 2, 0, 0, 0, time.UTC)
+
+	// The TTL is lowered (in the state) but the provider still has the old TTL
+	// (for example, the correction was skipped with push -i):
+	for _, now := range []time.Time{start, start.Add(2 * time.Hour)} {
+		dc := &models.DomainConfig{Name: "example.com", Records: models.Records{makeRec("www", "A", "5.6.7.8", 3600, "60")}}
+		if _, err := s.Apply("example.com", "mem", existing, dc, now); err != nil {
+			t.Fatal(err)
+		}
+		s.Commit("example.com", "mem")
+		if got, want := records(dc.Records), "www A 1.2.3.4 60"; got != want {
+			t.Errorf("at %s: records = %s, want %s", now, got, want)
+		}
+	}
+	if len(s.Entries) != 1 || !s.Entries[0].LoweredAt.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("entries = %v, want lowered again", s.Entries)
+	}
+}
+
+func TestApplyStateLost(t *testing.T) {
+	s := &State{}
+	// Phase 1 was pushed, but the state file was lost:
+	existing := models.Records{makeRec("www", "A", "1.2.3.4", 60, "")}
+	start := time.Date(2025, 6, 20, 2, 0, 0, 0, time.UTC)
+
+	run := func(now time.Time, want string) {
+		t.Helper()
+		dc := &models.DomainConfig{Name: "example.com", Records: models.Records{makeRec("www", "A", "5.6.7.8", 3600, "60")}}
+		if _, err := s.Apply("example.com", "mem", existing, dc, now); err != nil {
+			t.Fatal(err)
+		}
+		s.Commit("example.com", "mem")
+		if got := records(dc.Records); got != want {
+			t.Errorf("at %s: records = %s, want %s", now, got, want)
+		}
+	}
+	// The value is not changed before the desired TTL has passed:
+	run(start, "www A 1.2.3.4 60")
+	run(start.Add(30*time.Minute), "www A 1.2.3.4 60")
+	run(start.Add(time.Hour), "www A 5.6.7.8 60")
+}
@@ -0,0 +1,128 @@
+// Package statecache stores the records of zones between runs of preview,
+// with a token that tells whether the zone changed since (see
+// providers.ZoneVersioner), so that unchanged zones need not be downloaded
+// again.
+package statecache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+// Version is the version of the file format. A file of another version is
+// ignored.
+const Version = 1
+
+// Cache is the records of zones at providers.
+type Cache struct {
+	Version int     `json:"version"`
+	Zones   []*Zone `json:"zones"`
+
+	mu           sync.Mutex
+	changed      bool
+	hits, misses int
+}
+
+// Zone is the records of a zone at a provider.
+type Zone struct {
+	Provider string          `json:"provider"` // The DNS provider's name (creds.json key).
+	Zone     string          `json:"zone"`     // The zone's UniqueName.
+	Token    string          `json:"token"`    // The provider's version of the zone when the records were saved.
+	Saved    time.Time       `json:"saved"`
+	Records  json.RawMessage `json:"records"`
+}
+
+// Load reads the cache from filename. A missing file (or a file of another
+// version) is an empty cache.
+func Load(filename string) (*Cache, error) {
+	c := &Cache{Version: Version}
+	b, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if c.Version != Version {
+		return &Cache{Version: Version}, nil
+	}
+	return c, nil
+}
+
+// Save writes the cache to filename if it changed since Load.
+func (c *Cache) Save(filename string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.changed {
+		return nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, 0o600)
+}
+
+// find returns the index of zone at provider, or -1.
+func (c *Cache) find(provider, zone string) int {
+	return slices.IndexFunc(c.Zones, func(z *Zone) bool { return z.Provider == provider && z.Zone == zone })
+}
+
+// Get returns the records of zone (whose name is domain) at provider if
+// they were saved when the zone's version was token.
+func (c *Cache) Get(provider, zone, domain, token string) (models.Records, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.find(provider, zone)
+	if i == -1 || c.Zones[i].Token != token {
+		c.misses++
+		return nil, false
+	}
+	var recs models.Records
+	if err := json.Unmarshal(c.Zones[i].Records, &recs); err != nil {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	for _, rec := range recs {
+		rec.SetLabel(rec.Name, domain) // NameFQDN is not stored.
+	}
+	return recs, true
+}
+
+// Put saves the records of zone at provider, whose version is token.
+func (c *Cache) Put(provider, zone, token string, recs models.Records) error {
+	b, err := json.Marshal(recs)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	z := &Zone{Provider: provider, Zone: zone, Token: token, Saved: time.Now().UTC(), Records: b}
+	if i := c.find(provider, zone); i != -1 {
+		c.Zones[i] = z
+	} else {
+		c.Zones = append(c.Zones, z)
+	}
+	c.changed = true
+	return nil
+}
+
+// Stats returns how many times Get found the records, and how many times it
+// did not.
+func (c *Cache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
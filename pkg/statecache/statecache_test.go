@@ -0,0 +1,72 @@
+package statecache
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+)
+
+func makeRec(label, rtype, content string) *models.RecordConfig {
+	r := &models.RecordConfig{TTL: 300}
+	r.SetLabel(label, "example.com")
+	if err := r.PopulateFromString(rtype, content, "example.com"); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func strs(recs models.Records) []string {
+	var r []string
+	for _, rec := range recs {
+		r = append(r, rec.NameFQDN+" "+rec.Type+" "+rec.ToComparableNoTTL())
+	}
+	return r
+}
+
+func TestCache(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cache.json")
+	recs := models.Records{
+		makeRec("@", "MX", "10 mx.example.com."),
+		makeRec("www", "A", "1.2.3.4"),
+		makeRec("_sip._tcp", "SRV", "10 20 5060 sip.example.net."),
+		makeRec("txt", "TXT", "v=spf1 -all"),
+		makeRec("@", "CAA", `0 issue "letsencrypt.org"`),
+	}
+
+	c, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get("bind", "example.com", "example.com", "1"); ok {
+		t.Errorf("Get() on an empty cache found records")
+	}
+	if err := c.Put("bind", "example.com", "1", recs); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get("bind", "example.com", "example.com", "2"); ok {
+		t.Errorf("Get() with another token found records")
+	}
+	if _, ok := c.Get("other", "example.com", "example.com", "1"); ok {
+		t.Errorf("Get() at another provider found records")
+	}
+	got, ok := c.Get("bind", "example.com", "example.com", "1")
+	if !ok {
+		t.Fatalf("Get() found no records")
+	}
+	if !slices.Equal(strs(got), strs(recs)) {
+		t.Errorf("Get() = %v, want %v", strs(got), strs(recs))
+	}
+	if got[1].TTL != 300 {
+		t.Errorf("TTL = %d, want 300", got[1].TTL)
+	}
+}
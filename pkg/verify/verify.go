@@ -0,0 +1,195 @@
+// Package verify checks that nameservers serve the records that a push was
+// supposed to create, by querying them directly (without recursion).
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+	"github.com/DNSControl/dnscontrol/v4/pkg/dnsrr"
+	"github.com/miekg/dns"
+)
+
+// Timeout is the timeout of each query.
+var Timeout = 5 * time.Second
+
+// Mismatch is an RRset that a nameserver does not serve as expected.
+type Mismatch struct {
+	Nameserver string
+	Name       string // FQDN
+	Type       string
+	Want       []string // The expected records (without TTL). Empty if the RRset should not exist.
+	Got        []string // The records served.
+	Err        error    // The query failed.
+}
+
+func (m Mismatch) String() string {
+	if m.Err != nil {
+		return fmt.Sprintf("%s: %s %s: %s", m.Nameserver, m.Name, m.Type, m.Err)
+	}
+	return fmt.Sprintf("%s: %s %s: want [%s], got [%s]", m.Nameserver, m.Name, m.Type, strings.Join(m.Want, ", "), strings.Join(m.Got, ", "))
+}
+
+// Status is whether the nameservers serve an RRset as expected.
+type Status struct {
+	Name       string // FQDN
+	Type       string
+	Converged  bool
+	After      time.Duration // How long it took to converge.
+	Mismatches []Mismatch    // Why it has not converged (at the last attempt).
+}
+
+// Poll queries the nameservers every interval, until they serve all the
+// RRsets that changes (grouped by RecordSet, as in zonerecs.Result.Changes)
+// create, modify or delete, or until timeout. It returns the status of each
+// RRset that can be verified (see Verifiable).
+//
+// A nameserver is a hostname or IP address, optionally followed by a port
+// (the default is 53).
+func Poll(nameservers []string, zone string, changes diff2.ChangeList, timeout, interval time.Duration) []*Status {
+	var statuses []*Status
+	pending := map[*Status]diff2.Change{}
+	for _, c := range changes {
+		if Verifiable(zone, c) {
+			st := &Status{Name: c.Key.NameFQDN, Type: c.Key.Type}
+			statuses = append(statuses, st)
+			pending[st] = c
+		}
+	}
+
+	start := time.Now()
+	for {
+		for _, st := range statuses {
+			c, ok := pending[st]
+			if !ok {
+				continue
+			}
+			st.Mismatches = check(nameservers, zone, c)
+			if len(st.Mismatches) == 0 {
+				st.Converged = true
+				st.After = time.Since(start).Round(time.Second)
+				delete(pending, st)
+			}
+		}
+		if len(pending) == 0 || time.Since(start)+interval > timeout {
+			return statuses
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Verifiable returns true if the result of c can be verified by querying
+// the nameservers. The records of types that can not be queried (SOA and
+// provider-specific types) and the NS records at the apex (which many
+// providers manage themselves) can not.
+func Verifiable(zone string, c diff2.Change) bool {
+	if c.Type == diff2.REPORT || !Queryable(c.Key.Type) {
+		return false
+	}
+	return c.Key.Type != "NS" || !strings.EqualFold(c.Key.NameFQDN, zone)
+}
+
+// check queries each of the nameservers for the RRset of c.
+func check(nameservers []string, zone string, c diff2.Change) []Mismatch {
+	var want []string
+	if c.Type != diff2.DELETE {
+		for _, rec := range c.New {
+			want = append(want, rec.ToComparableNoTTL())
+		}
+	}
+	var ms []Mismatch
+	for _, ns := range nameservers {
+		got, err := Lookup(ns, zone, c.Key.NameFQDN, c.Key.Type)
+		if err != nil || !sameSet(want, got) {
+			ms = append(ms, Mismatch{Nameserver: ns, Name: c.Key.NameFQDN, Type: c.Key.Type, Want: want, Got: got, Err: err})
+		}
+	}
+	return ms
+}
+
+// Queryable returns true if records of type rtype can be verified by
+// querying a nameserver.
+func Queryable(rtype string) bool {
+	_, ok := dns.StringToType[rtype]
+	return ok && rtype != "SOA"
+}
+
+// Lookup returns the records (as models.RecordConfig.ToComparableNoTTL) of
+// type rtype at name that nameserver serves for zone. The nameserver must
+// be authoritative for the zone.
+func Lookup(nameserver, zone, name, rtype string) ([]string, error) {
+	qtype, ok := dns.StringToType[rtype]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q", rtype)
+	}
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.RecursionDesired = false
+
+	addr := nameserver
+	if _, _, err := net.SplitHostPort(nameserver); err != nil {
+		addr = net.JoinHostPort(nameserver, "53")
+	}
+	c := &dns.Client{Timeout: Timeout}
+	r, _, err := c.Exchange(m, addr)
+	if err == nil && r.Truncated {
+		c.Net = "tcp"
+		r, _, err = c.Exchange(m, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case r.Rcode == dns.RcodeNameError:
+		return nil, nil
+	case r.Rcode != dns.RcodeSuccess:
+		return nil, fmt.Errorf("query failed: %s", dns.RcodeToString[r.Rcode])
+	case !r.Authoritative:
+		return nil, errors.New("the answer is not authoritative")
+	}
+
+	var got []string
+	for _, rr := range r.Answer {
+		if rr.Header().Rrtype != qtype || !strings.EqualFold(rr.Header().Name, dns.Fqdn(name)) {
+			continue
+		}
+		rc, err := dnsrr.RRtoRC(rr, zone)
+		if err != nil {
+			return nil, err
+		}
+		got = append(got, rc.ToComparableNoTTL())
+	}
+	return got, nil
+}
+
+// sameSet returns true if a and b contain the same strings (ignoring
+// order and case).
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	lower := func(s []string) []string {
+		r := make([]string, len(s))
+		for i, v := range s {
+			r[i] = strings.ToLower(v)
+		}
+		slices.Sort(r)
+		return r
+	}
+	return slices.Equal(lower(a), lower(b))
+}
+
+// Nameservers returns the names of nss.
+func Nameservers(nss []*models.Nameserver) []string {
+	r := make([]string, len(nss))
+	for i, ns := range nss {
+		r[i] = ns.Name
+	}
+	return r
+}
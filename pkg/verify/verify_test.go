@@ -0,0 +1,165 @@
+package verify
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+	"github.com/miekg/dns"
+)
+
+// server is an authoritative nameserver for example.com.
+type server struct {
+	addr string
+	mu   sync.Mutex
+	rrs  []dns.RR
+}
+
+// set replaces the records served (in zonefile format).
+func (s *server) set(t *testing.T, records ...string) {
+	t.Helper()
+	var rrs []dns.RR
+	for _, r := range records {
+		rr, err := dns.NewRR(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rrs = append(rrs, rr)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rrs = rrs
+}
+
+// startServer starts a server that serves records.
+func startServer(t *testing.T, records ...string) *server {
+	t.Helper()
+	s := &server{}
+	s.set(t, records...)
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.Authoritative = true
+		q := req.Question[0]
+		exists := false
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, rr := range s.rrs {
+			if strings.EqualFold(rr.Header().Name, q.Name) {
+				exists = true
+				if rr.Header().Rrtype == q.Qtype {
+					m.Answer = append(m.Answer, rr)
+				}
+			}
+		}
+		if !exists {
+			m.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(m)
+	})}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+	s.addr = pc.LocalAddr().String()
+	return s
+}
+
+func makeRec(label, rtype, content string) *models.RecordConfig {
+	r := &models.RecordConfig{TTL: 300}
+	r.SetLabel(label, "example.com")
+	if err := r.PopulateFromString(rtype, content, "example.com"); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func TestPoll(t *testing.T) {
+	srv := startServer(t,
+		"www.example.com. 300 IN A 1.2.3.4",
+		"www.example.com. 300 IN A 1.2.3.5",
+		"mail.example.com. 300 IN MX 10 mx.example.net.",
+		"old.example.com. 300 IN TXT \"still here\"",
+		"txt.example.com. 300 IN TXT \"v=spf1 -all\"",
+	)
+
+	www := models.Records{makeRec("www", "A", "1.2.3.5"), makeRec("www", "A", "1.2.3.4")}
+	changes := diff2.ChangeList{
+		{Type: diff2.CHANGE, Key: www[0].Key(), New: www},
+		{Type: diff2.CREATE, Key: makeRec("mail", "MX", "10 mx.example.net.").Key(), New: models.Records{makeRec("mail", "MX", "10 mx.example.net.")}},
+		{Type: diff2.CREATE, Key: makeRec("txt", "TXT", "v=spf1 -all").Key(), New: models.Records{makeRec("txt", "TXT", "v=spf1 -all")}},
+		{Type: diff2.CREATE, Key: makeRec("api", "A", "1.2.3.6").Key(), New: models.Records{makeRec("api", "A", "1.2.3.6")}},
+		{Type: diff2.DELETE, Key: makeRec("old", "TXT", "x").Key(), Old: models.Records{makeRec("old", "TXT", "still here")}},
+		{Type: diff2.DELETE, Key: makeRec("gone", "A", "1.1.1.1").Key(), Old: models.Records{makeRec("gone", "A", "1.1.1.1")}},
+		{Type: diff2.CREATE, Key: models.RecordKey{NameFQDN: "example.com", Type: "R53_ALIAS"}},
+	}
+
+	statuses := func(sts []*Status) string {
+		var r []string
+		for _, st := range sts {
+			s := fmt.Sprintf("%s %s: %v", st.Name, st.Type, st.Converged)
+			for _, m := range st.Mismatches {
+				s += "; " + strings.TrimPrefix(m.String(), srv.addr+": ")
+			}
+			r = append(r, s)
+		}
+		return strings.Join(r, "\n")
+	}
+
+	// One attempt:
+	got := statuses(Poll([]string{srv.addr}, "example.com", changes, 0, time.Millisecond))
+	want := strings.Join([]string{
+		"www.example.com A: true",
+		"mail.example.com MX: true",
+		"txt.example.com TXT: true",
+		"api.example.com A: false; api.example.com A: want [1.2.3.6], got []",
+		"old.example.com TXT: false; old.example.com TXT: want [], got [\"still here\"]",
+		"gone.example.com A: true",
+	}, "\n")
+	if got != want {
+		t.Errorf("Poll():\n%s\nwant:\n%s", got, want)
+	}
+
+	// The server catches up while polling:
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		srv.set(t,
+			"www.example.com. 300 IN A 1.2.3.4",
+			"www.example.com. 300 IN A 1.2.3.5",
+			"mail.example.com. 300 IN MX 10 mx.example.net.",
+			"txt.example.com. 300 IN TXT \"v=spf1 -all\"",
+			"api.example.com. 300 IN A 1.2.3.6",
+		)
+	}()
+	for _, st := range Poll([]string{srv.addr}, "example.com", changes, 5*time.Second, 5*time.Millisecond) {
+		if !st.Converged {
+			t.Errorf("%s %s did not converge: %v", st.Name, st.Type, st.Mismatches)
+		}
+	}
+}
+
+func TestLookupNotAuthoritative(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		w.WriteMsg(m)
+	})}
+	go srv.ActivateAndServe()
+	defer srv.Shutdown()
+
+	if _, err := Lookup(pc.LocalAddr().String(), "example.com", "www.example.com", "A"); err == nil || !strings.Contains(err.Error(), "not authoritative") {
+		t.Errorf("Lookup() error = %v, want not authoritative", err)
+	}
+}
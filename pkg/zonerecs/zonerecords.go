@@ -1,17 +1,83 @@
 package zonerecs
 
 import (
+	"sync"
+
 	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
 	"github.com/DNSControl/dnscontrol/v4/pkg/rtypecontrol"
 )
 
+// Result is everything learned while generating the corrections for a zone
+// at a particular provider.
+type Result struct {
+	Existing          models.Records       // The records found at the provider (post-processed).
+	Desired           *models.DomainConfig // The copy of the DomainConfig that was given to the provider.
+	Reports           []*models.Correction // Informational messages (.F == nil).
+	Corrections       []*models.Correction // Actions to be taken (.F != nil).
+	ActualChangeCount int
+
+	compFunc    diff2.ComparableFunc // The ComparableFunc the provider gave to diff2.
+	changesOnce sync.Once
+	changes     diff2.ChangeList
+	changesErr  error
+}
+
+// Changes returns the changes as diff2 sees them, grouped by RecordKey,
+// independently of how the provider will apply them. They are compared with
+// the provider's ComparableFunc, after any adjustments the provider made to
+// the desired records (TTL clamping, etc.), and are computed on first use.
+func (r *Result) Changes() (diff2.ChangeList, error) {
+	r.changesOnce.Do(func() {
+		r.changes, _, r.changesErr = diff2.ByRecordSetUnordered(r.Existing, r.Desired, r.compFunc)
+	})
+	return r.changes, r.changesErr
+}
+
+// RecordChanges returns the Changes record by record (see
+// diff2.SplitByRecord): each change has at most one old and one new record.
+func (r *Result) RecordChanges() (diff2.ChangeList, error) {
+	changes, err := r.Changes()
+	if err != nil {
+		return nil, err
+	}
+	var split diff2.ChangeList
+	for _, c := range changes {
+		split = append(split, diff2.SplitByRecord(c, r.compFunc)...)
+	}
+	return split, nil
+}
+
 // CorrectZoneRecords calls both GetZoneRecords, does any
 // post-processing, and then calls GetZoneRecordsCorrections.  The
 // name sucks because all the good names were taken.
 func CorrectZoneRecords(driver models.DNSProvider, dc *models.DomainConfig) ([]*models.Correction, []*models.Correction, int, error) {
+	r, err := CorrectZoneRecordsResult(driver, dc)
+	if r == nil {
+		return nil, nil, 0, err
+	}
+	return r.Reports, r.Corrections, r.ActualChangeCount, err
+}
+
+// CorrectZoneRecordsResult is like CorrectZoneRecords but returns the
+// existing records and the diff2 view of the changes (Changes) too.  If the provider
+// returns an error while generating corrections, the partial Result is
+// returned along with the error.
+func CorrectZoneRecordsResult(driver models.DNSProvider, dc *models.DomainConfig) (*Result, error) {
+	return CorrectZoneRecordsResultAdjusted(driver, dc, nil)
+}
+
+// Adjuster changes the desired records of dc (a copy), knowing the existing
+// records, before the corrections are generated. It returns messages that
+// are added to the reports.
+type Adjuster func(existing models.Records, dc *models.DomainConfig) ([]string, error)
+
+// CorrectZoneRecordsResultAdjusted is like CorrectZoneRecordsResult but
+// calls adjust (if not nil) before the corrections are generated.
+func CorrectZoneRecordsResultAdjusted(driver models.DNSProvider, dc *models.DomainConfig, adjust Adjuster) (*Result, error) {
 	existingRecords, err := driver.GetZoneRecords(dc)
 	if err != nil {
-		return nil, nil, 0, err
+		return nil, err
 	}
 	rtypecontrol.FixLegacyRecords(&existingRecords) // Call this after GetZoneRecords() to fix providers that haven't been updated for RecordConfigV2.
 
@@ -27,19 +93,43 @@ func CorrectZoneRecords(driver models.DNSProvider, dc *models.DomainConfig) ([]*
 	// dc.Records.
 	dc, err = dc.Copy()
 	if err != nil {
-		return nil, nil, 0, err
+		return nil, err
 	}
 
 	// punycode
 	if err := dc.Punycode(); err != nil {
-		return nil, nil, 0, err
+		return nil, err
 	}
 	// FIXME(tlim) It is a waste to PunyCode every iteration.
 	// This should be moved to where the JavaScript is processed.
 
+	var msgs []string
+	if adjust != nil {
+		if msgs, err = adjust(existingRecords, dc); err != nil {
+			return nil, err
+		}
+	}
+
+	stop := diff2.RecordCompFunc(dc)
 	everything, actualChangeCount, err := driver.GetZoneRecordsCorrections(dc, existingRecords)
+	compFunc := stop()
 	reports, corrections := splitReportsAndCorrections(everything)
-	return reports, corrections, actualChangeCount, err
+	if len(msgs) != 0 {
+		adjusted := make([]*models.Correction, len(msgs))
+		for i, m := range msgs {
+			adjusted[i] = &models.Correction{Msg: m}
+		}
+		reports = append(adjusted, reports...)
+	}
+	r := &Result{
+		Existing:          existingRecords,
+		Desired:           dc,
+		Reports:           reports,
+		Corrections:       corrections,
+		ActualChangeCount: actualChangeCount,
+		compFunc:          compFunc,
+	}
+	return r, err
 }
 
 func splitReportsAndCorrections(everything []*models.Correction) (reports, corrections []*models.Correction) {
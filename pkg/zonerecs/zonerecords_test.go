@@ -0,0 +1,71 @@
+package zonerecs
+
+import (
+	"testing"
+
+	"github.com/DNSControl/dnscontrol/v4/models"
+	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
+)
+
+// proxyProvider is a DNS provider that, like CLOUDFLAREAPI, compares the
+// "proxy" metadata of the records too.
+type proxyProvider struct {
+	recs models.Records
+}
+
+func (p *proxyProvider) GetNameservers(string) ([]*models.Nameserver, error) { return nil, nil }
+
+func (p *proxyProvider) GetZoneRecords(*models.DomainConfig) (models.Records, error) {
+	return p.recs, nil
+}
+
+func (p *proxyProvider) GetZoneRecordsCorrections(dc *models.DomainConfig, existing models.Records) ([]*models.Correction, int, error) {
+	changes, count, err := diff2.ByRecord(existing, dc, func(rc *models.RecordConfig) string {
+		return "proxy=" + rc.Metadata["proxy"]
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	var corrections []*models.Correction
+	for _, c := range changes {
+		corrections = append(corrections, c.CreateCorrection(func() error { return nil }))
+	}
+	return corrections, count, nil
+}
+
+func makeRec(label, target, proxy string) *models.RecordConfig {
+	r := &models.RecordConfig{TTL: 300, Metadata: map[string]string{"proxy": proxy}}
+	r.SetLabel(label, "example.com")
+	if err := r.PopulateFromString("A", target, "example.com"); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func TestCorrectZoneRecordsResult_changes(t *testing.T) {
+	p := &proxyProvider{recs: models.Records{makeRec("www", "1.1.1.1", "on"), makeRec("api", "1.1.1.2", "on")}}
+	dc := &models.DomainConfig{
+		Name:    "example.com",
+		Records: models.Records{makeRec("www", "1.1.1.1", "off"), makeRec("api", "1.1.1.2", "on")},
+	}
+	r, err := CorrectZoneRecordsResult(p, dc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ActualChangeCount != 1 {
+		t.Fatalf("got %d changes from the provider, want 1", r.ActualChangeCount)
+	}
+
+	// The change is only visible with the provider's ComparableFunc.
+	changes, err := r.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Type.String()+" "+c.Key.String())
+	}
+	if len(got) != 1 || got[0] != "CHANGE www.example.com:A" {
+		t.Errorf("got changes %q, want [CHANGE www.example.com:A]", got)
+	}
+}
@@ -199,44 +199,56 @@ func (c *bindProvider) ListZones() ([]string, error) {
 // GetZoneRecords gets the records of a zone and returns them in RecordConfig format.
 func (c *bindProvider) GetZoneRecords(dc *models.DomainConfig) (models.Records, error) {
 	domain := dc.Name
-	meta := dc.Metadata
-
-	var zonefile string
 
 	if _, err := os.Stat(c.directory); os.IsNotExist(err) {
 		printer.Printf("\nWARNING: BIND directory %q does not exist! (will create)\n", c.directory)
 	}
+	zonefile := c.zonefileName(dc)
+
+	content, err := os.ReadFile(zonefile)
+	if os.IsNotExist(err) {
+		// If the file doesn't exist, that's not an error. Just informational.
+		fmt.Fprintf(os.Stderr, "INFO: File does not (yet) exist: %q\n", zonefile)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't open %s: %w", zonefile, err)
+	}
+
+	return ParseZoneContents(string(content), domain, zonefile)
+}
+
+// ZoneVersion returns the modification time and size of the zonefile.
+func (c *bindProvider) ZoneVersion(dc *models.DomainConfig) (string, error) {
+	fi, err := os.Stat(c.zonefileName(dc))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", fi.ModTime().UnixNano(), fi.Size()), nil
+}
+
+// zonefileName returns the name of the zonefile of dc.
+func (c *bindProvider) zonefileName(dc *models.DomainConfig) string {
+	meta := dc.Metadata
 	ff := domaintags.DomainNameVarieties{
 		Tag:         meta[models.DomainTag],
 		NameRaw:     meta[models.DomainNameRaw],
-		NameASCII:   domain,
+		NameASCII:   dc.Name,
 		NameUnicode: meta[models.DomainNameUnicode],
 		UniqueName:  meta[models.DomainUniqueName],
 		// NB(tlim): When "get-zones" is called, these values are populated
 		// directly by commands/getZones.go near where provider.GetZoneRecords()
 		// is called. Changes here may need to be reflected there too.
 	}
-	zonefile = filepath.Join(c.directory,
+	return filepath.Join(c.directory,
 		makeFileName(
 			c.filenameformat,
 			ff,
 		),
 	)
-	//fmt.Printf("DEBUG: Reading zonefile %q\n", zonefile)
-	//fmt.Printf("DEBUG: Meta %+v\n", meta)
-	//fmt.Printf("DEBUG: Domain Names %+v\n", ff)
-
-	content, err := os.ReadFile(zonefile)
-	if os.IsNotExist(err) {
-		// If the file doesn't exist, that's not an error. Just informational.
-		fmt.Fprintf(os.Stderr, "INFO: File does not (yet) exist: %q\n", zonefile)
-		return nil, nil
-	}
-	if err != nil {
-		return nil, fmt.Errorf("can't open %s: %w", zonefile, err)
-	}
-
-	return ParseZoneContents(string(content), domain, zonefile)
 }
 
 // ParseZoneContents parses a string as a BIND zone and returns the records.
@@ -15,6 +15,7 @@ import (
 	"github.com/DNSControl/dnscontrol/v4/models"
 	"github.com/DNSControl/dnscontrol/v4/pkg/diff2"
 	"github.com/DNSControl/dnscontrol/v4/pkg/providers"
+	"github.com/DNSControl/dnscontrol/v4/pkg/ratelimit"
 )
 
 /*
@@ -113,10 +114,15 @@ func NewProvider(m map[string]string, metadata json.RawMessage) (providers.DNSSe
 		baseURL = "https://api.dnscale.eu/v1"
 	}
 
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+	if err := ratelimit.WrapClient(client, m); err != nil {
+		return nil, err
+	}
+
 	provider := &dnscaleProvider{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:  client,
 		apiKey:  apiKey,
 		baseURL: baseURL,
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/DNSControl/dnscontrol/v4/models"
 	"github.com/DNSControl/dnscontrol/v4/pkg/diff"
 	"github.com/DNSControl/dnscontrol/v4/pkg/providers"
+	"github.com/DNSControl/dnscontrol/v4/pkg/ratelimit"
 )
 
 // packetframeProvider is the handle for this provider.
@@ -33,6 +34,9 @@ func newPacketframe(m map[string]string, metadata json.RawMessage) (providers.DN
 		return nil, errors.New("invalid base URL for Packetframe")
 	}
 	client := http.Client{}
+	if err := ratelimit.WrapClient(&client, m); err != nil {
+		return nil, err
+	}
 
 	api := &packetframeProvider{client: &client, baseURL: baseURL, token: m["token"]}
 